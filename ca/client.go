@@ -0,0 +1,247 @@
+package ca
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//Config fabric ca client config
+type Config struct {
+	// TLSCAs tls root certificates of fabric ca server
+	TLSCAs [][]byte
+	// ClientCert, ClientKey for fabric ca server which require client auth
+	ClientCert []byte
+	ClientKey  []byte
+	Timeout    time.Duration
+}
+
+//WithTLS tls ca
+func WithTLS(caPEM []byte) func(config *Config) {
+	return func(config *Config) {
+		if len(caPEM) == 0 {
+			return
+		}
+		config.TLSCAs = append(config.TLSCAs, caPEM)
+	}
+}
+
+//WithClientCert for require client auth cert
+func WithClientCert(keyPEM, certPEM []byte) func(config *Config) {
+	return func(config *Config) {
+		if len(keyPEM) == 0 || len(certPEM) == 0 {
+			return
+		}
+		config.ClientKey = keyPEM
+		config.ClientCert = certPEM
+	}
+}
+
+//WithTimeout http request timeout
+func WithTimeout(duration time.Duration) func(config *Config) {
+	return func(config *Config) {
+		config.Timeout = duration
+	}
+}
+
+//Client fabric ca client, speaks fabric ca rest api
+type Client struct {
+	url        string
+	caName     string
+	httpClient *http.Client
+}
+
+//NewClient new fabric ca client
+// url fabric ca server url, eg: https://127.0.0.1:7054
+// caName ca name, "" means the default ca of the server
+func NewClient(url, caName string, Opt ...func(config *Config)) (*Client, error) {
+	config := &Config{Timeout: 10 * time.Second}
+
+	for oi := range Opt {
+		Opt[oi](config)
+	}
+
+	transport := &http.Transport{}
+	if len(config.TLSCAs) != 0 || len(config.ClientCert) != 0 {
+		c := &tls.Config{}
+
+		if len(config.TLSCAs) != 0 {
+			certPool := x509.NewCertPool()
+			for i := range config.TLSCAs {
+				certPool.AppendCertsFromPEM(config.TLSCAs[i])
+			}
+			c.RootCAs = certPool
+		}
+
+		if len(config.ClientCert) != 0 {
+			cert, err := tls.X509KeyPair(config.ClientCert, config.ClientKey)
+			if err != nil {
+				return nil, fmt.Errorf("load client key pair failed: %v", err)
+			}
+			c.Certificates = append(c.Certificates, cert)
+		}
+
+		transport.TLSClientConfig = c
+	}
+
+	return &Client{
+		url:    strings.TrimSuffix(url, "/"),
+		caName: caName,
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   config.Timeout,
+		},
+	}, nil
+}
+
+// response fabric ca server response body
+type response struct {
+	Success  bool            `json:"success"`
+	Result   json.RawMessage `json:"result"`
+	Errors   []responseMsg   `json:"errors"`
+	Messages []responseMsg   `json:"messages"`
+}
+
+type responseMsg struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// auth set authorization header of the request
+type auth func(req *http.Request, body []byte) error
+
+// basicAuth authorization for enroll
+func basicAuth(name, secret string) auth {
+	return func(req *http.Request, _ []byte) error {
+		req.SetBasicAuth(name, secret)
+		return nil
+	}
+}
+
+// tokenAuth authorization signed by a enrolled identity
+func tokenAuth(id *Identity) auth {
+	return func(req *http.Request, body []byte) error {
+		token, err := id.token(req.Method, req.URL.RequestURI(), body)
+		if err != nil {
+			return fmt.Errorf("create token failed: %v", err)
+		}
+		req.Header.Set("Authorization", token)
+		return nil
+	}
+}
+
+func (c *Client) do(method, endpoint string, a auth, reqBody, result interface{}) error {
+	var body []byte
+	if reqBody != nil {
+		var err error
+		body, err = json.Marshal(reqBody)
+		if err != nil {
+			return fmt.Errorf("marshal request failed: %v", err)
+		}
+	}
+
+	req, err := http.NewRequest(method, c.url+"/api/v1/"+endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request failed: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if a != nil {
+		if err = a(req, body); err != nil {
+			return err
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %v", method, endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response body failed: %v", err)
+	}
+
+	r := &response{}
+	if err = json.Unmarshal(data, r); err != nil {
+		return fmt.Errorf("unmarshal response [%d: %s] failed: %v", resp.StatusCode, data, err)
+	}
+
+	if !r.Success || len(r.Errors) != 0 {
+		var msgs []string
+		for _, e := range r.Errors {
+			msgs = append(msgs, fmt.Sprintf("%d - %s", e.Code, e.Message))
+		}
+		return fmt.Errorf("%s %s failed with status [%d]: %s", method, endpoint, resp.StatusCode, strings.Join(msgs, "; "))
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	if err = json.Unmarshal(r.Result, result); err != nil {
+		return fmt.Errorf("unmarshal result failed: %v", err)
+	}
+	return nil
+}
+
+//CAInfo ca information
+type CAInfo struct {
+	CAName string
+	// CAChain pem root and intermediate certificates
+	CAChain []byte
+	// IssuerPublicKey idemix issuer public key
+	IssuerPublicKey []byte
+	// IssuerRevocationPublicKey idemix issuer revocation public key
+	IssuerRevocationPublicKey []byte
+	Version                   string
+}
+
+// serverInfo ca information in response, bytes are base64 encoded
+type serverInfo struct {
+	CAName                    string `json:"CAName"`
+	CAChain                   string `json:"CAChain"`
+	IssuerPublicKey           string `json:"IssuerPublicKey"`
+	IssuerRevocationPublicKey string `json:"IssuerRevocationPublicKey"`
+	Version                   string `json:"Version"`
+}
+
+func (s *serverInfo) toCAInfo() (*CAInfo, error) {
+	info := &CAInfo{CAName: s.CAName, Version: s.Version}
+
+	var err error
+	for _, x := range []struct {
+		dst *[]byte
+		src string
+	}{
+		{&info.CAChain, s.CAChain},
+		{&info.IssuerPublicKey, s.IssuerPublicKey},
+		{&info.IssuerRevocationPublicKey, s.IssuerRevocationPublicKey},
+	} {
+		*x.dst, err = base64.StdEncoding.DecodeString(x.src)
+		if err != nil {
+			return nil, fmt.Errorf("decode server info failed: %v", err)
+		}
+	}
+
+	return info, nil
+}
+
+//GetCAInfo get ca information
+func (c *Client) GetCAInfo() (*CAInfo, error) {
+	s := &serverInfo{}
+	err := c.do(http.MethodPost, "cainfo", nil, map[string]string{"caname": c.caName}, s)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.toCAInfo()
+}
@@ -0,0 +1,331 @@
+package ca
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Asutorufa/fabricsdk/chaincode"
+)
+
+// fakeCA a fabric ca server stand-in
+type fakeCA struct {
+	key     *ecdsa.PrivateKey
+	cert    *x509.Certificate
+	certPEM []byte
+	serial  int64
+	revoked []pkix.RevokedCertificate
+	users   map[string]string
+}
+
+func newFakeCA(t *testing.T) *fakeCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// subject key identifier, same as fabric bccsp
+	ski := sha256.Sum256(elliptic.Marshal(key.Curve, key.X, key.Y))
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca.org1.example.com", Organization: []string{"org1.example.com"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		SubjectKeyId:          ski[:],
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &fakeCA{
+		key:     key,
+		cert:    cert,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		serial:  1,
+		users:   map[string]string{"admin": "adminpw"},
+	}
+}
+
+func (f *fakeCA) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+
+	result, err := f.handle(r, body)
+	resp := map[string]interface{}{"success": err == nil, "result": result, "errors": []interface{}{}, "messages": []interface{}{}}
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		resp["errors"] = []interface{}{map[string]interface{}{"code": 20, "message": err.Error()}}
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (f *fakeCA) serverInfo() map[string]string {
+	return map[string]string{"CAName": "ca-org1", "CAChain": base64.StdEncoding.EncodeToString(f.certPEM), "Version": "1.5.0"}
+}
+
+func (f *fakeCA) handle(r *http.Request, body []byte) (interface{}, error) {
+	endpoint := strings.TrimPrefix(r.URL.Path, "/api/v1/")
+
+	switch endpoint {
+	case "cainfo":
+		return f.serverInfo(), nil
+	case "enroll":
+		name, secret, ok := r.BasicAuth()
+		if !ok || f.users[name] != secret {
+			return nil, fmt.Errorf("authentication failure")
+		}
+		return f.enroll(name, body)
+	}
+
+	caller, err := f.verifyToken(r, body)
+	if err != nil {
+		return nil, err
+	}
+
+	switch endpoint {
+	case "reenroll":
+		return f.enroll(caller.Subject.CommonName, body)
+	case "register":
+		req := &RegistrationRequest{}
+		_ = json.Unmarshal(body, req)
+		if req.Secret == "" {
+			req.Secret = req.Name + "pw"
+		}
+		f.users[req.Name] = req.Secret
+		return map[string]string{"secret": req.Secret}, nil
+	case "revoke":
+		req := &RevocationRequest{}
+		_ = json.Unmarshal(body, req)
+		serial, _ := new(big.Int).SetString(req.Serial, 16)
+		f.revoked = append(f.revoked, pkix.RevokedCertificate{SerialNumber: serial, RevocationTime: time.Now()})
+		return map[string]interface{}{"RevokedCerts": []RevokedCert{{Serial: req.Serial, AKI: req.AKI}}}, nil
+	case "gencrl":
+		der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+			RevokedCertificates: f.revoked,
+			Number:              big.NewInt(1),
+			ThisUpdate:          time.Now(),
+			NextUpdate:          time.Now().Add(time.Hour),
+		}, f.cert, f.key)
+		if err != nil {
+			return nil, err
+		}
+		crl := pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})
+		return map[string]string{"CRL": base64.StdEncoding.EncodeToString(crl)}, nil
+	}
+
+	return nil, fmt.Errorf("unknown endpoint: %s", endpoint)
+}
+
+func (f *fakeCA) enroll(name string, body []byte) (interface{}, error) {
+	req := &signRequest{}
+	if err := json.Unmarshal(body, req); err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode([]byte(req.Request))
+	if block == nil {
+		return nil, fmt.Errorf("bad csr")
+	}
+
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	f.serial++
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber:   big.NewInt(f.serial),
+		Subject:        pkix.Name{CommonName: name, OrganizationalUnit: []string{"client"}},
+		NotBefore:      time.Now().Add(-time.Minute),
+		NotAfter:       time.Now().Add(time.Hour),
+		KeyUsage:       x509.KeyUsageDigitalSignature,
+		DNSNames:       csr.DNSNames,
+		AuthorityKeyId: f.cert.SubjectKeyId,
+	}, f.cert, csr.PublicKey, f.key)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"Cert":       base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		"ServerInfo": f.serverInfo(),
+	}, nil
+}
+
+func (f *fakeCA) verifyToken(r *http.Request, body []byte) (*x509.Certificate, error) {
+	parts := strings.Split(r.Header.Get("Authorization"), ".")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("bad token")
+	}
+
+	certPEM, _ := base64.StdEncoding.DecodeString(parts[0])
+	sig, _ := base64.StdEncoding.DecodeString(parts[1])
+
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, fmt.Errorf("bad token certificate")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = cert.CheckSignatureFrom(f.cert); err != nil {
+		return nil, err
+	}
+
+	payload := r.Method + "." + base64.StdEncoding.EncodeToString([]byte(r.URL.RequestURI())) + "." +
+		base64.StdEncoding.EncodeToString(body) + "." + parts[0]
+	digest := sha256.Sum256([]byte(payload))
+	if !ecdsa.VerifyASN1(cert.PublicKey.(*ecdsa.PublicKey), digest[:], sig) {
+		return nil, fmt.Errorf("bad token signature")
+	}
+
+	return cert, nil
+}
+
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(newFakeCA(t))
+	defer server.Close()
+
+	c, err := NewClient(server.URL, "ca-org1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := c.GetCAInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.CAName != "ca-org1" || len(info.CAChain) == 0 {
+		t.Errorf("bad ca info: %v", info)
+	}
+
+	_, err = c.Enroll(EnrollmentRequest{Name: "admin", Secret: "wrong"})
+	if err == nil {
+		t.Error("enroll with wrong secret successful")
+	}
+
+	admin, err := c.Enroll(EnrollmentRequest{Name: "admin", Secret: "adminpw"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := c.Register(admin, RegistrationRequest{Name: "user1", Type: "client", Affiliation: "org1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := c.Enroll(EnrollmentRequest{Name: "user1", Secret: secret, Hosts: []string{"user1.org1.example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err = c.Reenroll(user, ReenrollmentRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := user.certificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.Subject.CommonName != "user1" {
+		t.Errorf("reenroll common name: %s", cert.Subject.CommonName)
+	}
+
+	resp, err := c.Revoke(admin, RevocationRequest{Serial: cert.SerialNumber.Text(16), AKI: fmt.Sprintf("%x", cert.AuthorityKeyId)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.RevokedCerts) != 1 {
+		t.Errorf("revoked certs: %v", resp.RevokedCerts)
+	}
+
+	crl, err := c.GenCRL(admin, GenCRLRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, _ := pem.Decode(crl)
+	if block == nil {
+		t.Fatal("bad crl")
+	}
+	list, err := x509.ParseRevocationList(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.RevokedCertificateEntries) != 1 {
+		t.Errorf("crl entries: %d", len(list.RevokedCertificateEntries))
+	}
+}
+
+func TestIdentitySigner(t *testing.T) {
+	server := httptest.NewServer(newFakeCA(t))
+	defer server.Close()
+
+	c, err := NewClient(server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	admin, err := c.Enroll(EnrollmentRequest{Name: "admin", Secret: "adminpw"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := admin.Signer("Org1MSP")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sig, err := signer.Sign([]byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = signer.Verify([]byte("hello"), sig); err != nil {
+		t.Error(err)
+	}
+
+	dir := t.TempDir()
+	if err = admin.StoreMSP(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err = chaincode.GetSigner(dir, "Org1MSP")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sig, err = signer.Sign([]byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = signer.Verify([]byte("hello"), sig); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,268 @@
+package ca
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+//AttributeRequest attribute which should be added to the enrollment certificate
+type AttributeRequest struct {
+	Name     string `json:"name"`
+	Optional bool   `json:"optional,omitempty"`
+}
+
+//EnrollmentRequest enroll request
+type EnrollmentRequest struct {
+	Name   string
+	Secret string
+	// Profile signing profile, eg: tls
+	Profile string
+	// Label hsm label
+	Label string
+	// Hosts subject alternative names of the certificate
+	Hosts    []string
+	AttrReqs []AttributeRequest
+}
+
+//ReenrollmentRequest reenroll request
+type ReenrollmentRequest struct {
+	Profile  string
+	Label    string
+	Hosts    []string
+	AttrReqs []AttributeRequest
+}
+
+// signRequest enroll and reenroll request body
+type signRequest struct {
+	Hosts    []string           `json:"hosts,omitempty"`
+	Request  string             `json:"certificate_request"`
+	Profile  string             `json:"profile,omitempty"`
+	Label    string             `json:"label,omitempty"`
+	CAName   string             `json:"caname,omitempty"`
+	AttrReqs []AttributeRequest `json:"attr_reqs,omitempty"`
+}
+
+// enrollmentResponse enroll and reenroll response result
+type enrollmentResponse struct {
+	Cert       string     `json:"Cert"`
+	ServerInfo serverInfo `json:"ServerInfo"`
+}
+
+//Enroll enroll a identity with enrollment id and secret, a new key pair is generated
+func (c *Client) Enroll(req EnrollmentRequest) (*Identity, error) {
+	return c.sign("enroll", basicAuth(req.Name, req.Secret), req.Name,
+		ReenrollmentRequest{Profile: req.Profile, Label: req.Label, Hosts: req.Hosts, AttrReqs: req.AttrReqs})
+}
+
+//Reenroll renew the certificate of a enrolled identity with a new key pair
+func (c *Client) Reenroll(id *Identity, req ReenrollmentRequest) (*Identity, error) {
+	cert, err := id.certificate()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.sign("reenroll", tokenAuth(id), cert.Subject.CommonName, req)
+}
+
+func (c *Client) sign(endpoint string, a auth, cn string, req ReenrollmentRequest) (*Identity, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate key failed: %v", err)
+	}
+
+	csr, err := createCSR(key, cn, req.Hosts)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &enrollmentResponse{}
+	err = c.do(http.MethodPost, endpoint, a, &signRequest{
+		Hosts:    req.Hosts,
+		Request:  string(csr),
+		Profile:  req.Profile,
+		Label:    req.Label,
+		CAName:   c.caName,
+		AttrReqs: req.AttrReqs,
+	}, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := base64.StdEncoding.DecodeString(resp.Cert)
+	if err != nil {
+		return nil, fmt.Errorf("decode certificate failed: %v", err)
+	}
+
+	info, err := resp.ServerInfo.toCAInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("marshal private key failed: %v", err)
+	}
+
+	return &Identity{
+		Cert:    cert,
+		Key:     pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}),
+		CAChain: info.CAChain,
+	}, nil
+}
+
+func createCSR(key *ecdsa.PrivateKey, cn string, hosts []string) ([]byte, error) {
+	template := &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: cn},
+	}
+
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		return nil, fmt.Errorf("create certificate request failed: %v", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}), nil
+}
+
+func (i *Identity) certificate() (*x509.Certificate, error) {
+	block, _ := pem.Decode(i.Cert)
+	if block == nil {
+		return nil, fmt.Errorf("decode certificate pem failed")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse certificate failed: %v", err)
+	}
+
+	return cert, nil
+}
+
+//Attribute attribute of a registered identity
+type Attribute struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// ECert add the attribute to the enrollment certificate by default
+	ECert bool `json:"ecert,omitempty"`
+}
+
+//RegistrationRequest register request
+type RegistrationRequest struct {
+	Name string `json:"id"`
+	// Type identity type, eg: client, peer, orderer, admin
+	Type string `json:"type,omitempty"`
+	// Secret enrollment secret, "" means generated by the server
+	Secret         string      `json:"secret,omitempty"`
+	MaxEnrollments int         `json:"max_enrollments,omitempty"`
+	Affiliation    string      `json:"affiliation"`
+	Attributes     []Attribute `json:"attrs,omitempty"`
+	CAName         string      `json:"caname,omitempty"`
+}
+
+//Register register a new identity by registrar, return the enrollment secret
+func (c *Client) Register(registrar *Identity, req RegistrationRequest) (string, error) {
+	req.CAName = c.caName
+
+	resp := &struct {
+		Secret string `json:"secret"`
+	}{}
+	err := c.do(http.MethodPost, "register", tokenAuth(registrar), &req, resp)
+	if err != nil {
+		return "", err
+	}
+
+	return resp.Secret, nil
+}
+
+//RevocationRequest revoke request, Name or Serial and AKI must be set
+type RevocationRequest struct {
+	// Name revoke all certificates of the enrollment id
+	Name string `json:"id,omitempty"`
+	// Serial, AKI hex encoded, revoke one certificate
+	Serial string `json:"serial,omitempty"`
+	AKI    string `json:"aki,omitempty"`
+	// Reason eg: keycompromise, superseded
+	Reason string `json:"reason,omitempty"`
+	CAName string `json:"caname,omitempty"`
+	// GenCRL generate crl in response
+	GenCRL bool `json:"gencrl,omitempty"`
+}
+
+//RevokedCert revoked certificate
+type RevokedCert struct {
+	Serial string `json:"Serial"`
+	AKI    string `json:"AKI"`
+}
+
+//RevocationResponse revoke response
+type RevocationResponse struct {
+	RevokedCerts []RevokedCert
+	// CRL pem crl, only if RevocationRequest.GenCRL
+	CRL []byte
+}
+
+//Revoke revoke a identity or a certificate by registrar
+func (c *Client) Revoke(registrar *Identity, req RevocationRequest) (*RevocationResponse, error) {
+	req.CAName = c.caName
+
+	resp := &struct {
+		RevokedCerts []RevokedCert `json:"RevokedCerts"`
+		CRL          string        `json:"CRL"`
+	}{}
+	err := c.do(http.MethodPost, "revoke", tokenAuth(registrar), &req, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	crl, err := base64.StdEncoding.DecodeString(resp.CRL)
+	if err != nil {
+		return nil, fmt.Errorf("decode crl failed: %v", err)
+	}
+
+	return &RevocationResponse{RevokedCerts: resp.RevokedCerts, CRL: crl}, nil
+}
+
+//GenCRLRequest generate crl request, nil time means no limit
+type GenCRLRequest struct {
+	CAName        string     `json:"caname,omitempty"`
+	RevokedAfter  *time.Time `json:"revokedafter,omitempty"`
+	RevokedBefore *time.Time `json:"revokedbefore,omitempty"`
+	ExpireAfter   *time.Time `json:"expireafter,omitempty"`
+	ExpireBefore  *time.Time `json:"expirebefore,omitempty"`
+}
+
+//GenCRL generate crl by registrar, return pem crl, which can be put in msp/crls
+func (c *Client) GenCRL(registrar *Identity, req GenCRLRequest) ([]byte, error) {
+	req.CAName = c.caName
+
+	resp := &struct {
+		CRL string `json:"CRL"`
+	}{}
+	err := c.do(http.MethodPost, "gencrl", tokenAuth(registrar), &req, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	crl, err := base64.StdEncoding.DecodeString(resp.CRL)
+	if err != nil {
+		return nil, fmt.Errorf("decode crl failed: %v", err)
+	}
+
+	return crl, nil
+}
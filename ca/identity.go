@@ -0,0 +1,123 @@
+package ca
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/Asutorufa/fabricsdk/chaincode"
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/hyperledger/fabric/msp"
+)
+
+//Identity a enrolled identity
+type Identity struct {
+	// Cert pem signer certificate
+	Cert []byte
+	// Key pem pkcs8 private key
+	Key []byte
+	// CAChain pem root and intermediate certificates
+	CAChain []byte
+}
+
+//Signer get signer of the identity
+func (i *Identity) Signer(mspID string) (msp.SigningIdentity, error) {
+	return chaincode.GetSignerFromPEM(mspID, i.Cert, i.Key, i.CAChain)
+}
+
+//StoreMSP write the identity as a msp directory, which can be loaded by chaincode.GetSigner
+// dir/signcerts/cert.pem
+// dir/keystore/<ski>_sk
+// dir/admincerts/cert.pem, the identity is the admin of its local msp, msp 1.4.3 requires admins without node ous
+// dir/cacerts/ca.pem
+// dir/intermediatecerts/intermediate.pem
+func (i *Identity) StoreMSP(dir string) error {
+	_, key, err := i.privateKey()
+	if err != nil {
+		return err
+	}
+
+	roots, intermediates, err := chaincode.SplitCAChain(i.CAChain)
+	if err != nil {
+		return err
+	}
+
+	files := map[string][]byte{
+		filepath.Join("signcerts", "cert.pem"):                         i.Cert,
+		filepath.Join("keystore", hex.EncodeToString(key.SKI())+"_sk"): i.Key,
+		filepath.Join("admincerts", "cert.pem"):                        i.Cert,
+		filepath.Join("cacerts", "ca.pem"):                             bytes.Join(roots, nil),
+		filepath.Join("intermediatecerts", "intermediate.pem"):         bytes.Join(intermediates, nil),
+	}
+
+	for name, data := range files {
+		if len(data) == 0 {
+			continue
+		}
+
+		path := filepath.Join(dir, name)
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("create directory failed: %v", err)
+		}
+
+		if err = ioutil.WriteFile(path, data, 0600); err != nil {
+			return fmt.Errorf("write %s failed: %v", path, err)
+		}
+	}
+
+	return nil
+}
+
+// privateKey import the private key into a bccsp without keystore, the key is parsed by bccsp as msp does
+func (i *Identity) privateKey() (bccsp.BCCSP, bccsp.Key, error) {
+	block, _ := pem.Decode(i.Key)
+	if block == nil {
+		return nil, nil, fmt.Errorf("decode private key pem failed")
+	}
+
+	csp, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	if err != nil {
+		return nil, nil, fmt.Errorf("create bccsp failed: %v", err)
+	}
+
+	key, err := csp.KeyImport(block.Bytes, &bccsp.ECDSAPrivateKeyImportOpts{Temporary: true})
+	if err != nil {
+		return nil, nil, fmt.Errorf("import private key failed: %v", err)
+	}
+
+	return csp, key, nil
+}
+
+// token create fabric ca authorization token
+// token = b64(cert).b64(sign(method.b64(uri).b64(body).b64(cert)))
+func (i *Identity) token(method, uri string, body []byte) (string, error) {
+	csp, key, err := i.privateKey()
+	if err != nil {
+		return "", err
+	}
+
+	b64Cert := base64.StdEncoding.EncodeToString(i.Cert)
+	payload := method + "." +
+		base64.StdEncoding.EncodeToString([]byte(uri)) + "." +
+		base64.StdEncoding.EncodeToString(body) + "." +
+		b64Cert
+
+	digest, err := csp.Hash([]byte(payload), &bccsp.SHA256Opts{})
+	if err != nil {
+		return "", fmt.Errorf("hash token payload failed: %v", err)
+	}
+
+	// bccsp signatures are low-s
+	sig, err := csp.Sign(key, digest, nil)
+	if err != nil {
+		return "", fmt.Errorf("sign token failed: %v", err)
+	}
+
+	return b64Cert + "." + base64.StdEncoding.EncodeToString(sig), nil
+}
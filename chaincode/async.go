@@ -0,0 +1,120 @@
+package chaincode
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+//Commit handle of a transaction accepted by the orderer
+type Commit struct {
+	txID     string
+	response *peer.ProposalResponse
+	tx       *endorsedTx
+	done     chan struct{}
+	status   *CommitStatus
+	err      error
+}
+
+//TxID transaction id
+func (c *Commit) TxID() string { return c.txID }
+
+//Response endorsement result, the first proposal response
+func (c *Commit) Response() *peer.ProposalResponse { return c.response }
+
+//Result payload returned by the chaincode
+func (c *Commit) Result() []byte { return c.response.Response.Payload }
+
+//Done closed once the commit status is resolved
+func (c *Commit) Done() <-chan struct{} { return c.done }
+
+//Status wait for the commit status,
+// a committed but invalidated transaction is not a error, check CommitStatus.Successful
+func (c *Commit) Status(ctx context.Context) (*CommitStatus, error) {
+	select {
+	case <-c.done:
+		return c.status, c.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+//InvokeAsync Invoke without waiting for the commit, clients are closed once the commit is resolved
+func InvokeAsync(chaincode ChainOpt, mspOpt MSPOpt, args [][]byte,
+	privateData map[string][]byte, channelID string,
+	peers []Endpoint, orderers []Endpoint) (*Commit, error) {
+	peerClients := GetPeerClients(peers)
+	if len(peerClients) == 0 {
+		return nil, fmt.Errorf("peer clients' number is 0")
+	}
+
+	ordererClients := GetOrdererClients(orderers)
+	if len(ordererClients) == 0 {
+		CloseClients(peerClients)
+		return nil, fmt.Errorf("orderer clients' number is 0")
+	}
+	defer CloseClients(ordererClients)
+
+	commit, err := InternalInvokeAsync(chaincode, mspOpt, args, privateData, channelID, peerClients, ordererClients)
+	if err != nil {
+		CloseClients(peerClients)
+		return nil, err
+	}
+
+	go func() {
+		<-commit.Done()
+		CloseClients(peerClients)
+	}()
+
+	return commit, nil
+}
+
+//InternalInvokeAsync invoke, return once the orderer accepted the transaction,
+// the commit status is resolved by chaincode.Wait, nil means WaitForAny endorsing peer in DefaultCommitTimeout
+func InternalInvokeAsync(chaincode ChainOpt, mspOpt MSPOpt, args [][]byte,
+	privateData map[string][]byte, channelID string,
+	peers []*client.PeerClient, orderers []*client.OrdererClient,
+) (*Commit, error) {
+	signer, err := GetSignerByOpt(mspOpt)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, _, err := endorseOnPeers(chaincode, signer, args, privateData, channelID, nil, peers)
+	if err != nil {
+		return nil, err
+	}
+
+	wait := chaincode.Wait
+	if wait == nil {
+		wait = WaitForAny(DefaultCommitTimeout)
+	}
+
+	return submitAsync(tx, wait, channelID, orderers)
+}
+
+// submitAsync connect to deliver, send the transaction, resolve the commit in background
+func submitAsync(tx *endorsedTx, wait WaitStrategy, channelID string, orderers []*client.OrdererClient) (*Commit, error) {
+	waiter, err := NewCommitWaiter(wait, tx.signer, channelID, tx.txID, tx.peers)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = Broadcast(tx.env, orderers); err != nil {
+		waiter.Close()
+		return nil, err
+	}
+
+	c := &Commit{txID: tx.txID, response: tx.response, tx: tx, done: make(chan struct{})}
+	go func() {
+		defer close(c.done)
+		c.status, c.err = waiter.WaitStatus()
+		if c.err == nil && c.status == nil {
+			c.err = fmt.Errorf("commit status of transaction [%s] is not waited", tx.txID)
+		}
+	}()
+
+	return c, nil
+}
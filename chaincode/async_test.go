@@ -0,0 +1,72 @@
+package chaincode
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+func TestInternalInvokeAsync(t *testing.T) {
+	l, pc, oc := newFakeLedger(t, peer.TxValidationCode_VALID)
+	mspOpt := newTestMSPOpt(t)
+
+	commit, err := InternalInvokeAsync(ChainOpt{Name: "basic"}, mspOpt, [][]byte{[]byte("set"), []byte("a")},
+		nil, "mychannel", []*client.PeerClient{pc}, []*client.OrdererClient{oc})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if commit.TxID() == "" || string(commit.Result()) != "a" {
+		t.Errorf("txid: %s, result: %s", commit.TxID(), commit.Result())
+	}
+
+	// blocks are held, returned without waiting
+	select {
+	case <-commit.Done():
+		t.Fatal("done before committed")
+	default:
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err = commit.Status(ctx); err != context.DeadlineExceeded {
+		t.Errorf("status before committed: %v", err)
+	}
+
+	close(l.release)
+	status, err := commit.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Successful() || status.TxID != commit.TxID() || status.BlockNumber != 0 {
+		t.Errorf("status: %+v", status)
+	}
+
+	if _, err = InternalInvokeAsync(ChainOpt{Name: "basic"}, mspOpt, [][]byte{[]byte("fail")},
+		nil, "mychannel", []*client.PeerClient{pc}, []*client.OrdererClient{oc}); err == nil {
+		t.Error("failed endorsement is submitted")
+	}
+}
+
+func TestInternalInvokeAsyncInvalid(t *testing.T) {
+	l, pc, oc := newFakeLedger(t, peer.TxValidationCode_MVCC_READ_CONFLICT)
+	close(l.release)
+
+	commit, err := InternalInvokeAsync(ChainOpt{Name: "basic"}, newTestMSPOpt(t), [][]byte{[]byte("set"), []byte("a")},
+		nil, "mychannel", []*client.PeerClient{pc}, []*client.OrdererClient{oc})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	<-commit.Done()
+	status, err := commit.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Successful() || status.Code != peer.TxValidationCode_MVCC_READ_CONFLICT {
+		t.Errorf("status: %+v", status)
+	}
+}
@@ -0,0 +1,434 @@
+package chaincode
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/ledger/rwset"
+	"github.com/hyperledger/fabric-protos-go/orderer"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/msp"
+	"github.com/hyperledger/fabric/protoutil"
+)
+
+//SeekOldest start from the genesis block
+func SeekOldest() *orderer.SeekPosition {
+	return &orderer.SeekPosition{Type: &orderer.SeekPosition_Oldest{Oldest: &orderer.SeekOldest{}}}
+}
+
+//SeekNewest start from the newest block when connected
+func SeekNewest() *orderer.SeekPosition {
+	return &orderer.SeekPosition{Type: &orderer.SeekPosition_Newest{Newest: &orderer.SeekNewest{}}}
+}
+
+//SeekBlock start from block number
+func SeekBlock(number uint64) *orderer.SeekPosition {
+	return &orderer.SeekPosition{Type: &orderer.SeekPosition_Specified{
+		Specified: &orderer.SeekSpecified{Number: number},
+	}}
+}
+
+//BlockEvent a delivered block, Block is set for full blocks, Filtered for filtered blocks
+type BlockEvent struct {
+	Number   uint64
+	Block    *common.Block
+	Filtered *peer.FilteredBlock
+	// PrivateData transaction index in block -> private read write set,
+	// only for blocks delivered with private data, collections the client's org is not member of are not included
+	PrivateData map[uint64]*rwset.TxPvtReadWriteSet
+}
+
+// deliverKind which deliver service of peers
+type deliverKind int
+
+const (
+	deliverBlocks deliverKind = iota
+	deliverFiltered
+	deliverPrivateData
+)
+
+// openDeliver send the seek info envelope to the deliver service kind of pc
+func openDeliver(ctx context.Context, pc *client.PeerClient, kind deliverKind,
+	env *common.Envelope) (func() (*peer.DeliverResponse, error), error) {
+	if env == nil {
+		return nil, fmt.Errorf("create deliver envelope failed")
+	}
+
+	dc, err := pc.PeerDeliver()
+	if err != nil {
+		return nil, fmt.Errorf("get deliver client failed: %v", err)
+	}
+
+	var stream interface {
+		Send(*common.Envelope) error
+		Recv() (*peer.DeliverResponse, error)
+	}
+	switch kind {
+	case deliverFiltered:
+		stream, err = dc.DeliverFiltered(ctx)
+	case deliverPrivateData:
+		stream, err = dc.DeliverWithPrivateData(ctx)
+	default:
+		stream, err = dc.Deliver(ctx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("connect to deliver failed: %v", err)
+	}
+
+	if err = stream.Send(env); err != nil {
+		return nil, fmt.Errorf("send deliver seek info envelope failed: %v", err)
+	}
+
+	return stream.Recv, nil
+}
+
+// blockEvent the block of resp, nil if resp is a status
+func blockEvent(resp *peer.DeliverResponse) (*BlockEvent, error) {
+	switch r := resp.Type.(type) {
+	case *peer.DeliverResponse_Block:
+		if r.Block.Header == nil {
+			return nil, fmt.Errorf("received block without header")
+		}
+		return &BlockEvent{Number: r.Block.Header.Number, Block: r.Block}, nil
+	case *peer.DeliverResponse_FilteredBlock:
+		return &BlockEvent{Number: r.FilteredBlock.Number, Filtered: r.FilteredBlock}, nil
+	case *peer.DeliverResponse_BlockAndPrivateData:
+		b := r.BlockAndPrivateData
+		if b.Block == nil || b.Block.Header == nil {
+			return nil, fmt.Errorf("received block without header")
+		}
+		return &BlockEvent{Number: b.Block.Header.Number, Block: b.Block, PrivateData: b.PrivateDataMap}, nil
+	case *peer.DeliverResponse_Status:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("received unexpected response type (%T)", r)
+	}
+}
+
+//FetchBlocks blocks from number from to to of the channel, fail if any of them is not committed yet,
+// withPrivateData deliver private data of collections the client's org is member of
+func FetchBlocks(ctx context.Context, mspOpt MSPOpt, channelID string, pc *client.PeerClient,
+	from, to uint64, withPrivateData bool) ([]*BlockEvent, error) {
+	if from > to {
+		return nil, fmt.Errorf("invalid block range [%d, %d]", from, to)
+	}
+
+	signer, err := GetSignerByOpt(mspOpt)
+	if err != nil {
+		return nil, err
+	}
+
+	kind := deliverBlocks
+	if withPrivateData {
+		kind = deliverPrivateData
+	}
+
+	blocks, err := fetchBlocks(ctx, signer, channelID, pc, kind, SeekBlock(from), SeekBlock(to))
+	if err != nil {
+		return nil, fmt.Errorf("fetch blocks [%d, %d] failed: %v", from, to, err)
+	}
+	if uint64(len(blocks)) != to-from+1 {
+		return nil, fmt.Errorf("fetch blocks [%d, %d] received %d blocks", from, to, len(blocks))
+	}
+	return blocks, nil
+}
+
+// fetchBlocks blocks from start to stop, fail if any of them is not committed yet
+func fetchBlocks(ctx context.Context, signer msp.SigningIdentity, channelID string, pc *client.PeerClient,
+	kind deliverKind, start, stop *orderer.SeekPosition) ([]*BlockEvent, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	recv, err := openDeliver(ctx, pc, kind, createSeekInfoEnvelope(channelID, pc.Certificate(), signer, &orderer.SeekInfo{
+		Start:    start,
+		Stop:     stop,
+		Behavior: orderer.SeekInfo_FAIL_IF_NOT_READY,
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks []*BlockEvent
+	for {
+		resp, err := recv()
+		if err != nil {
+			return nil, fmt.Errorf("receive from deliver failed: %v", err)
+		}
+
+		event, err := blockEvent(resp)
+		if err != nil {
+			return nil, err
+		}
+		if event != nil {
+			blocks = append(blocks, event)
+			continue
+		}
+
+		if status := resp.GetStatus(); status != common.Status_SUCCESS {
+			return nil, fmt.Errorf("deliver failed with status (%s)", status)
+		}
+		return blocks, nil
+	}
+}
+
+//FetchChannelConfig the latest config of the channel, from the config block of the newest block of pc
+func FetchChannelConfig(ctx context.Context, mspOpt MSPOpt, channelID string, pc *client.PeerClient) (*common.Config, error) {
+	signer, err := GetSignerByOpt(mspOpt)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks, err := fetchBlocks(ctx, signer, channelID, pc, deliverBlocks, SeekNewest(), SeekNewest())
+	if err != nil {
+		return nil, fmt.Errorf("fetch newest block failed: %v", err)
+	}
+	if len(blocks) != 1 {
+		return nil, fmt.Errorf("fetch newest block received %d blocks", len(blocks))
+	}
+
+	index, err := protoutil.GetLastConfigIndexFromBlock(blocks[0].Block)
+	if err != nil {
+		return nil, fmt.Errorf("get last config index of block [%d] failed: %v", blocks[0].Number, err)
+	}
+
+	if index != blocks[0].Number {
+		blocks, err = fetchBlocks(ctx, signer, channelID, pc, deliverBlocks, SeekBlock(index), SeekBlock(index))
+		if err != nil {
+			return nil, fmt.Errorf("fetch config block [%d] failed: %v", index, err)
+		}
+		if len(blocks) != 1 {
+			return nil, fmt.Errorf("fetch config block [%d] received %d blocks", index, len(blocks))
+		}
+	}
+
+	env, err := protoutil.ExtractEnvelope(blocks[0].Block, 0)
+	if err != nil {
+		return nil, fmt.Errorf("extract config envelope of block [%d] failed: %v", index, err)
+	}
+
+	payload, err := protoutil.UnmarshalPayload(env.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal payload of block [%d] failed: %v", index, err)
+	}
+
+	configEnv := &common.ConfigEnvelope{}
+	if err = proto.Unmarshal(payload.Data, configEnv); err != nil {
+		return nil, fmt.Errorf("unmarshal config envelope of block [%d] failed: %v", index, err)
+	}
+	if configEnv.Config == nil {
+		return nil, fmt.Errorf("block [%d] is not a config block", index)
+	}
+
+	return configEnv.Config, nil
+}
+
+//BlockListener deliver blocks of a channel from peers one at a time to a handler, in order and exactly once,
+// once the stream of a peer fails, the next peer is connected from the block after the last handled one
+type BlockListener struct {
+	signer    msp.SigningIdentity
+	channelID string
+	peers     []*client.PeerClient
+	kind      deliverKind
+	start     *orderer.SeekPosition
+	handler   func(*BlockEvent) error
+	// checkpointer save every handled block, nil if not checkpointed
+	checkpointer Checkpointer
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+
+	mutex sync.Mutex
+	// handled the last handled block number, valid if started is true
+	handled uint64
+	started bool
+}
+
+//ListenFrom start position of the listener, default is SeekNewest
+func ListenFrom(start *orderer.SeekPosition) func(*BlockListener) {
+	return func(l *BlockListener) {
+		l.start = start
+	}
+}
+
+//ListenFiltered deliver filtered blocks instead of full blocks, BlockEvent.Filtered is set
+func ListenFiltered() func(*BlockListener) {
+	return func(l *BlockListener) {
+		l.kind = deliverFiltered
+	}
+}
+
+//ListenWithPrivateData deliver full blocks with private data of collections the client's org is member of,
+// BlockEvent.PrivateData is set, see BlockTransactions
+func ListenWithPrivateData() func(*BlockListener) {
+	return func(l *BlockListener) {
+		l.kind = deliverPrivateData
+	}
+}
+
+//ListenWithCheckpointer resume from the checkpoint of cp instead of the start position if one is saved,
+// the checkpoint is saved as complete after every handled block, the listener ends if saving fails,
+// a block whose checkpoint is not complete is delivered again
+func ListenWithCheckpointer(cp Checkpointer) func(*BlockListener) {
+	return func(l *BlockListener) {
+		l.checkpointer = cp
+	}
+}
+
+//ListenBlocks call handler with every block of the channel from the start position,
+// the listener ends once ctx is done, Close is called, or handler returns a error (the block is not handled),
+// handler is called from one goroutine
+func ListenBlocks(ctx context.Context, mspOpt MSPOpt, channelID string, peers []*client.PeerClient,
+	handler func(*BlockEvent) error, opts ...func(*BlockListener)) (*BlockListener, error) {
+	if len(peers) == 0 {
+		return nil, fmt.Errorf("peer clients' number is 0")
+	}
+
+	signer, err := GetSignerByOpt(mspOpt)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &BlockListener{signer: signer, channelID: channelID, peers: peers, handler: handler, done: make(chan struct{})}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	if l.checkpointer != nil {
+		c, err := l.checkpointer.Load()
+		if err != nil {
+			return nil, fmt.Errorf("load checkpoint failed: %v", err)
+		}
+		if c != nil {
+			l.start = SeekBlock(c.start())
+		}
+	}
+
+	l.ctx, l.cancel = context.WithCancel(ctx)
+	go l.run()
+	return l, nil
+}
+
+//Done closed once the listener ends
+func (l *BlockListener) Done() <-chan struct{} { return l.done }
+
+//Err why the listener ended, valid after Done is closed
+func (l *BlockListener) Err() error { return l.err }
+
+//Close end the listener
+func (l *BlockListener) Close() { l.cancel() }
+
+//LastBlock the last handled block number, false if no block is handled
+func (l *BlockListener) LastBlock() (uint64, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.handled, l.started
+}
+
+// handlerError the handler failed, the listener stops instead of reconnecting
+type handlerError struct{ err error }
+
+func (e *handlerError) Error() string { return e.err.Error() }
+
+func (l *BlockListener) run() {
+	defer close(l.done)
+	defer l.cancel()
+
+	for i := 0; ; i++ {
+		pc := l.peers[i%len(l.peers)]
+
+		err := l.deliver(pc)
+		if l.ctx.Err() != nil {
+			l.err = l.ctx.Err()
+			return
+		}
+		if h, ok := err.(*handlerError); ok {
+			l.err = h.err
+			return
+		}
+		log.Printf("deliver of peer [%s] is interrupted, connect to the next peer: %v", pc.Address(), err)
+
+		// every peer failed, wait before the next round
+		if (i+1)%len(l.peers) == 0 {
+			select {
+			case <-time.After(reconnectDelay):
+			case <-l.ctx.Done():
+				l.err = l.ctx.Err()
+				return
+			}
+		}
+	}
+}
+
+// next position to seek from, the block after the last handled one
+func (l *BlockListener) next() *orderer.SeekPosition {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.started {
+		return SeekBlock(l.handled + 1)
+	}
+	return l.start
+}
+
+func (l *BlockListener) deliver(pc *client.PeerClient) error {
+	recv, err := openDeliver(l.ctx, pc, l.kind, createSeekEnvelope(l.channelID, pc.Certificate(), l.signer, l.next()))
+	if err != nil {
+		return err
+	}
+
+	for {
+		resp, err := recv()
+		if err != nil {
+			return fmt.Errorf("receive from deliver failed: %v", err)
+		}
+
+		event, err := blockEvent(resp)
+		if err != nil {
+			return err
+		}
+		if event == nil {
+			return fmt.Errorf("deliver completed with status (%s)", resp.GetStatus())
+		}
+
+		if err = l.handle(event); err != nil {
+			return err
+		}
+	}
+}
+
+// handle skip blocks handled already, eg: the newest block delivered again after reconnected
+func (l *BlockListener) handle(event *BlockEvent) error {
+	l.mutex.Lock()
+	handled, started := l.handled, l.started
+	l.mutex.Unlock()
+
+	if started && event.Number <= handled {
+		return nil
+	}
+	if started && event.Number != handled+1 {
+		return fmt.Errorf("received block %d, want %d", event.Number, handled+1)
+	}
+
+	if err := l.handler(event); err != nil {
+		return &handlerError{err: fmt.Errorf("handle block %d failed: %v", event.Number, err)}
+	}
+
+	if l.checkpointer != nil {
+		if err := l.checkpointer.Save(&Checkpoint{BlockNumber: event.Number, Complete: true}); err != nil {
+			return &handlerError{err: fmt.Errorf("save checkpoint of block %d failed: %v", event.Number, err)}
+		}
+	}
+
+	l.mutex.Lock()
+	l.handled, l.started = event.Number, true
+	l.mutex.Unlock()
+	return nil
+}
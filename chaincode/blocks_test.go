@@ -0,0 +1,113 @@
+package chaincode
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+func TestListenBlocks(t *testing.T) {
+	l, pc, _ := newFakeLedger(t, peer.TxValidationCode_VALID)
+	close(l.release)
+	mspOpt := newTestMSPOpt(t)
+	down, stop := l.serveDeliver(t)
+
+	l.commit("tx0")
+	l.commit("tx1")
+
+	tests := []struct {
+		name     string
+		opts     []func(*BlockListener)
+		filtered bool
+		first    uint64
+	}{
+		{"oldest", []func(*BlockListener){ListenFrom(SeekOldest())}, false, 0},
+		{"newest", nil, false, 1},
+		{"filtered from block", []func(*BlockListener){ListenFiltered(), ListenFrom(SeekBlock(1))}, true, 1},
+	}
+
+	var listeners []*BlockListener
+	var received []chan *BlockEvent
+	for _, tt := range tests {
+		blocks := make(chan *BlockEvent, 10)
+		listener, err := ListenBlocks(context.Background(), mspOpt, "mychannel", []*client.PeerClient{down, pc},
+			func(b *BlockEvent) error {
+				blocks <- b
+				return nil
+			}, tt.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+
+		listeners = append(listeners, listener)
+		received = append(received, blocks)
+	}
+
+	next := func(blocks chan *BlockEvent) *BlockEvent {
+		select {
+		case b := <-blocks:
+			return b
+		case <-time.After(3 * time.Second):
+			t.Fatal("no block received")
+		}
+		return nil
+	}
+
+	for i, tt := range tests {
+		for n := tt.first; n < 2; n++ {
+			b := next(received[i])
+			if b.Number != n || (b.Filtered != nil) != tt.filtered || (b.Block != nil) == tt.filtered {
+				t.Errorf("%s: block %+v, want %d", tt.name, b, n)
+			}
+		}
+	}
+
+	// interrupted, every listener continues from block 2 on the next peer
+	stop()
+	l.commit("tx2")
+	for i, tt := range tests {
+		if b := next(received[i]); b.Number != 2 {
+			t.Errorf("%s: block %d after reconnected, want 2", tt.name, b.Number)
+		}
+		if last, ok := listeners[i].LastBlock(); !ok || last != 2 {
+			t.Errorf("%s: last block %d, %v", tt.name, last, ok)
+		}
+
+		select {
+		case b := <-received[i]:
+			t.Errorf("%s: block %d delivered twice", tt.name, b.Number)
+		default:
+		}
+	}
+}
+
+func TestListenBlocksHandlerError(t *testing.T) {
+	l, pc, _ := newFakeLedger(t, peer.TxValidationCode_VALID)
+	close(l.release)
+	l.commit("tx0")
+	l.commit("tx1")
+
+	listener, err := ListenBlocks(context.Background(), newTestMSPOpt(t), "mychannel", []*client.PeerClient{pc},
+		func(b *BlockEvent) error {
+			if b.Number == 1 {
+				return fmt.Errorf("database is down")
+			}
+			return nil
+		}, ListenFrom(SeekOldest()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	<-listener.Done()
+	if listener.Err() == nil {
+		t.Error("listener continues after handler failed")
+	}
+	if last, ok := listener.LastBlock(); !ok || last != 0 {
+		t.Errorf("last block %d, %v", last, ok)
+	}
+}
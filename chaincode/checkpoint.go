@@ -0,0 +1,149 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+//Checkpoint position of a event consumer: the last block it processed or is processing,
+// and the transactions of the block processed already
+type Checkpoint struct {
+	BlockNumber uint64 `json:"blockNumber"`
+	// TxIDs processed transactions of block BlockNumber, their events are not delivered again
+	TxIDs []string `json:"txIDs,omitempty"`
+	// Complete the whole block is processed, consumers resume from the next block
+	Complete bool `json:"complete"`
+}
+
+//Processed txID of the block is processed
+func (c *Checkpoint) Processed(txID string) bool {
+	if c.Complete {
+		return true
+	}
+	for _, id := range c.TxIDs {
+		if id == txID {
+			return true
+		}
+	}
+	return false
+}
+
+// start position to resume from, the block itself if it is not complete
+func (c *Checkpoint) start() uint64 {
+	if c.Complete {
+		return c.BlockNumber + 1
+	}
+	return c.BlockNumber
+}
+
+//Checkpointer store the checkpoint of a event consumer, Save replaces the checkpoint atomically,
+// see ListenWithCheckpointer and WithCheckpointer
+type Checkpointer interface {
+	// Load the saved checkpoint, nil if nothing is saved
+	Load() (*Checkpoint, error)
+	Save(*Checkpoint) error
+}
+
+//FileCheckpointer store the checkpoint in a json file
+type FileCheckpointer struct {
+	path  string
+	mutex sync.Mutex
+}
+
+//NewFileCheckpointer new file checkpointer of path, create the directory of path if not exist
+func NewFileCheckpointer(path string) (*FileCheckpointer, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("create checkpoint directory failed: %v", err)
+	}
+
+	return &FileCheckpointer{path: path}, nil
+}
+
+//Load read the checkpoint file, nil if the file not exist
+func (f *FileCheckpointer) Load() (*Checkpoint, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	data, err := ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read checkpoint file failed: %v", err)
+	}
+
+	c := &Checkpoint{}
+	if err = json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("unmarshal checkpoint failed: %v", err)
+	}
+	return c, nil
+}
+
+//Save write to a temporary file, sync then rename, so the checkpoint file is never half written
+func (f *FileCheckpointer) Save(c *Checkpoint) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("marshal checkpoint failed: %v", err)
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	tmp, err := ioutil.TempFile(filepath.Dir(f.path), "."+filepath.Base(f.path)+"-*")
+	if err != nil {
+		return fmt.Errorf("create temporary file failed: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("write temporary file failed: %v", err)
+	}
+
+	return os.Rename(tmp.Name(), f.path)
+}
+
+//InMemoryCheckpointer store the checkpoint in memory, for consumers restarting within the process
+type InMemoryCheckpointer struct {
+	mutex      sync.Mutex
+	checkpoint *Checkpoint
+}
+
+//NewInMemoryCheckpointer new empty in-memory checkpointer
+func NewInMemoryCheckpointer() *InMemoryCheckpointer {
+	return &InMemoryCheckpointer{}
+}
+
+//Load a copy of the saved checkpoint
+func (m *InMemoryCheckpointer) Load() (*Checkpoint, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.checkpoint.copy(), nil
+}
+
+//Save store a copy of c
+func (m *InMemoryCheckpointer) Save(c *Checkpoint) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.checkpoint = c.copy()
+	return nil
+}
+
+func (c *Checkpoint) copy() *Checkpoint {
+	if c == nil {
+		return nil
+	}
+	cp := *c
+	cp.TxIDs = append([]string(nil), c.TxIDs...)
+	return &cp
+}
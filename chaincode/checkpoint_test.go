@@ -0,0 +1,299 @@
+package chaincode
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+func testCheckpointer(t *testing.T, cp Checkpointer) {
+	c, err := cp.Load()
+	if err != nil || c != nil {
+		t.Fatalf("empty checkpointer loaded %v, %v", c, err)
+	}
+
+	saved := &Checkpoint{BlockNumber: 3, TxIDs: []string{"tx1", "tx2"}}
+	if err = cp.Save(saved); err != nil {
+		t.Fatal(err)
+	}
+	saved.TxIDs[0] = "changed"
+
+	if c, err = cp.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if want := (&Checkpoint{BlockNumber: 3, TxIDs: []string{"tx1", "tx2"}}); !reflect.DeepEqual(c, want) {
+		t.Errorf("loaded %+v, want %+v", c, want)
+	}
+	if !c.Processed("tx2") || c.Processed("tx3") || c.start() != 3 {
+		t.Errorf("checkpoint %+v", c)
+	}
+
+	if err = cp.Save(&Checkpoint{BlockNumber: 3, Complete: true}); err != nil {
+		t.Fatal(err)
+	}
+	if c, err = cp.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Complete || len(c.TxIDs) != 0 || !c.Processed("tx3") || c.start() != 4 {
+		t.Errorf("complete checkpoint %+v", c)
+	}
+}
+
+func TestInMemoryCheckpointer(t *testing.T) {
+	testCheckpointer(t, NewInMemoryCheckpointer())
+}
+
+func TestFileCheckpointer(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "checkpoints", "mychannel.json")
+
+	cp, err := NewFileCheckpointer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCheckpointer(t, cp)
+
+	// a new checkpointer of the same file, eg: after a restart
+	cp, err = NewFileCheckpointer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, err := cp.Load(); err != nil || c == nil || c.BlockNumber != 3 || !c.Complete {
+		t.Errorf("reloaded %+v, %v", c, err)
+	}
+
+	files, err := ioutil.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name() != "mychannel.json" {
+		t.Errorf("temporary files are left: %v", files)
+	}
+}
+
+func TestListenBlocksWithCheckpointer(t *testing.T) {
+	l, pc, _ := newFakeLedger(t, peer.TxValidationCode_VALID)
+	close(l.release)
+	mspOpt := newTestMSPOpt(t)
+	cp := NewInMemoryCheckpointer()
+
+	l.commit("tx0")
+	l.commit("tx1")
+
+	listen := func() (*BlockListener, chan *BlockEvent) {
+		blocks := make(chan *BlockEvent, 10)
+		listener, err := ListenBlocks(context.Background(), mspOpt, "mychannel", []*client.PeerClient{pc},
+			func(b *BlockEvent) error {
+				blocks <- b
+				return nil
+			}, ListenFrom(SeekOldest()), ListenWithCheckpointer(cp))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return listener, blocks
+	}
+
+	next := func(blocks chan *BlockEvent) uint64 {
+		select {
+		case b := <-blocks:
+			return b.Number
+		case <-time.After(3 * time.Second):
+			t.Fatal("no block received")
+		}
+		return 0
+	}
+
+	listener, blocks := listen()
+	for n := uint64(0); n < 2; n++ {
+		if got := next(blocks); got != n {
+			t.Fatalf("block %d, want %d", got, n)
+		}
+	}
+	listener.Close()
+	<-listener.Done()
+
+	if c, _ := cp.Load(); c == nil || c.BlockNumber != 1 || !c.Complete {
+		t.Fatalf("checkpoint %+v", c)
+	}
+
+	// restarted from the block after the checkpoint, not from the oldest
+	l.commit("tx2")
+	listener, blocks = listen()
+	defer listener.Close()
+	if got := next(blocks); got != 2 {
+		t.Errorf("block %d after restarted, want 2", got)
+	}
+}
+
+func TestSubscribeChaincodeEventsWithCheckpointer(t *testing.T) {
+	l, pc, oc := newFakeLedger(t, peer.TxValidationCode_VALID)
+	close(l.release)
+	mspOpt := newTestMSPOpt(t)
+
+	cp, err := NewFileCheckpointer(filepath.Join(t.TempDir(), "events.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	put := func(key string) string {
+		commit, err := InternalInvokeAsync(ChainOpt{Name: "basic"}, mspOpt, [][]byte{[]byte("put"), []byte(key), []byte(key)},
+			nil, "mychannel", []*client.PeerClient{pc}, []*client.OrdererClient{oc})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = commit.Status(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return commit.TxID()
+	}
+
+	subscribe := func() *EventSubscription {
+		s, err := SubscribeChaincodeEvents(context.Background(), mspOpt, "mychannel", "basic", "",
+			[]*client.PeerClient{pc}, FromBlock(0), WithCheckpointer(cp), WithEventBuffer(10))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	tx0, tx1 := put("a"), put("b")
+
+	// block 0 was processed before, tx0 in it is not delivered again
+	if err = cp.Save(&Checkpoint{BlockNumber: 0, TxIDs: []string{tx0}}); err != nil {
+		t.Fatal(err)
+	}
+
+	s := subscribe()
+	e := nextEvent(t, s)
+	if e.TxID != tx1 || e.BlockNumber != 1 {
+		t.Errorf("event %+v, want [%s] of block 1", e, tx1)
+	}
+	if err = s.Checkpoint(&ChaincodeEvent{TxID: tx0}); err == nil {
+		t.Error("checkpointed a event not received last")
+	}
+	if err = s.Checkpoint(e); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	for range s.Events() {
+	}
+
+	if c, _ := cp.Load(); c == nil || c.BlockNumber != 1 || !c.Processed(tx1) {
+		t.Fatalf("checkpoint %+v", c)
+	}
+
+	// restarted, only the new event is delivered
+	tx2 := put("c")
+	s = subscribe()
+	defer s.Close()
+	if e := nextEvent(t, s); e.TxID != tx2 || e.BlockNumber != 2 {
+		t.Errorf("event %+v after restarted, want [%s] of block 2", e, tx2)
+	}
+}
+
+func TestSubscribeChaincodeEventsRedelivery(t *testing.T) {
+	l, pc, oc := newFakeLedger(t, peer.TxValidationCode_VALID)
+	close(l.release)
+	mspOpt := newTestMSPOpt(t)
+	cp := NewInMemoryCheckpointer()
+
+	put := func(key string) string {
+		commit, err := InternalInvokeAsync(ChainOpt{Name: "basic"}, mspOpt, [][]byte{[]byte("put"), []byte(key), []byte(key)},
+			nil, "mychannel", []*client.PeerClient{pc}, []*client.OrdererClient{oc})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = commit.Status(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return commit.TxID()
+	}
+
+	subscribe := func() *EventSubscription {
+		s, err := SubscribeChaincodeEvents(context.Background(), mspOpt, "mychannel", "basic", "",
+			[]*client.PeerClient{pc}, FromBlock(0), WithCheckpointer(cp))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	tx0, tx1 := put("a"), put("b")
+
+	s := subscribe()
+	e := nextEvent(t, s)
+	if e.TxID != tx0 {
+		t.Fatalf("event %+v, want [%s]", e, tx0)
+	}
+	if err := s.Checkpoint(e); err != nil {
+		t.Fatal(err)
+	}
+
+	// crashed while processing tx1, it is received but not checkpointed, only block 0 is
+	if e = nextEvent(t, s); e.TxID != tx1 {
+		t.Fatalf("event %+v, want [%s]", e, tx1)
+	}
+	s.Close()
+	for range s.Events() {
+	}
+	if err := s.Checkpoint(e); err == nil {
+		t.Error("checkpointed after closed")
+	}
+
+	if c, _ := cp.Load(); c == nil || c.BlockNumber != 0 || !c.Complete {
+		t.Fatalf("checkpoint %+v", c)
+	}
+
+	// restarted, tx1 is delivered again
+	s = subscribe()
+	defer s.Close()
+	if e = nextEvent(t, s); e.TxID != tx1 || e.BlockNumber != 1 {
+		t.Errorf("event %+v after restarted, want [%s] of block 1", e, tx1)
+	}
+}
+
+func TestSubscribeChaincodeEventsCheckpointTimeout(t *testing.T) {
+	l, pc, oc := newFakeLedger(t, peer.TxValidationCode_VALID)
+	close(l.release)
+	mspOpt := newTestMSPOpt(t)
+
+	commit, err := InternalInvokeAsync(ChainOpt{Name: "basic"}, mspOpt, [][]byte{[]byte("put"), []byte("a"), []byte("a")},
+		nil, "mychannel", []*client.PeerClient{pc}, []*client.OrdererClient{oc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = commit.Status(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := SubscribeChaincodeEvents(context.Background(), mspOpt, "mychannel", "basic", "",
+		[]*client.PeerClient{pc}, FromBlock(0), WithCheckpointer(NewInMemoryCheckpointer()),
+		WithCheckpointTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// the consumer never checkpoints the event, the subscription ends instead of blocking
+	if e := nextEvent(t, s); e.TxID != commit.TxID() {
+		t.Fatalf("event %+v, want [%s]", e, commit.TxID())
+	}
+	select {
+	case _, ok := <-s.Events():
+		if ok {
+			t.Fatal("event delivered before checkpointed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription is not ended")
+	}
+	if err = s.Err(); err == nil || !strings.Contains(err.Error(), "not checkpointed") {
+		t.Errorf("err: %v", err)
+	}
+}
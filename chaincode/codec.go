@@ -0,0 +1,294 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+//Codec encode go values to chaincode arguments, decode chaincode results to go values
+type Codec interface {
+	Encode(v interface{}) ([]byte, error)
+	// Decode v is a pointer
+	Decode(data []byte, v interface{}) error
+}
+
+//DefaultCodec the encoding most chaincodes use:
+// []byte as is, string as utf-8, bool and numbers as their text, proto.Message as protobuf, others as json
+var DefaultCodec Codec = defaultCodec{}
+
+//JSONCodec every value as json, eg: strings are quoted
+var JSONCodec Codec = jsonCodec{}
+
+//ProtoCodec proto.Message only
+var ProtoCodec Codec = protoCodec{}
+
+type defaultCodec struct{}
+
+func (defaultCodec) Encode(v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	case bool:
+		return []byte(strconv.FormatBool(v)), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return []byte(fmt.Sprint(v)), nil
+	case float32:
+		return []byte(strconv.FormatFloat(float64(v), 'g', -1, 32)), nil
+	case float64:
+		return []byte(strconv.FormatFloat(v, 'g', -1, 64)), nil
+	case proto.Message:
+		return protoCodec{}.Encode(v)
+	}
+	return jsonCodec{}.Encode(v)
+}
+
+func (defaultCodec) Decode(data []byte, v interface{}) error {
+	var err error
+	switch v := v.(type) {
+	case *[]byte:
+		*v = append([]byte(nil), data...)
+	case *string:
+		*v = string(data)
+	case *bool:
+		*v, err = strconv.ParseBool(string(data))
+	case *int:
+		var i int64
+		i, err = strconv.ParseInt(string(data), 10, strconv.IntSize)
+		*v = int(i)
+	case *int32:
+		var i int64
+		i, err = strconv.ParseInt(string(data), 10, 32)
+		*v = int32(i)
+	case *int64:
+		*v, err = strconv.ParseInt(string(data), 10, 64)
+	case *uint:
+		var i uint64
+		i, err = strconv.ParseUint(string(data), 10, strconv.IntSize)
+		*v = uint(i)
+	case *uint32:
+		var i uint64
+		i, err = strconv.ParseUint(string(data), 10, 32)
+		*v = uint32(i)
+	case *uint64:
+		*v, err = strconv.ParseUint(string(data), 10, 64)
+	case *float32:
+		var f float64
+		f, err = strconv.ParseFloat(string(data), 32)
+		*v = float32(f)
+	case *float64:
+		*v, err = strconv.ParseFloat(string(data), 64)
+	case proto.Message:
+		return protoCodec{}.Decode(data, v)
+	default:
+		return jsonCodec{}.Decode(data, v)
+	}
+
+	if err != nil {
+		return fmt.Errorf("decode [%s] to %T failed: %v", data, v, err)
+	}
+	return nil
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Encode(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("json marshal %T failed: %v", v, err)
+	}
+	return data, nil
+}
+
+func (jsonCodec) Decode(data []byte, v interface{}) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("json unmarshal to %T failed: %v", v, err)
+	}
+	return nil
+}
+
+type protoCodec struct{}
+
+func (protoCodec) Encode(v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a proto message", v)
+	}
+
+	data, err := proto.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("proto marshal %T failed: %v", v, err)
+	}
+	return data, nil
+}
+
+func (protoCodec) Decode(data []byte, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%T is not a proto message", v)
+	}
+
+	if err := proto.Unmarshal(data, m); err != nil {
+		return fmt.Errorf("proto unmarshal to %T failed: %v", v, err)
+	}
+	return nil
+}
+
+//EncodeArgs chaincode arguments, the function name first
+func EncodeArgs(codec Codec, function string, args ...interface{}) ([][]byte, error) {
+	if codec == nil {
+		codec = DefaultCodec
+	}
+
+	res := [][]byte{[]byte(function)}
+	for i, arg := range args {
+		data, err := codec.Encode(arg)
+		if err != nil {
+			return nil, fmt.Errorf("encode argument %d failed: %v", i, err)
+		}
+		res = append(res, data)
+	}
+	return res, nil
+}
+
+//EncodeTransient transient data, see privateData of Invoke
+func EncodeTransient(codec Codec, transient map[string]interface{}) (map[string][]byte, error) {
+	if codec == nil {
+		codec = DefaultCodec
+	}
+
+	if transient == nil {
+		return nil, nil
+	}
+
+	res := make(map[string][]byte, len(transient))
+	for k, v := range transient {
+		data, err := codec.Encode(v)
+		if err != nil {
+			return nil, fmt.Errorf("encode transient [%s] failed: %v", k, err)
+		}
+		res[k] = data
+	}
+	return res, nil
+}
+
+//DecodeResult decode the payload of a successful proposal response into v
+func DecodeResult(codec Codec, resp *peer.ProposalResponse, v interface{}) error {
+	if codec == nil {
+		codec = DefaultCodec
+	}
+
+	if resp == nil || resp.Response == nil {
+		return fmt.Errorf("no response to decode")
+	}
+
+	if resp.Response.Status >= shim.ERRORTHRESHOLD {
+		return fmt.Errorf("response failed with status: %d - %s", resp.Response.Status, resp.Response.Message)
+	}
+
+	return codec.Decode(resp.Response.Payload, v)
+}
+
+//TypedCall chaincode function call with go values
+type TypedCall struct {
+	// Codec nil means DefaultCodec
+	Codec     Codec
+	Function  string
+	Args      []interface{}
+	Transient map[string]interface{}
+	// Result pointer the payload of the response is decoded into, nil means not decode
+	Result interface{}
+}
+
+func (c TypedCall) encode() ([][]byte, map[string][]byte, error) {
+	args, err := EncodeArgs(c.Codec, c.Function, c.Args...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	transient, err := EncodeTransient(c.Codec, c.Transient)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return args, transient, nil
+}
+
+func (c TypedCall) decode(resp *peer.ProposalResponse) error {
+	if c.Result == nil || resp == nil || resp.Response == nil || resp.Response.Status >= shim.ERRORTHRESHOLD {
+		return nil
+	}
+
+	return DecodeResult(c.Codec, resp, c.Result)
+}
+
+//InvokeTyped Invoke with go values, call.Result is decoded if the endorsement succeeds
+func InvokeTyped(chaincode ChainOpt, mspOpt MSPOpt, call TypedCall, channelID string,
+	peers []Endpoint, orderers []Endpoint) (*peer.ProposalResponse, error) {
+	args, transient, err := call.encode()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := Invoke(chaincode, mspOpt, args, transient, channelID, peers, orderers)
+	if err != nil {
+		return resp, err
+	}
+
+	return resp, call.decode(resp)
+}
+
+//InternalInvokeTyped InternalInvoke with go values, call.Result is decoded if the endorsement succeeds
+func InternalInvokeTyped(chaincode ChainOpt, mspOpt MSPOpt, call TypedCall, channelID string,
+	peers []*client.PeerClient, orderers []*client.OrdererClient) (*peer.ProposalResponse, error) {
+	args, transient, err := call.encode()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := InternalInvoke(chaincode, mspOpt, args, transient, channelID, peers, orderers)
+	if err != nil {
+		return resp, err
+	}
+
+	return resp, call.decode(resp)
+}
+
+//QueryTyped Query with go values, call.Result is decoded
+func QueryTyped(chaincode ChainOpt, mspOpt MSPOpt, call TypedCall, channelID string,
+	peers []Endpoint) (*peer.ProposalResponse, error) {
+	args, transient, err := call.encode()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := Query(chaincode, mspOpt, args, transient, channelID, peers)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, call.decode(resp)
+}
+
+//InternalQueryTyped InternalQuery with go values, call.Result is decoded
+func InternalQueryTyped(chaincode ChainOpt, mspOpt MSPOpt, call TypedCall, channelID string,
+	peers []*client.PeerClient) (*peer.ProposalResponse, error) {
+	args, transient, err := call.encode()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := InternalQuery(chaincode, mspOpt, args, transient, channelID, peers)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, call.decode(resp)
+}
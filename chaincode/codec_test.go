@@ -0,0 +1,109 @@
+package chaincode
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+type asset struct {
+	ID    string `json:"id"`
+	Value int    `json:"value"`
+}
+
+func TestDefaultCodec(t *testing.T) {
+	args, err := EncodeArgs(nil, "set", "a", 1, int64(-2), 1.5, true, []byte{0xff},
+		asset{ID: "a", Value: 1}, &peer.ChaincodeID{Name: "basic"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"set", "a", "1", "-2", "1.5", "true", "\xff", `{"id":"a","value":1}`}
+	for i, w := range want {
+		if string(args[i]) != w {
+			t.Errorf("arg %d: %q, want %q", i, args[i], w)
+		}
+	}
+
+	id := &peer.ChaincodeID{}
+	if err = DefaultCodec.Decode(args[8], id); err != nil || id.Name != "basic" {
+		t.Errorf("proto: %v, %v", id, err)
+	}
+
+	var i int
+	var f float64
+	var b bool
+	var s string
+	var a asset
+	for _, d := range []struct {
+		data []byte
+		v    interface{}
+	}{{args[2], &i}, {args[4], &f}, {args[5], &b}, {args[1], &s}, {args[7], &a}} {
+		if err = DefaultCodec.Decode(d.data, d.v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if i != 1 || f != 1.5 || !b || s != "a" || a != (asset{ID: "a", Value: 1}) {
+		t.Errorf("decoded: %v %v %v %v %v", i, f, b, s, a)
+	}
+
+	if err = DefaultCodec.Decode([]byte("x"), &i); err == nil {
+		t.Error("decode x to int")
+	}
+}
+
+func TestJSONAndProtoCodec(t *testing.T) {
+	data, err := JSONCodec.Encode("a")
+	if err != nil || string(data) != `"a"` {
+		t.Errorf("json string: %s, %v", data, err)
+	}
+
+	if _, err = ProtoCodec.Encode("a"); err == nil {
+		t.Error("proto encode string")
+	}
+
+	transient, err := EncodeTransient(ProtoCodec, map[string]interface{}{"id": &peer.ChaincodeID{Name: "basic"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := proto.Marshal(&peer.ChaincodeID{Name: "basic"})
+	if !reflect.DeepEqual(transient["id"], want) {
+		t.Errorf("transient: %v", transient)
+	}
+}
+
+func TestTypedCall(t *testing.T) {
+	l, pc, oc := newFakeLedger(t, 0)
+	close(l.release)
+	mspOpt := newTestMSPOpt(t)
+
+	var result asset
+	_, err := InternalInvokeTyped(ChainOpt{Name: "basic"}, mspOpt,
+		TypedCall{Function: "set", Args: []interface{}{asset{ID: "a", Value: 1}}, Result: &result},
+		"mychannel", []*client.PeerClient{pc}, []*client.OrdererClient{oc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != (asset{ID: "a", Value: 1}) {
+		t.Errorf("invoke result: %v", result)
+	}
+
+	var n int
+	if _, err = InternalQueryTyped(ChainOpt{Name: "basic"}, mspOpt,
+		TypedCall{Function: "get", Args: []interface{}{42}, Result: &n},
+		"mychannel", []*client.PeerClient{pc}); err != nil {
+		t.Fatal(err)
+	}
+	if n != 42 {
+		t.Errorf("query result: %d", n)
+	}
+
+	resp, err := InternalQueryTyped(ChainOpt{Name: "basic"}, mspOpt,
+		TypedCall{Function: "fail", Result: &n}, "mychannel", []*client.PeerClient{pc})
+	if err == nil {
+		t.Errorf("failed query: %v", resp)
+	}
+}
@@ -12,30 +12,23 @@ import (
 	"github.com/Asutorufa/fabricsdk/client"
 	"github.com/golang/protobuf/proto"
 	mb "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric-protos-go/common"
 	"github.com/hyperledger/fabric-protos-go/peer"
 	"github.com/hyperledger/fabric/bccsp"
-	"github.com/hyperledger/fabric/bccsp/factory"
 	"github.com/hyperledger/fabric/common/policydsl"
 	"github.com/hyperledger/fabric/msp"
+	"github.com/hyperledger/fabric/protoutil"
 )
 
+//GetSigner get a x509 signer from msp path
 func GetSigner(mspPath, mspID string) (msp.SigningIdentity, error) {
-	amsp, err := msp.New(msp.Options["bccsp"], factory.GetDefault())
-	if err != nil {
-		return nil, fmt.Errorf("create new msp failed: %v", err)
-	}
-
-	mspConfig, err := msp.GetLocalMspConfig(mspPath, factory.GetDefaultOpts(), mspID)
-	if err != nil {
-		return nil, fmt.Errorf("get local msp config failed: %v", err)
-	}
-
-	err = amsp.Setup(mspConfig)
-	if err != nil {
-		return nil, fmt.Errorf("setup msp failed: %v", err)
-	}
+	return client.GetMSPSigner(mspPath, mspID)
+}
 
-	return amsp.GetDefaultSigningIdentity()
+//GetSignerWithPassword get signer from msp directory which keystore keys are encrypted,
+// encrypted pkcs#8 and legacy "Proc-Type: 4,ENCRYPTED" pem keys are supported
+func GetSignerWithPassword(mspPath, mspID string, password client.PasswordFunc) (msp.SigningIdentity, error) {
+	return client.GetMSPSignerWithPassword(mspPath, mspID, password)
 }
 
 func createSigner(
@@ -104,16 +97,104 @@ func getChaincodeSpec(
 	}
 }
 
-func getChaincodeInvocationSpec(
-	path string,
-	name string,
-	isInit bool,
-	version string,
-	Type peer.ChaincodeSpec_Type,
-	args [][]byte) *peer.ChaincodeInvocationSpec {
+//Lifecycle chaincode lifecycle the invoked chaincode is deployed by
+type Lifecycle int
+
+const (
+	// LifecycleAny send Path, Name and Version as they are set
+	LifecycleAny Lifecycle = iota
+	// LifecycleLegacy 1.x lifecycle (install, instantiate), IsInit is not supported
+	LifecycleLegacy
+	// Lifecycle2 2.x lifecycle (approveformyorg, commit), only Name identifies the chaincode,
+	// Path and Version are ignored by peers and not sent
+	Lifecycle2
+)
+
+//NewChainOpt 2.x style invocation options, only the chaincode name is needed
+func NewChainOpt(name string) ChainOpt {
+	return ChainOpt{Name: name, Lifecycle: Lifecycle2}
+}
+
+// invocationSpec invocation spec of the chaincode, Type UNDEFINED means GOLANG
+func (c ChainOpt) invocationSpec(args [][]byte) (*peer.ChaincodeInvocationSpec, error) {
+	if c.Name == "" {
+		return nil, fmt.Errorf("chaincode name is empty")
+	}
+
+	if _, ok := peer.ChaincodeSpec_Type_name[int32(c.Type)]; !ok {
+		return nil, fmt.Errorf("unknown chaincode type: %d", c.Type)
+	}
+
+	ccType := c.Type
+	if ccType == peer.ChaincodeSpec_UNDEFINED {
+		ccType = peer.ChaincodeSpec_GOLANG
+	}
+
+	path, version := c.Path, c.Version
+	switch c.Lifecycle {
+	case LifecycleAny:
+	case LifecycleLegacy:
+		if c.IsInit {
+			return nil, fmt.Errorf("init invocation is only supported by 2.x lifecycle")
+		}
+	case Lifecycle2:
+		path, version = "", ""
+	default:
+		return nil, fmt.Errorf("unknown chaincode lifecycle: %d", c.Lifecycle)
+	}
+
 	return &peer.ChaincodeInvocationSpec{
-		ChaincodeSpec: getChaincodeSpec(path, name, isInit, version, args, Type),
+		ChaincodeSpec: getChaincodeSpec(path, c.Name, c.IsInit, version, args, ccType),
+	}, nil
+}
+
+//NewSignedProposal create a signed chaincode invocation proposal, return the proposal, signed proposal and txid
+func NewSignedProposal(chaincode ChainOpt, signer msp.SigningIdentity, args [][]byte,
+	privateData map[string][]byte, channelID string) (*peer.Proposal, *peer.SignedProposal, string, error) {
+	return NewSignedProposalWithNonce(chaincode, signer, args, privateData, channelID, nil)
+}
+
+//NewSignedProposalWithNonce NewSignedProposal with the nonce, txid is computed from the nonce and the signer,
+// nil nonce means a random one
+func NewSignedProposalWithNonce(chaincode ChainOpt, signer msp.SigningIdentity, args [][]byte,
+	privateData map[string][]byte, channelID string, nonce []byte) (*peer.Proposal, *peer.SignedProposal, string, error) {
+	invocation, err := chaincode.invocationSpec(args)
+	if err != nil {
+		return nil, nil, "", err
 	}
+
+	creator, err := signer.Serialize()
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("serialize signer failed: %v", err)
+	}
+
+	if nonce == nil {
+		nonce, err = NewNonce()
+		if err != nil {
+			return nil, nil, "", err
+		}
+	}
+
+	txid := protoutil.ComputeTxID(nonce, creator)
+	prop, _, err := protoutil.CreateChaincodeProposalWithTxIDNonceAndTransient(
+		txid,
+		common.HeaderType_ENDORSER_TRANSACTION,
+		channelID,
+		invocation,
+		nonce,
+		creator,
+		privateData,
+	)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("create proposal failed: %v", err)
+	}
+
+	signedProp, err := protoutil.GetSignedProposal(prop, signer)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("sign proposal failed: %v", err)
+	}
+
+	return prop, signedProp, txid, nil
 }
 
 //ChainOpt chaincode about options for functions
@@ -133,6 +214,40 @@ type ChainOpt struct {
 	CollectionsConfig []PrivateDataCollectionConfig
 	// 详见: https://hyperledger-fabric.readthedocs.io/en/release-2.2/private_data_tutorial.html
 	Type peer.ChaincodeSpec_Type
+	// Lifecycle which fields of invocations are used, see NewChainOpt
+	Lifecycle Lifecycle
+	// Wait how to wait for the transaction committed, nil means WaitForAll endorsing peers in DefaultCommitTimeout
+	Wait WaitStrategy
+	// Retry re-endorse and resubmit transactions invalidated by read conflicts, nil means no retry
+	Retry *RetryPolicy
+	// Plan endorse on the peers of the plan until its endorsement policy is satisfied,
+	// instead of on all peers passed to invocations, nil means all peers, see NewEndorsementPlanFromValidationParameter
+	Plan *EndorsementPlan
+	// QueryTimeout timeout of querying peers, 0 means DefaultQueryTimeout
+	QueryTimeout time.Duration
+	// EndorseTimeout timeout of the endorsement of each peer, 0 means DefaultEndorseTimeout
+	EndorseTimeout time.Duration
+}
+
+func (c ChainOpt) endorseTimeout() time.Duration {
+	if c.EndorseTimeout <= 0 {
+		return DefaultEndorseTimeout
+	}
+	return c.EndorseTimeout
+}
+
+func (c ChainOpt) queryTimeout() time.Duration {
+	if c.QueryTimeout <= 0 {
+		return DefaultQueryTimeout
+	}
+	return c.QueryTimeout
+}
+
+func (c ChainOpt) waitStrategy() WaitStrategy {
+	if c.Wait == nil {
+		return WaitForAll(DefaultCommitTimeout)
+	}
+	return c.Wait
 }
 
 //PrivateDataCollectionConfig private data collection config
@@ -217,6 +332,8 @@ type GrpcTLSOptWithPath struct {
 	ClientCrtPath string // for client auth req
 	ClientKeyPath string // for client auth req
 	CaPath        string // tls ca cert
+	// ClientKeyPassword for encrypted client key
+	ClientKeyPassword client.PasswordFunc
 
 	ServerNameOverride string
 	Timeout            time.Duration
@@ -227,6 +344,8 @@ type GrpcTLSOpt struct {
 	ClientCrt []byte
 	ClientKey []byte
 	Ca        []byte
+	// ClientKeyPassword for encrypted ClientKey
+	ClientKeyPassword client.PasswordFunc
 
 	ServerNameOverride string
 	Timeout            time.Duration
@@ -292,15 +411,49 @@ func ParseGrpcTLSOptWithPath(g GrpcTLSOptWithPath) (gg GrpcTLSOpt, err error) {
 			return
 		}
 	}
+	gg.ClientKeyPassword = g.ClientKeyPassword
 	gg.ServerNameOverride = g.ServerNameOverride
 	gg.Timeout = g.Timeout
 	return
 }
 
+//MSP types of MSPOpt
+const (
+	MSPTypeBCCSP  = "bccsp"
+	MSPTypeIdemix = "idemix"
+)
+
 //MSPOpt msp about options
 type MSPOpt struct {
 	Path string
 	ID   string
+	// Type msp type, MSPTypeBCCSP or MSPTypeIdemix, default MSPTypeBCCSP
+	Type string
+	// Idemix only for MSPTypeIdemix
+	Idemix IdemixOpt
+	// KeyPassword only for MSPTypeBCCSP, passphrase of the encrypted keystore keys
+	KeyPassword client.PasswordFunc
+	// Signer if not nil, use it directly, others are ignored, eg: identity from wallet
+	Signer msp.SigningIdentity
+}
+
+//GetSignerByOpt get signer by msp options
+func GetSignerByOpt(mspOpt MSPOpt) (msp.SigningIdentity, error) {
+	if mspOpt.Signer != nil {
+		return mspOpt.Signer, nil
+	}
+
+	switch mspOpt.Type {
+	case "", MSPTypeBCCSP:
+		if mspOpt.KeyPassword != nil {
+			return GetSignerWithPassword(mspOpt.Path, mspOpt.ID, mspOpt.KeyPassword)
+		}
+		return GetSigner(mspOpt.Path, mspOpt.ID)
+	case MSPTypeIdemix:
+		return GetIdemixSigner(mspOpt.Path, mspOpt.ID, mspOpt.Idemix)
+	}
+
+	return nil, fmt.Errorf("unsupported msp type: %s", mspOpt.Type)
 }
 
 // processProposals sends a signed proposal to a set of peers, and gathers all the responses.
@@ -462,6 +615,7 @@ func GetOrdererClients(orderers []Endpoint) []*client.OrdererClient {
 			orderers[oi].Address,
 			orderers[oi].GrpcTLSOpt.ServerNameOverride,
 			client.WithClientCert(orderers[oi].GrpcTLSOpt.ClientKey, orderers[oi].GrpcTLSOpt.ClientCrt),
+			client.WithClientKeyPasswordFunc(orderers[oi].GrpcTLSOpt.ClientKeyPassword),
 			client.WithTLS(orderers[oi].GrpcTLSOpt.Ca),
 			client.WithTimeout(orderers[oi].GrpcTLSOpt.Timeout),
 		)
@@ -485,6 +639,7 @@ func GetPeerClients(peers []Endpoint) []*client.PeerClient {
 			peers[pi].Address,
 			peers[pi].GrpcTLSOpt.ServerNameOverride,
 			client.WithClientCert(peers[pi].GrpcTLSOpt.ClientKey, peers[pi].GrpcTLSOpt.ClientCrt),
+			client.WithClientKeyPasswordFunc(peers[pi].GrpcTLSOpt.ClientKeyPassword),
 			client.WithTLS(peers[pi].GrpcTLSOpt.Ca),
 			client.WithTimeout(peers[pi].GrpcTLSOpt.Timeout),
 		)
@@ -0,0 +1,151 @@
+package chaincode
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/Asutorufa/fabricsdk/internal/testutil"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+func TestGetSignerByOpt(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteMSP(t, dir, testutil.NewIdentity(t, "User1@org1.example.com"), nil)
+
+	signer, err := GetSignerByOpt(MSPOpt{Path: dir, ID: "Org1MSP"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer.GetMSPIdentifier() != "Org1MSP" {
+		t.Errorf("msp id: %s", signer.GetMSPIdentifier())
+	}
+
+	sig, err := signer.Sign([]byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = signer.Verify([]byte("hello"), sig); err != nil {
+		t.Error(err)
+	}
+
+	// a group loads the same identity from the msp directory
+	group := client.NewGroup()
+	if err = group.AddSigner("Org1MSP", dir); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := signer.Serialize()
+	if got, err := (*group.GetSigner("Org1MSP")).Serialize(); err != nil || !bytes.Equal(got, want) {
+		t.Errorf("group signer: %x, %v", got, err)
+	}
+
+	// the signer is used as it is
+	if s, err := GetSignerByOpt(MSPOpt{Signer: signer}); err != nil || s != signer {
+		t.Errorf("signer option: %v, %v", s, err)
+	}
+
+	// a second msp directory has its own keystore
+	other := t.TempDir()
+	testutil.WriteMSP(t, other, testutil.NewIdentity(t, "User1@org1.example.com"), nil)
+	if _, err = GetSignerByOpt(MSPOpt{Path: other, ID: "Org2MSP"}); err != nil {
+		t.Error(err)
+	}
+
+	// msp directories are loaded as msp 1.4.3, without node ous they declare their admins
+	if err = os.RemoveAll(filepath.Join(other, "admincerts")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = GetSignerByOpt(MSPOpt{Path: other, ID: "Org2MSP"}); err == nil {
+		t.Error("get signer of msp directory without admins")
+	}
+
+	if _, err = GetSignerByOpt(MSPOpt{Path: filepath.Join(dir, "not-exist"), ID: "Org1MSP"}); err == nil {
+		t.Error("get signer of not exist msp directory")
+	}
+}
+
+func TestGetSignerWithPassword(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteMSP(t, dir, testutil.NewIdentity(t, "User1@org1.example.com"), []byte("passw0rd"))
+
+	// not keys in the keystore are skipped
+	for name, data := range map[string][]byte{"README": []byte("keys"), "cert.pem": []byte("-----BEGIN CERTIFICATE-----\n-----END CERTIFICATE-----\n")} {
+		if err := ioutil.WriteFile(filepath.Join(dir, "keystore", name), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := GetSignerByOpt(MSPOpt{Path: dir, ID: "Org1MSP"}); err == nil {
+		t.Error("get signer of encrypted keystore without password")
+	}
+
+	if _, err := GetSignerByOpt(MSPOpt{Path: dir, ID: "Org1MSP", KeyPassword: client.Password([]byte("wrong"))}); err == nil {
+		t.Error("get signer with wrong password")
+	}
+
+	signer, err := GetSignerByOpt(MSPOpt{Path: dir, ID: "Org1MSP", KeyPassword: client.Password([]byte("passw0rd"))})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sig, err := signer.Sign([]byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = signer.Verify([]byte("hello"), sig); err != nil {
+		t.Error(err)
+	}
+
+	group := client.NewGroup()
+	if err = group.AddSigner("Org1MSP", dir); err == nil {
+		t.Error("group add signer of encrypted keystore without password")
+	}
+	if err = group.AddSignerWithPassword("Org1MSP", dir, client.Password([]byte("passw0rd"))); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := signer.Serialize()
+	if got, err := (*group.GetSigner("Org1MSP")).Serialize(); err != nil || !bytes.Equal(got, want) {
+		t.Errorf("group signer: %x, %v", got, err)
+	}
+}
+
+func TestInvocationSpec(t *testing.T) {
+	args := [][]byte{[]byte("get"), []byte("a")}
+
+	spec, err := ChainOpt{Name: "basic", Path: "basic", Version: "1.0"}.invocationSpec(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.ChaincodeSpec.Type != peer.ChaincodeSpec_GOLANG || spec.ChaincodeSpec.ChaincodeId.Version != "1.0" {
+		t.Errorf("default spec: %v", spec)
+	}
+
+	spec, err = ChainOpt{Name: "basic", Type: peer.ChaincodeSpec_NODE, Version: "1.0", IsInit: true,
+		Lifecycle: Lifecycle2}.invocationSpec(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.ChaincodeSpec.Type != peer.ChaincodeSpec_NODE || spec.ChaincodeSpec.ChaincodeId.Version != "" ||
+		!spec.ChaincodeSpec.Input.IsInit {
+		t.Errorf("2.x spec: %v", spec)
+	}
+
+	spec, err = NewChainOpt("basic").invocationSpec(args)
+	if err != nil || spec.ChaincodeSpec.ChaincodeId.Name != "basic" {
+		t.Errorf("NewChainOpt spec: %v, %v", spec, err)
+	}
+
+	for _, opt := range []ChainOpt{
+		{},
+		{Name: "basic", Type: 100},
+		{Name: "basic", IsInit: true, Lifecycle: LifecycleLegacy},
+		{Name: "basic", Lifecycle: 100},
+	} {
+		if _, err = opt.invocationSpec(args); err == nil {
+			t.Errorf("invalid options: %+v", opt)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package chaincode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+//Stage stage of a transaction a error happened in
+type Stage string
+
+const (
+	// StageEndorse sending proposals to peers
+	StageEndorse Stage = "endorse"
+	// StageBroadcast sending the transaction to orderers
+	StageBroadcast Stage = "broadcast"
+	// StageCommit waiting for the transaction committed
+	StageCommit Stage = "commit"
+)
+
+//ErrUnknownTransaction no peer returned the transaction on its ledger, it may be not committed yet
+var ErrUnknownTransaction = errors.New("unknown transaction")
+
+//PeerFailure failure of a peer, Err is set if the peer can't be called,
+// otherwise Status and Message are of the failed response
+type PeerFailure struct {
+	Address string
+	Status  int32
+	Message string
+	Err     error
+}
+
+func (f PeerFailure) String() string {
+	var s string
+	if f.Err != nil {
+		s = f.Err.Error()
+	} else {
+		s = fmt.Sprintf("%d - %s", f.Status, f.Message)
+	}
+
+	if f.Address == "" {
+		return s
+	}
+	return fmt.Sprintf("peer [%s]: %s", f.Address, s)
+}
+
+//EndorseError endorsement failed, Failures are the failed peers
+type EndorseError struct {
+	TxID     string
+	Failures []PeerFailure
+}
+
+func (e *EndorseError) Error() string {
+	var failures []string
+	for _, f := range e.Failures {
+		failures = append(failures, f.String())
+	}
+
+	if len(failures) == 0 {
+		return fmt.Sprintf("endorsement of transaction [%s] failed: no proposal response", e.TxID)
+	}
+	return fmt.Sprintf("endorsement of transaction [%s] failed: %s", e.TxID, strings.Join(failures, "; "))
+}
+
+// endorseError error of failed peers, a TimeoutError of StageEndorse if all of them timed out
+func endorseError(txid string, failures []PeerFailure) error {
+	timedOut := len(failures) > 0
+	for _, f := range failures {
+		if f.Err == nil || !(errors.Is(f.Err, context.DeadlineExceeded) || status.Code(f.Err) == codes.DeadlineExceeded) {
+			timedOut = false
+			break
+		}
+	}
+
+	if timedOut {
+		log.Printf("endorsement of transaction [%s] timed out: %v", txid, &EndorseError{TxID: txid, Failures: failures})
+		return &TimeoutError{TxID: txid, Stage: StageEndorse}
+	}
+	return &EndorseError{TxID: txid, Failures: failures}
+}
+
+//OrdererFailure failure of a orderer, Err is set if the orderer can't be called,
+// otherwise Status and Info are of the rejecting response
+type OrdererFailure struct {
+	Address string
+	Status  common.Status
+	Info    string
+	Err     error
+}
+
+func (f OrdererFailure) String() string {
+	if f.Err != nil {
+		return fmt.Sprintf("orderer [%s]: %v", f.Address, f.Err)
+	}
+	return fmt.Sprintf("orderer [%s]: %s - %s", f.Address, f.Status, f.Info)
+}
+
+//BroadcastError no orderer accepted the transaction
+type BroadcastError struct {
+	TxID     string
+	Failures []OrdererFailure
+}
+
+func (e *BroadcastError) Error() string {
+	var failures []string
+	for _, f := range e.Failures {
+		failures = append(failures, f.String())
+	}
+
+	if len(failures) == 0 {
+		return fmt.Sprintf("broadcast transaction [%s] failed: no orderer", e.TxID)
+	}
+	return fmt.Sprintf("broadcast transaction [%s] failed: %s", e.TxID, strings.Join(failures, "; "))
+}
+
+//Status the status of the last rejecting orderer, common.Status_UNKNOWN if no orderer responded
+func (e *BroadcastError) Status() common.Status {
+	for i := len(e.Failures) - 1; i >= 0; i-- {
+		if e.Failures[i].Err == nil {
+			return e.Failures[i].Status
+		}
+	}
+	return common.Status_UNKNOWN
+}
+
+//ValidationError the transaction is committed but invalidated
+type ValidationError struct {
+	TxID        string
+	Code        peer.TxValidationCode
+	BlockNumber uint64
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("transaction [%s] invalidated with status (%s)", e.TxID, e.Code)
+}
+
+//TimeoutError timed out in Stage
+type TimeoutError struct {
+	TxID  string
+	Stage Stage
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timed out waiting for %s of transaction [%s]", e.Stage, e.TxID)
+}
+
+//Timeout for net.Error like checks
+func (e *TimeoutError) Timeout() bool { return true }
+
+//IsConflict err is a ValidationError of MVCC_READ_CONFLICT or PHANTOM_READ_CONFLICT
+func IsConflict(err error) bool {
+	var v *ValidationError
+	return errors.As(err, &v) && isConflict(v.Code)
+}
+
+func isConflict(code peer.TxValidationCode) bool {
+	return code == peer.TxValidationCode_MVCC_READ_CONFLICT || code == peer.TxValidationCode_PHANTOM_READ_CONFLICT
+}
+
+// statusError the validation error of a unsuccessful status, nil if successful
+func statusError(status *CommitStatus) error {
+	if status == nil || status.Successful() {
+		return nil
+	}
+	return &ValidationError{TxID: status.TxID, Code: status.Code, BlockNumber: status.BlockNumber}
+}
+
+//DivergingPeer a peer whose response differs from the agreed one in a quorum query
+type DivergingPeer struct {
+	Address string
+	Status  int32
+	Message string
+	Payload []byte
+}
+
+func (d DivergingPeer) String() string {
+	if d.Status != int32(common.Status_SUCCESS) {
+		return fmt.Sprintf("peer [%s]: %d - %s", d.Address, d.Status, d.Message)
+	}
+	return fmt.Sprintf("peer [%s]: diverging payload (%d bytes)", d.Address, len(d.Payload))
+}
+
+//QuorumError no payload is returned identically by Required peers,
+// or more than one payload is, Agreed are the peers of the largest group of identical payloads
+type QuorumError struct {
+	TxID      string
+	Required  int
+	Agreed    []string
+	Diverging []DivergingPeer
+	// Failures peers can't be called
+	Failures []PeerFailure
+}
+
+func (e *QuorumError) Error() string {
+	var details []string
+	for _, d := range e.Diverging {
+		details = append(details, d.String())
+	}
+	for _, f := range e.Failures {
+		details = append(details, f.String())
+	}
+
+	reason := fmt.Sprintf("%d peers required to agree, %d agreed %v", e.Required, len(e.Agreed), e.Agreed)
+	if len(e.Agreed) >= e.Required {
+		reason = fmt.Sprintf("diverging payloads are returned by %d peers or more", e.Required)
+	}
+	return fmt.Sprintf("quorum query [%s] failed: %s: %s", e.TxID, reason, strings.Join(details, "; "))
+}
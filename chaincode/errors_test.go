@@ -0,0 +1,135 @@
+package chaincode
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/Asutorufa/fabricsdk/internal/testutil"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/orderer"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"google.golang.org/grpc"
+)
+
+// rejectingOrderer reject every envelope with status
+type rejectingOrderer struct{ status common.Status }
+
+func (o *rejectingOrderer) Broadcast(stream orderer.AtomicBroadcast_BroadcastServer) error {
+	for {
+		if _, err := stream.Recv(); err != nil {
+			return nil
+		}
+		if err := stream.Send(&orderer.BroadcastResponse{Status: o.status, Info: "rejected"}); err != nil {
+			return err
+		}
+	}
+}
+
+func (o *rejectingOrderer) Deliver(orderer.AtomicBroadcast_DeliverServer) error { return nil }
+
+func newRejectingOrderer(t *testing.T, status common.Status) *client.OrdererClient {
+	s := grpc.NewServer()
+	orderer.RegisterAtomicBroadcastServer(s, &rejectingOrderer{status})
+	return testutil.NewOrdererClient(t, testutil.Serve(t, s))
+}
+
+func TestEndorseError(t *testing.T) {
+	l, pc, oc := newFakeLedger(t, peer.TxValidationCode_VALID)
+	close(l.release)
+	mspOpt := newTestMSPOpt(t)
+
+	_, err := InternalInvokeAsync(ChainOpt{Name: "basic"}, mspOpt, [][]byte{[]byte("fail")},
+		nil, "mychannel", []*client.PeerClient{pc}, []*client.OrdererClient{oc})
+	var e *EndorseError
+	if !errors.As(err, &e) {
+		t.Fatalf("err: %v", err)
+	}
+	if e.TxID == "" || len(e.Failures) != 1 || e.Failures[0].Status != 500 ||
+		e.Failures[0].Message != "chaincode failed" || e.Failures[0].Address != pc.Address() {
+		t.Errorf("endorse error: %+v", e)
+	}
+
+	// InternalInvoke returns the failed response with the EndorseError
+	resp, err := InternalInvoke(ChainOpt{Name: "basic"}, mspOpt, [][]byte{[]byte("fail")},
+		nil, "mychannel", []*client.PeerClient{pc}, []*client.OrdererClient{oc})
+	if !errors.As(err, &e) || e.Failures[0].Status != 500 || resp == nil || resp.Response.Status != 500 {
+		t.Errorf("resp: %v, err: %v", resp, err)
+	}
+
+	tx, resp, err := PrepareTransaction(ChainOpt{Name: "basic"}, mspOpt, [][]byte{[]byte("fail")},
+		nil, "mychannel", nil, []*client.PeerClient{pc})
+	if !errors.As(err, &e) || tx != nil || resp == nil || resp.Response.Status != 500 {
+		t.Errorf("tx: %v, resp: %v, err: %v", tx, resp, err)
+	}
+}
+
+func TestBroadcastError(t *testing.T) {
+	l, pc, _ := newFakeLedger(t, peer.TxValidationCode_VALID)
+	close(l.release)
+	oc := newRejectingOrderer(t, common.Status_SERVICE_UNAVAILABLE)
+
+	_, err := InternalInvoke(ChainOpt{Name: "basic"}, newTestMSPOpt(t), [][]byte{[]byte("set"), []byte("a")},
+		nil, "mychannel", []*client.PeerClient{pc}, []*client.OrdererClient{oc})
+	var e *BroadcastError
+	if !errors.As(err, &e) {
+		t.Fatalf("err: %v", err)
+	}
+	if e.TxID == "" || e.Status() != common.Status_SERVICE_UNAVAILABLE || e.Failures[0].Info != "rejected" {
+		t.Errorf("broadcast error: %+v", e)
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	l, pc, oc := newFakeLedger(t, peer.TxValidationCode_MVCC_READ_CONFLICT)
+	close(l.release)
+
+	_, err := InternalInvoke(ChainOpt{Name: "basic"}, newTestMSPOpt(t), [][]byte{[]byte("set"), []byte("a")},
+		nil, "mychannel", []*client.PeerClient{pc}, []*client.OrdererClient{oc})
+	var e *ValidationError
+	if !errors.As(err, &e) {
+		t.Fatalf("err: %v", err)
+	}
+	if e.TxID == "" || e.Code != peer.TxValidationCode_MVCC_READ_CONFLICT || !IsConflict(err) {
+		t.Errorf("validation error: %+v", e)
+	}
+}
+
+func TestTimeoutError(t *testing.T) {
+	pc := newCommitPeer(t, &fakeCommitPeer{})
+	w, err := NewCommitWaiter(WaitForPeers(50*time.Millisecond, pc), testutil.NewSigner(t, "Org1MSP", "User1@org1.example.com"),
+		"mychannel", "tx1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = w.Wait()
+	var e *TimeoutError
+	if !errors.As(err, &e) {
+		t.Fatalf("err: %v", err)
+	}
+	if e.TxID != "tx1" || e.Stage != StageCommit || !e.Timeout() {
+		t.Errorf("timeout error: %+v", e)
+	}
+}
+
+func TestEndorseTimeoutError(t *testing.T) {
+	l, pc, oc := newFakeLedger(t, peer.TxValidationCode_VALID)
+	close(l.release)
+	l.endorseDelay = time.Minute
+
+	start := time.Now()
+	_, err := InternalInvoke(ChainOpt{Name: "basic", EndorseTimeout: 100 * time.Millisecond}, newTestMSPOpt(t),
+		[][]byte{[]byte("set"), []byte("a")}, nil, "mychannel", []*client.PeerClient{pc}, []*client.OrdererClient{oc})
+	var e *TimeoutError
+	if !errors.As(err, &e) {
+		t.Fatalf("err: %v", err)
+	}
+	if e.TxID == "" || e.Stage != StageEndorse {
+		t.Errorf("timeout error: %+v", e)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("timeout is not used, returned after %v", elapsed)
+	}
+}
@@ -0,0 +1,308 @@
+package chaincode
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/orderer"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/protoutil"
+)
+
+//ChaincodeEvent a event set by a transaction in block BlockNumber,
+// events of invalidated transactions are delivered too, check Code
+type ChaincodeEvent struct {
+	ChaincodeID string
+	EventName   string
+	Payload     []byte
+	TxID        string
+	BlockNumber uint64
+	Code        peer.TxValidationCode
+}
+
+//ChaincodeEventsFromBlock chaincode events of the endorser transactions in block
+func ChaincodeEventsFromBlock(block *common.Block) ([]*ChaincodeEvent, error) {
+	if block.Header == nil || block.Data == nil {
+		return nil, fmt.Errorf("block has no header or data")
+	}
+
+	var codes []byte
+	if len(block.Metadata.GetMetadata()) > int(common.BlockMetadataIndex_TRANSACTIONS_FILTER) {
+		codes = block.Metadata.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER]
+	}
+
+	var events []*ChaincodeEvent
+	for i, data := range block.Data.Data {
+		env, err := protoutil.GetEnvelopeFromBlock(data)
+		if err != nil {
+			return nil, fmt.Errorf("get envelope %d of block %d failed: %v", i, block.Header.Number, err)
+		}
+
+		payload, err := protoutil.UnmarshalPayload(env.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshal payload %d of block %d failed: %v", i, block.Header.Number, err)
+		}
+
+		chdr, err := protoutil.UnmarshalChannelHeader(payload.GetHeader().GetChannelHeader())
+		if err != nil {
+			return nil, fmt.Errorf("unmarshal channel header %d of block %d failed: %v", i, block.Header.Number, err)
+		}
+		if common.HeaderType(chdr.Type) != common.HeaderType_ENDORSER_TRANSACTION {
+			continue
+		}
+
+		code := peer.TxValidationCode_NOT_VALIDATED
+		if i < len(codes) {
+			code = peer.TxValidationCode(codes[i])
+		}
+
+		tx, err := protoutil.UnmarshalTransaction(payload.Data)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshal transaction [%s] failed: %v", chdr.TxId, err)
+		}
+
+		for _, action := range tx.Actions {
+			_, cca, err := protoutil.GetPayloads(action)
+			if err != nil {
+				return nil, fmt.Errorf("get chaincode action of transaction [%s] failed: %v", chdr.TxId, err)
+			}
+			if len(cca.Events) == 0 {
+				continue
+			}
+
+			event, err := protoutil.UnmarshalChaincodeEvents(cca.Events)
+			if err != nil {
+				return nil, fmt.Errorf("unmarshal chaincode event of transaction [%s] failed: %v", chdr.TxId, err)
+			}
+			if event.EventName == "" {
+				continue
+			}
+
+			events = append(events, &ChaincodeEvent{
+				ChaincodeID: event.ChaincodeId,
+				EventName:   event.EventName,
+				Payload:     event.Payload,
+				TxID:        chdr.TxId,
+				BlockNumber: block.Header.Number,
+				Code:        code,
+			})
+		}
+	}
+
+	return events, nil
+}
+
+//EventSubscription chaincode events of a channel, built on a BlockListener of full blocks
+type EventSubscription struct {
+	chaincodeID string
+	pattern     *regexp.Regexp
+	start       *orderer.SeekPosition
+	buffer      int
+	// checkpointer save every event checkpointed by the consumer, resumed the checkpoint loaded when subscribing
+	checkpointer Checkpointer
+	resumed      *Checkpoint
+	// acks events checkpointed by Checkpoint, received by the handler waiting for the last delivered event
+	acks chan eventAck
+	// checkpointTimeout the subscription ends if the last delivered event is not checkpointed in it
+	checkpointTimeout time.Duration
+
+	// ctx the handler stops sending once it is done, the listener runs on it too
+	ctx      context.Context
+	cancel   context.CancelFunc
+	listener *BlockListener
+	events   chan *ChaincodeEvent
+}
+
+//FromBlock deliver events from block number, default is from the newest block
+func FromBlock(number uint64) func(*EventSubscription) {
+	return func(s *EventSubscription) {
+		s.start = SeekBlock(number)
+	}
+}
+
+//WithEventBuffer buffer size of the events channel, default is 0
+func WithEventBuffer(n int) func(*EventSubscription) {
+	return func(s *EventSubscription) {
+		s.buffer = n
+	}
+}
+
+//DefaultCheckpointTimeout time the consumer has to checkpoint a event by default, see WithCheckpointTimeout
+const DefaultCheckpointTimeout = time.Minute
+
+//WithCheckpointer resume from the checkpoint of cp instead of FromBlock if one is saved,
+// events of processed transactions are not delivered again,
+// the consumer calls EventSubscription.Checkpoint once a event is processed, the next event is not delivered until then,
+// a event received but not checkpointed is delivered again after resubscribing, the events channel is not buffered
+func WithCheckpointer(cp Checkpointer) func(*EventSubscription) {
+	return func(s *EventSubscription) {
+		s.checkpointer = cp
+	}
+}
+
+//WithCheckpointTimeout time the consumer has to checkpoint a event of WithCheckpointer, default is DefaultCheckpointTimeout,
+// otherwise the subscription ends, Err reports the event not checkpointed
+func WithCheckpointTimeout(timeout time.Duration) func(*EventSubscription) {
+	return func(s *EventSubscription) {
+		s.checkpointTimeout = timeout
+	}
+}
+
+//SubscribeChaincodeEvents subscribe to events of chaincodeID whose names match eventPattern,
+// eventPattern is a regular expression matching the whole name, empty means all events,
+// the subscription lasts until ctx is done or Close, then the events channel is closed,
+// once a peer fails, the next one is connected from the block after the last received one
+func SubscribeChaincodeEvents(ctx context.Context, mspOpt MSPOpt, channelID, chaincodeID, eventPattern string,
+	peers []*client.PeerClient, opts ...func(*EventSubscription)) (*EventSubscription, error) {
+	s := &EventSubscription{chaincodeID: chaincodeID}
+
+	var err error
+	if eventPattern != "" {
+		if s.pattern, err = regexp.Compile("^(?:" + eventPattern + ")$"); err != nil {
+			return nil, fmt.Errorf("compile event pattern failed: %v", err)
+		}
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	listenOpts := []func(*BlockListener){ListenFrom(s.start)}
+	if s.checkpointer != nil {
+		if s.resumed, err = s.checkpointer.Load(); err != nil {
+			return nil, fmt.Errorf("load checkpoint failed: %v", err)
+		}
+		s.buffer = 0
+		s.acks = make(chan eventAck)
+		if s.checkpointTimeout <= 0 {
+			s.checkpointTimeout = DefaultCheckpointTimeout
+		}
+		listenOpts = append(listenOpts, ListenWithCheckpointer(s.checkpointer))
+	}
+
+	s.events = make(chan *ChaincodeEvent, s.buffer)
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	s.listener, err = ListenBlocks(s.ctx, mspOpt, channelID, peers, s.handle, listenOpts...)
+	if err != nil {
+		s.cancel()
+		return nil, err
+	}
+
+	go func() {
+		<-s.listener.Done()
+		s.cancel()
+		close(s.events)
+	}()
+	return s, nil
+}
+
+//Events matched events in block order, closed once the subscription ends
+func (s *EventSubscription) Events() <-chan *ChaincodeEvent { return s.events }
+
+//Err why the subscription ended, valid after Events is closed
+func (s *EventSubscription) Err() error { return s.listener.Err() }
+
+//Close end the subscription
+func (s *EventSubscription) Close() { s.cancel() }
+
+// eventAck a event checkpointed by the consumer, done receives the result of saving
+type eventAck struct {
+	event *ChaincodeEvent
+	done  chan error
+}
+
+//Checkpoint save e as processed by the checkpointer of WithCheckpointer,
+// e must be the last event received from Events, the next event is delivered after it is saved
+func (s *EventSubscription) Checkpoint(e *ChaincodeEvent) error {
+	if s.acks == nil {
+		return fmt.Errorf("subscription has no checkpointer")
+	}
+	if e == nil {
+		return fmt.Errorf("event is nil")
+	}
+
+	ack := eventAck{event: e, done: make(chan error, 1)}
+	select {
+	case s.acks <- ack:
+	case <-s.ctx.Done():
+		return fmt.Errorf("subscription is closed")
+	}
+	return <-ack.done
+}
+
+func (s *EventSubscription) handle(b *BlockEvent) error {
+	events, err := ChaincodeEventsFromBlock(b.Block)
+	if err != nil {
+		return err
+	}
+
+	// skip the resumed checkpoint of the block, checkpoint starts from it
+	var skip *Checkpoint
+	checkpoint := &Checkpoint{BlockNumber: b.Number}
+	if s.resumed != nil && s.resumed.BlockNumber == b.Number {
+		skip, checkpoint = s.resumed, s.resumed.copy()
+	}
+
+	for _, e := range events {
+		if !s.match(e) || (skip != nil && skip.Processed(e.TxID)) {
+			continue
+		}
+
+		select {
+		case s.events <- e:
+		case <-s.ctx.Done():
+			return s.ctx.Err()
+		}
+
+		if s.checkpointer == nil {
+			continue
+		}
+		if err = s.waitCheckpoint(e, checkpoint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitCheckpoint wait for the consumer checkpointing e in checkpointTimeout, then save it in checkpoint
+func (s *EventSubscription) waitCheckpoint(e *ChaincodeEvent, checkpoint *Checkpoint) error {
+	timer := time.NewTimer(s.checkpointTimeout)
+	defer timer.Stop()
+
+	for {
+		var ack eventAck
+		select {
+		case ack = <-s.acks:
+		case <-timer.C:
+			return fmt.Errorf("event of transaction [%s] is not checkpointed in %v", e.TxID, s.checkpointTimeout)
+		case <-s.ctx.Done():
+			return s.ctx.Err()
+		}
+
+		if ack.event != e {
+			ack.done <- fmt.Errorf("event of transaction [%s] is not the last received one", ack.event.TxID)
+			continue
+		}
+
+		if !checkpoint.Processed(e.TxID) {
+			checkpoint.TxIDs = append(checkpoint.TxIDs, e.TxID)
+		}
+		if err := s.checkpointer.Save(checkpoint.copy()); err != nil {
+			err = fmt.Errorf("save checkpoint of transaction [%s] failed: %v", e.TxID, err)
+			ack.done <- err
+			return err
+		}
+		ack.done <- nil
+		return nil
+	}
+}
+
+func (s *EventSubscription) match(e *ChaincodeEvent) bool {
+	if s.chaincodeID != "" && e.ChaincodeID != s.chaincodeID {
+		return false
+	}
+	return s.pattern == nil || s.pattern.MatchString(e.EventName)
+}
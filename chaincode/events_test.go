@@ -0,0 +1,106 @@
+package chaincode
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+func nextEvent(t *testing.T, s *EventSubscription) *ChaincodeEvent {
+	t.Helper()
+	select {
+	case e, ok := <-s.Events():
+		if !ok {
+			t.Fatalf("subscription ended: %v", s.Err())
+		}
+		return e
+	case <-time.After(3 * time.Second):
+		t.Fatal("no event received")
+	}
+	return nil
+}
+
+func TestSubscribeChaincodeEvents(t *testing.T) {
+	l, pc, oc := newFakeLedger(t, peer.TxValidationCode_VALID)
+	close(l.release)
+	mspOpt := newTestMSPOpt(t)
+	down, stop := l.serveDeliver(t)
+
+	put := func(chaincode, key string) string {
+		commit, err := InternalInvokeAsync(ChainOpt{Name: chaincode}, mspOpt, [][]byte{[]byte("put"), []byte(key), []byte(key)},
+			nil, "mychannel", []*client.PeerClient{pc}, []*client.OrdererClient{oc})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = commit.Status(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return commit.TxID()
+	}
+
+	// committed before subscribing
+	put("basic", "old")
+
+	s, err := SubscribeChaincodeEvents(context.Background(), mspOpt, "mychannel", "basic", "pu.*",
+		[]*client.PeerClient{down, pc}, FromBlock(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	put("other", "a")
+	txID := put("basic", "b")
+
+	e := nextEvent(t, s)
+	if e.ChaincodeID != "basic" || e.EventName != "put" || string(e.Payload) != "b" ||
+		e.TxID != txID || e.BlockNumber != 2 || e.Code != peer.TxValidationCode_VALID {
+		t.Errorf("event: %+v", e)
+	}
+
+	// interrupted, reconnected to the next peer from block 3
+	stop()
+	txID = put("basic", "c")
+	if e = nextEvent(t, s); e.TxID != txID || e.BlockNumber != 3 {
+		t.Errorf("event after reconnected: %+v", e)
+	}
+
+	s.Close()
+	for range s.Events() {
+		t.Error("event after closed")
+	}
+	if s.Err() != context.Canceled {
+		t.Errorf("err: %v", s.Err())
+	}
+}
+
+func TestEventSubscriptionMatch(t *testing.T) {
+	s, err := SubscribeChaincodeEvents(context.Background(), newTestMSPOpt(t), "mychannel", "basic", "created|deleted",
+		[]*client.PeerClient{newCommitPeer(t, &fakeCommitPeer{})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	tests := []struct {
+		chaincode, name string
+		match           bool
+	}{
+		{"basic", "created", true},
+		{"basic", "deleted", true},
+		{"basic", "created2", false},
+		{"other", "created", false},
+	}
+	for _, tt := range tests {
+		if m := s.match(&ChaincodeEvent{ChaincodeID: tt.chaincode, EventName: tt.name}); m != tt.match {
+			t.Errorf("match %s/%s: %v, want %v", tt.chaincode, tt.name, m, tt.match)
+		}
+	}
+
+	if _, err = SubscribeChaincodeEvents(context.Background(), newTestMSPOpt(t), "mychannel", "basic", "(",
+		[]*client.PeerClient{newCommitPeer(t, &fakeCommitPeer{})}); err == nil {
+		t.Error("invalid pattern is accepted")
+	}
+}
@@ -0,0 +1,413 @@
+package chaincode
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/Asutorufa/fabricsdk/internal/testutil"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/ledger/rwset"
+	"github.com/hyperledger/fabric-protos-go/ledger/rwset/kvrwset"
+	"github.com/hyperledger/fabric-protos-go/orderer"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/msp"
+	"github.com/hyperledger/fabric/protoutil"
+	"google.golang.org/grpc"
+)
+
+// fakeLedger a peer and a orderer in one server, every transaction is committed in its own block
+type fakeLedger struct {
+	endorser msp.SigningIdentity
+	code     peer.TxValidationCode
+	// codes validation codes of the next commits, code is used once it is empty
+	codes []peer.TxValidationCode
+	// release blocks are not delivered until release is closed
+	release chan struct{}
+	// payload replaces the echoed argument of successful responses, a stale or malicious peer
+	payload []byte
+	// acceptDelay delay of the orderer before accepting a transaction, a slow orderer
+	acceptDelay time.Duration
+	// endorseDelay delay of the peer before responding a proposal, a slow or hanging peer
+	endorseDelay time.Duration
+
+	mutex  sync.Mutex
+	blocks []*peer.FilteredBlock
+	// envs transaction of every block, nil for blocks committed by commit
+	envs   []*common.Envelope
+	notify chan struct{}
+	// txs validation codes of committed transactions
+	txs map[string]peer.TxValidationCode
+	// private private data of endorsed transactions
+	private map[string]*rwset.TxPvtReadWriteSet
+	// broadcasts number of broadcast streams
+	broadcasts int32
+	// proposals number of proposals endorsed, queries of system chaincodes excluded
+	proposals int32
+}
+
+func newFakeLedger(t *testing.T, code peer.TxValidationCode) (*fakeLedger, *client.PeerClient, *client.OrdererClient) {
+	l := &fakeLedger{
+		endorser: testutil.NewSigner(t, "Org1MSP", "peer0.org1.example.com"),
+		code:     code,
+		release:  make(chan struct{}),
+		notify:   make(chan struct{}),
+		txs:      map[string]peer.TxValidationCode{},
+		private:  map[string]*rwset.TxPvtReadWriteSet{},
+	}
+
+	s := grpc.NewServer()
+	peer.RegisterEndorserServer(s, l)
+	peer.RegisterDeliverServer(s, l)
+	orderer.RegisterAtomicBroadcastServer(s, &fakeLedgerOrderer{l})
+	address := testutil.Serve(t, s)
+
+	return l, testutil.NewPeerClient(t, address), testutil.NewOrdererClient(t, address)
+}
+
+func (l *fakeLedger) ProcessProposal(ctx context.Context, sp *peer.SignedProposal) (*peer.ProposalResponse, error) {
+	select {
+	case <-time.After(l.endorseDelay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	inv, err := testutil.UnmarshalInvocation(sp)
+	if err != nil {
+		return nil, err
+	}
+
+	// echo the last argument
+	args := inv.Spec.Input.Args
+	response := &peer.Response{Status: 200, Payload: args[len(args)-1]}
+	if l.payload != nil {
+		response.Payload = l.payload
+	}
+	if string(args[0]) == "fail" {
+		response = &peer.Response{Status: 500, Message: "chaincode failed"}
+	}
+	if inv.Spec.ChaincodeId.Name == "qscc" {
+		response = l.getBlockByTxID(string(args[2]))
+	} else {
+		atomic.AddInt32(&l.proposals, 1)
+	}
+
+	var results, events []byte
+	if string(args[0]) == "put" {
+		var pvt *rwset.TxPvtReadWriteSet
+		if results, events, pvt, err = simulatedPut(inv.Spec.ChaincodeId.Name, args); err != nil {
+			return nil, err
+		}
+
+		l.mutex.Lock()
+		l.private[inv.ChannelHeader.TxId] = pvt
+		l.mutex.Unlock()
+	}
+
+	resp, err := protoutil.CreateProposalResponse(inv.Proposal.Header, inv.Proposal.Payload, response, results, events,
+		inv.Spec.ChaincodeId, l.endorser)
+	if err != nil {
+		return nil, err
+	}
+	resp.Response = response
+	return resp, nil
+}
+
+// simulatedPut rwset, event and private data of put(key, value):
+// read then write the key, write the key to collection "private"
+func simulatedPut(namespace string, args [][]byte) ([]byte, []byte, *rwset.TxPvtReadWriteSet, error) {
+	key, value := string(args[1]), args[2]
+	kv, err := proto.Marshal(&kvrwset.KVRWSet{
+		Reads:  []*kvrwset.KVRead{{Key: key, Version: &kvrwset.Version{BlockNum: 1}}},
+		Writes: []*kvrwset.KVWrite{{Key: key, Value: value}},
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	pvtKV, err := proto.Marshal(&kvrwset.KVRWSet{Writes: []*kvrwset.KVWrite{{Key: key, Value: value}}})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	pvt := &rwset.TxPvtReadWriteSet{DataModel: rwset.TxReadWriteSet_KV, NsPvtRwset: []*rwset.NsPvtReadWriteSet{{
+		Namespace:          namespace,
+		CollectionPvtRwset: []*rwset.CollectionPvtReadWriteSet{{CollectionName: "private", Rwset: pvtKV}},
+	}}}
+
+	hashed, err := proto.Marshal(&kvrwset.HashedRWSet{HashedWrites: []*kvrwset.KVWriteHash{{KeyHash: []byte(key)}}})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	results, err := proto.Marshal(&rwset.TxReadWriteSet{DataModel: rwset.TxReadWriteSet_KV, NsRwset: []*rwset.NsReadWriteSet{{
+		Namespace:             namespace,
+		Rwset:                 kv,
+		CollectionHashedRwset: []*rwset.CollectionHashedReadWriteSet{{CollectionName: "private", HashedRwset: hashed}},
+	}}})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	events, err := proto.Marshal(&peer.ChaincodeEvent{ChaincodeId: namespace, EventName: "put", Payload: value})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return results, events, pvt, nil
+}
+
+// getBlockByTxID qscc GetBlockByTxID, the block of the first commit of txID
+func (l *fakeLedger) getBlockByTxID(txID string) *peer.Response {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for i, fb := range l.blocks {
+		if fb.FilteredTransactions[0].Txid != txID {
+			continue
+		}
+
+		block := l.block(i)
+		if l.envs[i] == nil {
+			// transactions committed by commit have no envelope, the block has one of the txid only
+			block.Data.Data = [][]byte{protoutil.MarshalOrPanic(&common.Envelope{Payload: protoutil.MarshalOrPanic(&common.Payload{
+				Header: &common.Header{ChannelHeader: protoutil.MarshalOrPanic(&common.ChannelHeader{
+					Type: int32(common.HeaderType_ENDORSER_TRANSACTION), ChannelId: "mychannel", TxId: txID})},
+			})})}
+		}
+		return &peer.Response{Status: 200, Payload: protoutil.MarshalOrPanic(block)}
+	}
+
+	return &peer.Response{Status: 500, Message: fmt.Sprintf("Failed to get block for txID %s, error entry not found in index", txID)}
+}
+
+func (l *fakeLedger) height() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return len(l.blocks)
+}
+
+func (l *fakeLedger) commit(txID string) {
+	l.commitEnvelope(txID, nil)
+}
+
+func (l *fakeLedger) commitEnvelope(txID string, env *common.Envelope) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	code := l.code
+	if len(l.codes) > 0 {
+		code, l.codes = l.codes[0], l.codes[1:]
+	}
+	if _, ok := l.txs[txID]; ok {
+		code = peer.TxValidationCode_DUPLICATE_TXID
+	} else {
+		l.txs[txID] = code
+	}
+
+	l.envs = append(l.envs, env)
+	l.blocks = append(l.blocks, &peer.FilteredBlock{
+		Number:               uint64(len(l.blocks)),
+		FilteredTransactions: []*peer.FilteredTransaction{{Txid: txID, TxValidationCode: code}},
+	})
+	close(l.notify)
+	l.notify = make(chan struct{})
+}
+
+// fakeSeek seek info of a deliver request, start and stop -1 mean the newest block
+type fakeSeek struct {
+	start, stop    int
+	failIfNotReady bool
+}
+
+func parseSeek(env *common.Envelope) (*fakeSeek, error) {
+	payload, err := protoutil.UnmarshalPayload(env.Payload)
+	if err != nil {
+		return nil, err
+	}
+	seekInfo := &orderer.SeekInfo{}
+	if err = proto.Unmarshal(payload.Data, seekInfo); err != nil {
+		return nil, err
+	}
+
+	seek := &fakeSeek{start: -1, stop: math.MaxInt32, failIfNotReady: seekInfo.Behavior == orderer.SeekInfo_FAIL_IF_NOT_READY}
+	if specified := seekInfo.Start.GetSpecified(); specified != nil {
+		seek.start = int(specified.Number)
+	}
+	if seekInfo.Start.GetOldest() != nil {
+		seek.start = 0
+	}
+	if specified := seekInfo.Stop.GetSpecified(); specified != nil && specified.Number < math.MaxInt32 {
+		seek.stop = int(specified.Number)
+	}
+	if seekInfo.Stop.GetNewest() != nil {
+		seek.stop = -1
+	}
+	return seek, nil
+}
+
+type fakeDeliverStream interface {
+	Context() context.Context
+	Recv() (*common.Envelope, error)
+	Send(*peer.DeliverResponse) error
+}
+
+// Deliver deliver full blocks
+func (l *fakeLedger) Deliver(stream peer.Deliver_DeliverServer) error {
+	return l.deliver(stream, func(i int) *peer.DeliverResponse {
+		return &peer.DeliverResponse{Type: &peer.DeliverResponse_Block{Block: l.block(i)}}
+	})
+}
+
+// DeliverWithPrivateData deliver full blocks with the private data of put
+func (l *fakeLedger) DeliverWithPrivateData(stream peer.Deliver_DeliverWithPrivateDataServer) error {
+	return l.deliver(stream, func(i int) *peer.DeliverResponse {
+		b := &peer.BlockAndPrivateData{Block: l.block(i)}
+		if pvt := l.private[l.blocks[i].FilteredTransactions[0].Txid]; pvt != nil && l.envs[i] != nil {
+			b.PrivateDataMap = map[uint64]*rwset.TxPvtReadWriteSet{0: pvt}
+		}
+		return &peer.DeliverResponse{Type: &peer.DeliverResponse_BlockAndPrivateData{BlockAndPrivateData: b}}
+	})
+}
+
+// DeliverFiltered deliver filtered blocks
+func (l *fakeLedger) DeliverFiltered(stream peer.Deliver_DeliverFilteredServer) error {
+	return l.deliver(stream, func(i int) *peer.DeliverResponse {
+		return &peer.DeliverResponse{Type: &peer.DeliverResponse_FilteredBlock{FilteredBlock: l.blocks[i]}}
+	})
+}
+
+// block full block i, l.mutex is held
+func (l *fakeLedger) block(i int) *common.Block {
+	fb := l.blocks[i]
+	block := protoutil.NewBlock(fb.Number, nil)
+	if env := l.envs[i]; env != nil {
+		block.Data.Data = [][]byte{protoutil.MarshalOrPanic(env)}
+	}
+	block.Metadata.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER] = []byte{byte(fb.FilteredTransactions[0].TxValidationCode)}
+
+	// index of the last config block, as orderers write it
+	for c := i; c >= 0; c-- {
+		if chdr, err := protoutil.ChannelHeader(l.envs[c]); err == nil && chdr.Type == int32(common.HeaderType_CONFIG) {
+			block.Metadata.Metadata[common.BlockMetadataIndex_SIGNATURES] = protoutil.MarshalOrPanic(&common.Metadata{
+				Value: protoutil.MarshalOrPanic(&common.OrdererBlockMetadata{LastConfig: &common.LastConfig{Index: uint64(c)}}),
+			})
+			break
+		}
+	}
+	return block
+}
+
+// commitConfig commit a config block of config
+func (l *fakeLedger) commitConfig(t *testing.T, config *common.Config) {
+	env, err := protoutil.CreateSignedEnvelope(common.HeaderType_CONFIG, "mychannel", nil,
+		&common.ConfigEnvelope{Config: config}, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.commitEnvelope(fmt.Sprintf("config%d", l.height()), env)
+}
+
+// deliver blocks by the seek info, blocks are held until released unless failing if not ready,
+// response is called with l.mutex held
+func (l *fakeLedger) deliver(stream fakeDeliverStream, response func(i int) *peer.DeliverResponse) error {
+	env, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	seek, err := parseSeek(env)
+	if err != nil {
+		return err
+	}
+
+	l.mutex.Lock()
+	next := seek.start
+	if next < 0 {
+		next = len(l.blocks) - 1
+	}
+	if next < 0 {
+		next = 0
+	}
+	if seek.stop < 0 {
+		seek.stop = next
+	}
+	l.mutex.Unlock()
+
+	if !seek.failIfNotReady {
+		select {
+		case <-l.release:
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+
+	status := func(s common.Status) error {
+		return stream.Send(&peer.DeliverResponse{Type: &peer.DeliverResponse_Status{Status: s}})
+	}
+
+	for {
+		l.mutex.Lock()
+		for ; next < len(l.blocks) && next <= seek.stop; next++ {
+			if err := stream.Send(response(next)); err != nil {
+				l.mutex.Unlock()
+				return err
+			}
+		}
+		notify := l.notify
+		l.mutex.Unlock()
+
+		if next > seek.stop {
+			return status(common.Status_SUCCESS)
+		}
+		if seek.failIfNotReady {
+			return status(common.Status_NOT_FOUND)
+		}
+
+		select {
+		case <-notify:
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// serveDeliver another peer of the ledger serving deliver only, stop it to interrupt its streams
+func (l *fakeLedger) serveDeliver(t *testing.T) (*client.PeerClient, func()) {
+	s := grpc.NewServer()
+	peer.RegisterDeliverServer(s, l)
+	return testutil.NewPeerClient(t, testutil.Serve(t, s)), s.Stop
+}
+
+type fakeLedgerOrderer struct{ l *fakeLedger }
+
+func (o *fakeLedgerOrderer) Broadcast(stream orderer.AtomicBroadcast_BroadcastServer) error {
+	atomic.AddInt32(&o.l.broadcasts, 1)
+	for {
+		env, err := stream.Recv()
+		if err != nil {
+			return nil
+		}
+
+		chdr, err := protoutil.ChannelHeader(env)
+		if err != nil {
+			return err
+		}
+		time.Sleep(o.l.acceptDelay)
+		o.l.commitEnvelope(chdr.TxId, env)
+
+		if err = stream.Send(&orderer.BroadcastResponse{Status: common.Status_SUCCESS}); err != nil {
+			return err
+		}
+	}
+}
+
+func (o *fakeLedgerOrderer) Deliver(orderer.AtomicBroadcast_DeliverServer) error { return nil }
+
+func newTestMSPOpt(t *testing.T) MSPOpt {
+	return MSPOpt{ID: "Org1MSP", Signer: testutil.NewSigner(t, "Org1MSP", "User1@org1.example.com")}
+}
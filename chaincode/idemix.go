@@ -0,0 +1,141 @@
+package chaincode
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/golang/protobuf/proto"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric/bccsp/factory"
+	"github.com/hyperledger/fabric/msp"
+)
+
+// idemix msp directory layout, same as fabric-ca-client and idemixgen
+const (
+	idemixConfigDirMsp                  = "msp"
+	idemixConfigDirUser                 = "user"
+	idemixConfigFileIssuerPublicKey     = "IssuerPublicKey"
+	idemixConfigFileRevocationPublicKey = "RevocationPublicKey"
+	idemixConfigFileSigner              = "SignerConfig"
+)
+
+// idemixRoleAdmin idemix admin role mask, from github.com/IBM/idemix/idemix_roles.go
+const idemixRoleAdmin int32 = 2
+
+//IdemixOpt idemix msp about options
+//
+// fabric's idemix msp always disclose the OU and the role of the credential,
+// the enrollment id and the revocation handle are always hidden,
+// so Role and OU here are used to check the chosen credential discloses what we expect
+type IdemixOpt struct {
+	// SignerConfigPath signer config of the credential to use,
+	// a user may be enrolled many times, every credential has its own revocation handle,
+	// default: <msp path>/user/SignerConfig
+	SignerConfigPath string
+	// CRIPath serialized credential revocation information,
+	// replace the one in signer config, eg: after the revocation epoch changed
+	CRIPath string
+	// Role expected disclosed role, "" means do not check, others: member, admin
+	Role string
+	// OU expected disclosed organizational unit, "" means do not check
+	OU string
+}
+
+//GetIdemixSigner get a idemix signer from msp path
+// mspPath must contain msp/IssuerPublicKey, msp/RevocationPublicKey and user/SignerConfig
+func GetIdemixSigner(mspPath, mspID string, opt IdemixOpt) (msp.SigningIdentity, error) {
+	mspConfig, err := getIdemixMspConfig(mspPath, mspID, opt)
+	if err != nil {
+		return nil, fmt.Errorf("get idemix msp config failed: %v", err)
+	}
+
+	amsp, err := msp.New(msp.Options[msp.ProviderTypeToString(msp.IDEMIX)], factory.GetDefault())
+	if err != nil {
+		return nil, fmt.Errorf("create new idemix msp failed: %v", err)
+	}
+
+	err = amsp.Setup(mspConfig)
+	if err != nil {
+		return nil, fmt.Errorf("setup idemix msp failed: %v", err)
+	}
+
+	return amsp.GetDefaultSigningIdentity()
+}
+
+func getIdemixMspConfig(mspPath, mspID string, opt IdemixOpt) (*mb.MSPConfig, error) {
+	ipk, err := ioutil.ReadFile(filepath.Join(mspPath, idemixConfigDirMsp, idemixConfigFileIssuerPublicKey))
+	if err != nil {
+		return nil, fmt.Errorf("read issuer public key failed: %v", err)
+	}
+
+	revocationPk, err := ioutil.ReadFile(filepath.Join(mspPath, idemixConfigDirMsp, idemixConfigFileRevocationPublicKey))
+	if err != nil {
+		return nil, fmt.Errorf("read revocation public key failed: %v", err)
+	}
+
+	signerConfigPath := opt.SignerConfigPath
+	if signerConfigPath == "" {
+		signerConfigPath = filepath.Join(mspPath, idemixConfigDirUser, idemixConfigFileSigner)
+	}
+
+	signerBytes, err := ioutil.ReadFile(signerConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("read signer config failed: %v", err)
+	}
+
+	signer := &mb.IdemixMSPSignerConfig{}
+	err = proto.Unmarshal(signerBytes, signer)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal signer config failed: %v", err)
+	}
+
+	if opt.CRIPath != "" {
+		signer.CredentialRevocationInformation, err = ioutil.ReadFile(opt.CRIPath)
+		if err != nil {
+			return nil, fmt.Errorf("read credential revocation information failed: %v", err)
+		}
+	}
+
+	if err = checkIdemixDisclosure(signer, opt); err != nil {
+		return nil, err
+	}
+
+	data, err := proto.Marshal(&mb.IdemixMSPConfig{
+		Name:         mspID,
+		Ipk:          ipk,
+		RevocationPk: revocationPk,
+		Signer:       signer,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal idemix msp config failed: %v", err)
+	}
+
+	return &mb.MSPConfig{Type: int32(msp.IDEMIX), Config: data}, nil
+}
+
+func checkIdemixDisclosure(signer *mb.IdemixMSPSignerConfig, opt IdemixOpt) error {
+	if opt.OU != "" && opt.OU != signer.OrganizationalUnitIdentifier {
+		return fmt.Errorf("credential discloses OU [%s], not [%s]", signer.OrganizationalUnitIdentifier, opt.OU)
+	}
+
+	if opt.Role == "" {
+		return nil
+	}
+
+	if opt.Role != "member" && opt.Role != "admin" {
+		return fmt.Errorf("unknown idemix role: %s", opt.Role)
+	}
+
+	// the msp discloses admin if the credential has the admin role, otherwise member
+	role := "member"
+	if signer.Role&idemixRoleAdmin == idemixRoleAdmin {
+		role = "admin"
+	}
+
+	if role != opt.Role {
+		return fmt.Errorf("credential discloses role [%s], not [%s]", role, opt.Role)
+	}
+
+	return nil
+}
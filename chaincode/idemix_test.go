@@ -0,0 +1,57 @@
+package chaincode
+
+import (
+	"testing"
+)
+
+func TestGetIdemixSigner(t *testing.T) {
+	signer, err := GetSignerByOpt(MSPOpt{
+		Path:   "testdata/idemix/MSP1OU1",
+		ID:     "MSP1OU1",
+		Type:   MSPTypeIdemix,
+		Idemix: IdemixOpt{Role: "member", OU: "OU1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sig, err := signer.Sign([]byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = signer.GetPublicVersion().Verify([]byte("hello"), sig)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if signer.GetMSPIdentifier() != "MSP1OU1" {
+		t.Errorf("msp id: %s", signer.GetMSPIdentifier())
+	}
+}
+
+func TestGetIdemixSignerDisclosure(t *testing.T) {
+	_, err := GetIdemixSigner("testdata/idemix/MSP1OU1Admin", "MSP1OU1Admin", IdemixOpt{Role: "admin"})
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, err = GetIdemixSigner("testdata/idemix/MSP1OU1Admin", "MSP1OU1Admin", IdemixOpt{Role: "member"})
+	if err == nil {
+		t.Error("admin credential disclosed as member")
+	}
+
+	_, err = GetIdemixSigner("testdata/idemix/MSP1OU1", "MSP1OU1", IdemixOpt{OU: "OU2"})
+	if err == nil {
+		t.Error("OU1 credential disclosed as OU2")
+	}
+
+	// choose another credential of the same issuer
+	_, err = GetIdemixSigner("testdata/idemix/MSP1OU1", "MSP1OU1", IdemixOpt{
+		SignerConfigPath: "testdata/idemix/MSP1OU1Admin/user/SignerConfig",
+		Role:             "admin",
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
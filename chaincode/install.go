@@ -24,11 +24,11 @@ import (
 //cTor eg: '{"Args":["init","a","100","b","200"]}'
 // isPackage whether chainOpt.Path is a .tar.gz package or not
 func Install(chainOpt ChainOpt, mspOpt MSPOpt, peers Endpoint, cTor string, isPackage bool) (proposalResponse *peer.ProposalResponse, err error) {
-	peerClient, err := client.NewPeerClient(peers.Address, peers.ServerNameOverride, client.WithTLS(peers.Ca), client.WithClientCert(peers.ClientKey, peers.ClientCrt))
+	peerClient, err := client.NewPeerClient(peers.Address, peers.ServerNameOverride, client.WithTLS(peers.Ca), client.WithClientCert(peers.ClientKey, peers.ClientCrt), client.WithClientKeyPasswordFunc(peers.ClientKeyPassword))
 	if err != nil {
 		return nil, fmt.Errorf("create new peer[%s] client failed: %v", peers.Address, err)
 	}
-	signer, err := GetSigner(mspOpt.Path, mspOpt.ID)
+	signer, err := GetSignerByOpt(mspOpt)
 	if err != nil {
 		return nil, fmt.Errorf("get signer from msp [id:%s,path:%s] failed: %v", mspOpt.ID, mspOpt.Path, err)
 	}
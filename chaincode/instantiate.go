@@ -3,7 +3,6 @@ package chaincode
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"log"
 
@@ -28,22 +27,23 @@ func Instantiate(channelID string, cTor string,
 		return nil
 	}
 
-	for oi := range ordererClients {
-		broadcast, err := ordererClients[oi].Broadcast()
-		if err != nil {
-			log.Printf("get broadcast failed: %v\n", err)
-			continue
-		}
+	chdr, err := protoutil.ChannelHeader(env)
+	if err != nil {
+		return fmt.Errorf("get channel header failed: %v", err)
+	}
 
-		err = broadcast.Send(env)
-		if err != nil {
-			log.Printf("broadcast send envelop failed: %v", err)
-			continue
-		}
+	orderers := make([]*client.OrdererClient, len(ordererClients))
+	for oi := range ordererClients {
+		orderers[oi] = &ordererClients[oi]
+	}
 
-		return nil
+	// not wait by default
+	wait := chainOpt.Wait
+	if wait == nil {
+		wait = NoWait()
 	}
-	return errors.New("broadcast transaction failed")
+
+	return BroadcastAndWait(env, signer, wait, []*client.PeerClient{&peerClient}, channelID, chdr.TxId, orderers)
 }
 
 func getSingedTx(channelID string, cTor string,
@@ -52,13 +52,13 @@ func Invoke2(
 // args [][]byte{[]byte("function"),[]byte("a"),[]byte("b")}, first array is function name
 // privateData not necessary, like: map[string][]byte{"cert":[]byte("transient")}, more: https://hyperledger-fabric.readthedocs.io/zh_CN/latest/private_data_tutorial.html
 // channelID necessary channel name
-// peerAddress necessary peer address array
+// peerAddress necessary peer address array, not necessary if chaincode.Plan is set
 // ordererAddress necessary orderer address
 func Invoke(chaincode ChainOpt, mspOpt MSPOpt, args [][]byte,
 	privateData map[string][]byte, channelID string, //txID string,
 	peers []Endpoint, orderers []Endpoint) (*peer.ProposalResponse, error) {
 	peerClients := GetPeerClients(peers)
-	if len(peerClients) == 0 {
+	if len(peerClients) == 0 && chaincode.Plan == nil {
 		return nil, fmt.Errorf("peer clients' number is 0")
 	}
 	defer CloseClients(peerClients)
@@ -72,132 +72,217 @@ func Invoke(chaincode ChainOpt, mspOpt MSPOpt, args [][]byte,
 	return InternalInvoke(chaincode, mspOpt, args, privateData, channelID, peerClients, ordererClients)
 }
 
-//InternalInvoke invoke
+//InternalInvoke invoke, retried by chaincode.Retry if the transaction is invalidated by read conflicts,
+// if the endorsement fails, the failed response is returned with a EndorseError
 func InternalInvoke(chaincode ChainOpt, mspOpt MSPOpt, args [][]byte,
 	privateData map[string][]byte, channelID string,
 	peers []*client.PeerClient, orderers []*client.OrdererClient,
 ) (*peer.ProposalResponse, error) {
+	resp, _, err := InternalInvokeWithAttempts(chaincode, mspOpt, args, privateData, channelID, peers, orderers)
+	return resp, err
+}
 
-	invocation := getChaincodeInvocationSpec(
-		chaincode.Path,
-		chaincode.Name,
-		chaincode.IsInit,
-		chaincode.Version,
-		peer.ChaincodeSpec_GOLANG,
-		args,
-	)
-	signer, err := GetSigner(mspOpt.Path, mspOpt.ID)
-	if err != nil {
-		return nil, err
-	}
-	creator, err := signer.Serialize()
-	if err != nil {
-		return nil, err
+//InternalInvokeWithPlan InternalInvoke with chaincode.Plan set to plan,
+// plan can be created from discovery or the committed chaincode definition, see NewEndorsementPlanFromValidationParameter
+func InternalInvokeWithPlan(chaincode ChainOpt, mspOpt MSPOpt, args [][]byte,
+	privateData map[string][]byte, channelID string,
+	plan *EndorsementPlan, orderers []*client.OrdererClient,
+) (*peer.ProposalResponse, error) {
+	if plan == nil {
+		return nil, fmt.Errorf("endorsement plan is nil")
 	}
 
-	//tMap := map[string][]byte{
-	//	"cert": []byte("transient"),
-	//}
+	chaincode.Plan = plan
+	return InternalInvoke(chaincode, mspOpt, args, privateData, channelID, nil, orderers)
+}
 
-	prop, txid, err := protoutil.CreateChaincodeProposalWithTxIDAndTransient(
-		common.HeaderType_ENDORSER_TRANSACTION,
-		channelID,
-		invocation,
-		creator,
-		"",
-		privateData, // transientMap <- 因为链码提案被存储在区块链上，
-		// 不要把私有数据包含在链码提案中也是非常重要的。
-		//在链码提案中有一个特殊的字段 transient，
-		//可以用它把私有数据来从客户端（或者链码将用来生成私有数据的数据）传递给节点上的链码调用。
-		//链码可以通过调用 GetTransient() API 来获取 transient 字段。
-		//这个 transient 字段会从通道交易中被排除
-	)
-	if err != nil {
-		return nil, err
-	}
-	fmt.Printf("txid: %s\n", txid)
+// endorsedTx a transaction endorsed, ready to be sent to orderers
+type endorsedTx struct {
+	signer msp.SigningIdentity
+	env    *common.Envelope
+	txID   string
+	// response the first proposal response
+	response *peer.ProposalResponse
+	// peers endorsing peers
+	peers []*client.PeerClient
+}
 
-	signedProp, err := protoutil.GetSignedProposal(prop, signer)
+//DefaultEndorseTimeout timeout of the endorsement of a peer by default
+const DefaultEndorseTimeout = 30 * time.Second
+
+// endorseOnPeers send the proposal to all peers, or the peers of chaincode.Plan, each in chaincode.EndorseTimeout,
+// tx is nil if no peer responses, or any response fails (returned with a EndorseError), or responses are invalid,
+// a TimeoutError of StageEndorse is returned if every failed peer timed out
+// nonce nil means a random one
+func endorseOnPeers(chaincode ChainOpt, signer msp.SigningIdentity, args [][]byte,
+	privateData map[string][]byte, channelID string, nonce []byte, peers []*client.PeerClient,
+) (*endorsedTx, *peer.ProposalResponse, error) {
+	prop, signedProp, txid, err := NewSignedProposalWithNonce(chaincode, signer, args, privateData, channelID, nonce)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	var deliverClients []peer.DeliverClient
-	var certificate tls.Certificate
-	var proposalResponse []*peer.ProposalResponse
-	for pi := range peers {
+	if chaincode.Plan != nil {
+		endorsements, failed, err := chaincode.Plan.endorse(signedProp, txid, chaincode.endorseTimeout())
+		if err != nil {
+			return nil, failed, err
+		}
 
-		certificate = peers[pi].Certificate()
+		tx, err := newEndorsedTx(signer, prop, txid, endorsements)
+		if err != nil {
+			return nil, endorsements[0].response, err
+		}
+		return tx, tx.response, nil
+	}
 
+	var endorsements []endorsement
+	var failures []PeerFailure
+	for pi := range peers {
 		endorserClient, err := peers[pi].Endorser()
 		if err != nil {
 			log.Printf("get endorser from peer client failed: %v", err)
+			failures = append(failures, PeerFailure{Address: peers[pi].Address(), Err: err})
 			continue
 		}
 
-		resp, err := endorserClient.ProcessProposal(context.Background(), signedProp)
+		resp, err := endorseWithTimeout(endorserClient, signedProp, chaincode.endorseTimeout())
 		if err != nil {
 			log.Printf("process proposal failed: %v", err)
+			failures = append(failures, PeerFailure{Address: peers[pi].Address(), Err: err})
 			continue
 		}
 
-		proposalResponse = append(proposalResponse, resp)
+		endorsements = append(endorsements, endorsement{peer: peers[pi], response: resp})
+	}
 
-		deliverClient, err := peers[pi].PeerDeliver()
-		if err != nil {
-			return nil, err
+	if len(endorsements) == 0 {
+		return nil, nil, endorseError(txid, failures)
+	}
+
+	var failed *peer.ProposalResponse
+	failures = nil
+	for _, e := range endorsements {
+		if e.response.Response == nil {
+			return nil, e.response, fmt.Errorf("received proposal response with nil response")
+		}
+		if e.response.Response.Status >= shim.ERRORTHRESHOLD {
+			if failed == nil {
+				failed = e.response
+			}
+			failures = append(failures, PeerFailure{
+				Address: e.peer.Address(), Status: e.response.Response.Status, Message: e.response.Response.Message})
 		}
-		deliverClients = append(deliverClients, deliverClient)
+	}
+	if failed != nil {
+		return nil, failed, &EndorseError{TxID: txid, Failures: failures}
 	}
 
-	if len(proposalResponse) == 0 {
-		return nil, nil
+	tx, err := newEndorsedTx(signer, prop, txid, endorsements)
+	if err != nil {
+		return nil, endorsements[0].response, err
+	}
+	return tx, tx.response, nil
+}
+
+func endorseWithTimeout(endorser peer.EndorserClient, signedProp *peer.SignedProposal,
+	timeout time.Duration) (*peer.ProposalResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return endorser.ProcessProposal(ctx, signedProp)
+}
+
+// newEndorsedTx validate endorsements, create the signed transaction
+func newEndorsedTx(signer msp.SigningIdentity, prop *peer.Proposal, txid string, endorsements []endorsement) (*endorsedTx, error) {
+	var proposalResponse []*peer.ProposalResponse
+	var endorsingPeers []*client.PeerClient
+	var endorsers []string
+	for _, e := range endorsements {
+		proposalResponse = append(proposalResponse, e.response)
+		endorsingPeers = append(endorsingPeers, e.peer)
+		endorsers = append(endorsers, e.peer.Address())
 	}
-	resp := proposalResponse[0]
 
-	if resp.Response.Status >= shim.ERRORTHRESHOLD {
-		return resp, nil
+	if err := ValidateProposalResponses(proposalResponse, endorsers); err != nil {
+		return nil, err
 	}
 
 	env, err := protoutil.CreateSignedTx(prop, signer, proposalResponse...)
 	if err != nil {
-		return resp, err
+		return nil, err
 	}
 
+	return &endorsedTx{
+		signer:   signer,
+		env:      env,
+		txID:     txid,
+		response: proposalResponse[0],
+		peers:    endorsingPeers,
+	}, nil
+}
+
+//BroadcastAndWait send the transaction to orderers one by one until one of them accepts it,
+// then wait for the commit by strategy
+func BroadcastAndWait(env *common.Envelope, signer msp.SigningIdentity, strategy WaitStrategy,
+	endorsers []*client.PeerClient, channelID, txid string, orderers []*client.OrdererClient) error {
+	// connect before sending, the block may be delivered before connected
+	waiter, err := NewCommitWaiter(strategy, signer, channelID, txid, endorsers)
+	if err != nil {
+		return err
+	}
+
+	if err = Broadcast(env, orderers); err != nil {
+		waiter.Close()
+		return err
+	}
+
+	return waiter.Wait()
+}
+
+//Broadcast send the envelope to orderers one by one until one of them accepts it,
+// a BroadcastError is returned if no orderer accepted it
+func Broadcast(env *common.Envelope, orderers []*client.OrdererClient) error {
+	var failures []OrdererFailure
 	for oi := range orderers {
 		broadcast, err := orderers[oi].Broadcast()
 		if err != nil {
 			log.Printf("get broadcast from orderer client failed: %v\n", err)
+			failures = append(failures, OrdererFailure{Address: orderers[oi].Address(), Err: err})
 			continue
 		}
 
 		err = broadcast.Send(env)
 		if err != nil {
 			log.Printf("orderer send proposal failed: %v", err)
+			failures = append(failures, OrdererFailure{Address: orderers[oi].Address(), Err: err})
 			continue
 		}
 
-		dg := NewDeliverGroup(deliverClients, signer, certificate, channelID, txid)
-		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
-		err = dg.Connect(ctx)
+		resp, err := broadcast.Recv()
+		_ = broadcast.CloseSend()
 		if err != nil {
-			log.Printf("create connect failed: %v", err)
-			goto _end
+			log.Printf("receive broadcast response failed: %v", err)
+			failures = append(failures, OrdererFailure{Address: orderers[oi].Address(), Err: err})
+			continue
 		}
 
-		err = dg.Wait(ctx)
-		if err != nil {
-			log.Printf("dg.Wait() -> %v\n", err)
-			goto _end
+		if resp.Status != common.Status_SUCCESS {
+			log.Printf("orderer [%s] rejected the transaction: %s - %s", orderers[oi].Address(), resp.Status, resp.Info)
+			failures = append(failures, OrdererFailure{Address: orderers[oi].Address(), Status: resp.Status, Info: resp.Info})
+			continue
 		}
-		log.Println("deliver get block, wait successful")
-		cancel()
-		return resp, nil
 
-	_end:
-		cancel()
+		return nil
+	}
+	return &BroadcastError{TxID: envelopeTxID(env), Failures: failures}
+}
+
+// envelopeTxID txid in the channel header of env, empty if it can't be read
+func envelopeTxID(env *common.Envelope) string {
+	chdr, err := protoutil.ChannelHeader(env)
+	if err != nil {
+		return ""
 	}
-	return nil, fmt.Errorf("broadcast proposal failed")
+	return chdr.TxId
 }
 
 // DeliverGroup holds all of the information needed to connect
@@ -355,7 +440,7 @@ func (dg *DeliverGroup) ClientWait(dc *DeliverClient) {
 				if tx.Txid == dg.TxID {
 					//logger.Infof("txid [%s] committed with status (%s) at %s", dg.TxID, tx.TxValidationCode, dc.Address)
 					if tx.TxValidationCode != peer.TxValidationCode_VALID {
-						err = fmt.Errorf("transaction invalidated with status (%s)", tx.TxValidationCode)
+						err = &ValidationError{TxID: tx.Txid, Code: tx.TxValidationCode, BlockNumber: r.FilteredBlock.Number}
 						dg.setError(err)
 					}
 					return
@@ -392,16 +477,22 @@ func createDeliverEnvelope(
 	certificate tls.Certificate,
 	signer msp.SigningIdentity,
 ) *common.Envelope {
-	var tlsCertHash []byte
-	// check for client certificate and create hash if present
-	if len(certificate.Certificate) > 0 {
-		tlsCertHash = util.ComputeSHA256(certificate.Certificate[0])
-	}
+	return createSeekEnvelope(channelID, certificate, signer, nil)
+}
 
-	start := &orderer.SeekPosition{
-		Type: &orderer.SeekPosition_Newest{
-			Newest: &orderer.SeekNewest{},
-		},
+// createSeekEnvelope deliver from start to the end of the ledger, nil start means the newest block
+func createSeekEnvelope(
+	channelID string,
+	certificate tls.Certificate,
+	signer msp.SigningIdentity,
+	start *orderer.SeekPosition,
+) *common.Envelope {
+	if start == nil {
+		start = &orderer.SeekPosition{
+			Type: &orderer.SeekPosition_Newest{
+				Newest: &orderer.SeekNewest{},
+			},
+		}
 	}
 
 	stop := &orderer.SeekPosition{
@@ -418,6 +509,22 @@ func createDeliverEnvelope(
 		Behavior: orderer.SeekInfo_BLOCK_UNTIL_READY,
 	}
 
+	return createSeekInfoEnvelope(channelID, certificate, signer, seekInfo)
+}
+
+// createSeekInfoEnvelope deliver blocks by seekInfo
+func createSeekInfoEnvelope(
+	channelID string,
+	certificate tls.Certificate,
+	signer msp.SigningIdentity,
+	seekInfo *orderer.SeekInfo,
+) *common.Envelope {
+	var tlsCertHash []byte
+	// check for client certificate and create hash if present
+	if len(certificate.Certificate) > 0 {
+		tlsCertHash = util.ComputeSHA256(certificate.Certificate[0])
+	}
+
 	env, err := protoutil.CreateSignedEnvelopeWithTLSBinding(
 		common.HeaderType_DELIVER_SEEK_INFO,
 		channelID,
@@ -30,7 +30,7 @@ func ApproveForMyOrg(chainOpt chaincode.ChainOpt, mspOpt chaincode.MSPOpt, chann
 
 func InternalApproveForMyOrg(chainOpt chaincode.ChainOpt, mspOpt chaincode.MSPOpt, channelID string,
 	peers []*client.PeerClient, orderers []*client.OrdererClient) (*peer.ProposalResponse, error) {
-	signer, err := chaincode.GetSigner(mspOpt.Path, mspOpt.ID)
+	signer, err := chaincode.GetSignerByOpt(mspOpt)
 	if err != nil {
 		return nil, fmt.Errorf("get signer [mspPath:%s, mspID:%s] error -> %v", mspOpt.Path, mspOpt.ID, err)
 	}
@@ -74,7 +74,7 @@ func InternalApproveForMyOrg(chainOpt chaincode.ChainOpt, mspOpt chaincode.MSPOp
 		return nil, fmt.Errorf("crate proposal error -> %v", err)
 	}
 
-	return internalInvoke(signer, proposal, peers, orderers, channelID, txID)
+	return internalInvoke(signer, proposal, peers, orderers, channelID, txID, chainOpt.Wait)
 }
 
 // ApproveForMyOrg2 to ApproveForMyOrg
@@ -101,7 +101,7 @@ func CheckCommittedReadiness(
 		InitRequired:        chainOpt.IsInit,
 	}
 
-	signer, err := chaincode.GetSigner(mspOpt.Path, mspOpt.ID)
+	signer, err := chaincode.GetSignerByOpt(mspOpt)
 	if err != nil {
 		return nil, err
 	}
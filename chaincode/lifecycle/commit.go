@@ -66,7 +66,7 @@ func Commit(chainOpt chaincode.ChainOpt, mspOpt chaincode.MSPOpt, channelID stri
 		collections.Config = append(collections.Config, cc)
 	}
 
-	signer, err := chaincode.GetSigner(mspOpt.Path, mspOpt.ID)
+	signer, err := chaincode.GetSignerByOpt(mspOpt)
 	if err != nil {
 		return nil, fmt.Errorf("get signer error -> %v", err)
 	}
@@ -87,7 +87,7 @@ func Commit(chainOpt chaincode.ChainOpt, mspOpt chaincode.MSPOpt, channelID stri
 		return nil, fmt.Errorf("create proposal error -> %v", err)
 	}
 
-	return invoke(signer, proposal, peers, orderers, channelID, txID)
+	return invoke(signer, proposal, peers, orderers, channelID, txID, chainOpt.Wait)
 }
 
 // Commit2 to Commit
@@ -2,10 +2,8 @@ package lifecycle
 
 import (
 	"context"
-	"crypto/tls"
 	"errors"
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/Asutorufa/fabricsdk/chaincode"
@@ -28,47 +26,18 @@ func peerInvoke(
 	peers []chaincode.Endpoint,
 	signedProposal *peer.SignedProposal,
 ) ([]*peer.ProposalResponse, error) {
-	var resps []*peer.ProposalResponse
-	for _, peer := range peers {
-		peerClient, err := client.NewPeerClientSelf(
-			peer.Address,
-			peer.ServerNameOverride,
-			client.WithClientCert(peer.ClientKey, peer.ClientCrt),
-			client.WithTLS(peer.Ca),
-			client.WithTimeout(6*time.Second),
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		endorserClient, err := peerClient.Endorser()
-		if err != nil {
-			return nil, err
-		}
-
-		resp, err := endorserClient.ProcessProposal(context.Background(), signedProposal)
-		if err != nil {
-			return nil, err
-		}
-
-		resps = append(resps, resp)
-	}
-
-	if len(resps) == 0 {
-		return nil, fmt.Errorf("all peers response is empty")
-	}
-
-	resp := resps[0]
-
-	if resp == nil {
-		return nil, fmt.Errorf("resp is nil")
+	peerClients, err := connectPeers(peers)
+	if err != nil {
+		return nil, err
 	}
+	defer chaincode.CloseClients(peerClients)
 
-	if resp.Response.Status != int32(common.Status_SUCCESS) {
-		return nil, fmt.Errorf("%d - %s", resp.Response.Status, resp.Response.Message)
+	var txID string
+	if proposal, err := protoutil.UnmarshalProposal(signedProposal.ProposalBytes); err == nil {
+		txID = proposalTxID(proposal)
 	}
 
-	return resps, nil
+	return endorse(signedProposal, txID, peerClients)
 }
 
 func query(signer msp.SigningIdentity, proposal *peer.Proposal,
@@ -78,47 +47,18 @@ func query(signer msp.SigningIdentity, proposal *peer.Proposal,
 		return nil, err
 	}
 
-	var resps []*peer.ProposalResponse
-	for _, peer := range peers {
-		peerClient, err := client.NewPeerClientSelf(
-			peer.Address,
-			peer.ServerNameOverride,
-			client.WithClientCert(peer.ClientKey, peer.ClientCrt),
-			client.WithTLS(peer.Ca),
-			client.WithTimeout(6*time.Second),
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		endorserClient, err := peerClient.Endorser()
-		if err != nil {
-			return nil, err
-		}
-
-		resp, err := endorserClient.ProcessProposal(context.Background(), signedProposal)
-		if err != nil {
-			return nil, err
-		}
-
-		resps = append(resps, resp)
-	}
-
-	if len(resps) == 0 {
-		return nil, fmt.Errorf("all peers response is empty")
-	}
-
-	resp := resps[0]
-
-	if resp == nil {
-		return nil, fmt.Errorf("resp is nil")
+	peerClients, err := connectPeers(peers)
+	if err != nil {
+		return nil, err
 	}
+	defer chaincode.CloseClients(peerClients)
 
-	if resp.Response.Status != int32(common.Status_SUCCESS) {
-		return nil, fmt.Errorf("%d - %s", resp.Response.Status, resp.Response.Message)
+	resps, err := endorse(signedProposal, proposalTxID(proposal), peerClients)
+	if err != nil {
+		return nil, err
 	}
 
-	return resp, nil
+	return resps[0], nil
 }
 
 func queryAll(signer msp.SigningIdentity, proposal *peer.Proposal,
@@ -139,41 +79,99 @@ func internalQueryAll(signer msp.SigningIdentity, proposal *peer.Proposal,
 		return nil, err
 	}
 
-	var resps []*peer.ProposalResponse
+	return endorse(signedProposal, proposalTxID(proposal), peers)
+}
+
+// connectPeers clients of all peers, error if any peer can't be connected
+func connectPeers(peers []chaincode.Endpoint) ([]*client.PeerClient, error) {
+	var peerClients []*client.PeerClient
 	for _, peer := range peers {
-		endorserClient, err := peer.Endorser()
+		peerClient, err := client.NewPeerClientSelf(
+			peer.Address,
+			peer.ServerNameOverride,
+			client.WithClientCert(peer.ClientKey, peer.ClientCrt),
+			client.WithClientKeyPasswordFunc(peer.ClientKeyPassword),
+			client.WithTLS(peer.Ca),
+			client.WithTimeout(6*time.Second),
+		)
 		if err != nil {
-			return nil, err
+			chaincode.CloseClients(peerClients)
+			return nil, fmt.Errorf("connect to peer [%s] failed: %v", peer.Address, err)
 		}
+		peerClients = append(peerClients, peerClient)
+	}
+	return peerClients, nil
+}
 
-		resp, err := endorserClient.ProcessProposal(context.Background(), signedProposal)
+// endorse send the proposal to every peer in chaincode.DefaultEndorseTimeout,
+// a chaincode.EndorseError is returned if any peer fails or responds with a unsuccessful status,
+// lifecycle invocations need the endorsements of all of them
+func endorse(signedProposal *peer.SignedProposal, txID string, peers []*client.PeerClient) ([]*peer.ProposalResponse, error) {
+	if len(peers) == 0 {
+		return nil, fmt.Errorf("all peers response is empty")
+	}
+
+	var resps []*peer.ProposalResponse
+	var failures []chaincode.PeerFailure
+	for _, p := range peers {
+		resp, err := processProposal(p, signedProposal)
 		if err != nil {
-			return nil, err
+			failures = append(failures, chaincode.PeerFailure{Address: p.Address(), Err: err})
+			continue
+		}
+
+		if resp.Response.Status != int32(common.Status_SUCCESS) {
+			failures = append(failures, chaincode.PeerFailure{
+				Address: p.Address(), Status: resp.Response.Status, Message: resp.Response.Message})
+			continue
 		}
 
 		resps = append(resps, resp)
 	}
 
-	if len(resps) == 0 {
-		return nil, fmt.Errorf("all peers response is empty")
+	if len(failures) > 0 {
+		return nil, &chaincode.EndorseError{TxID: txID, Failures: failures}
+	}
+	return resps, nil
+}
+
+func processProposal(p *client.PeerClient, signedProposal *peer.SignedProposal) (*peer.ProposalResponse, error) {
+	endorserClient, err := p.Endorser()
+	if err != nil {
+		return nil, err
 	}
 
-	resp := resps[0]
+	ctx, cancel := context.WithTimeout(context.Background(), chaincode.DefaultEndorseTimeout)
+	defer cancel()
 
-	if resp == nil {
-		return nil, fmt.Errorf("resp is nil")
+	resp, err := endorserClient.ProcessProposal(ctx, signedProposal)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp == nil || resp.Response == nil {
+		return nil, fmt.Errorf("received proposal response with nil response")
 	}
+	return resp, nil
+}
 
-	if resp.Response.Status != int32(common.Status_SUCCESS) {
-		return nil, fmt.Errorf("%d - %s", resp.Response.Status, resp.Response.Message)
+// proposalTxID txid in the channel header of proposal, empty if it can't be read
+func proposalTxID(proposal *peer.Proposal) string {
+	hdr, err := protoutil.UnmarshalHeader(proposal.Header)
+	if err != nil {
+		return ""
 	}
 
-	return resps, nil
+	chdr, err := protoutil.UnmarshalChannelHeader(hdr.ChannelHeader)
+	if err != nil {
+		return ""
+	}
+	return chdr.TxId
 }
 
 func invoke(signer msp.SigningIdentity, proposal *peer.Proposal,
 	peers []chaincode.Endpoint, orderers []chaincode.Endpoint,
-	channelID string, txID string) (*peer.ProposalResponse, error) {
+	channelID string, txID string, wait chaincode.WaitStrategy) (*peer.ProposalResponse, error) {
 	peerClients := chaincode.GetPeerClients(peers)
 	if len(peerClients) == 0 {
 		return nil, fmt.Errorf("peer clients' is 0")
@@ -186,14 +184,15 @@ func invoke(signer msp.SigningIdentity, proposal *peer.Proposal,
 	}
 	defer chaincode.CloseClients(ordererClients)
 
-	return internalInvoke(signer, proposal, peerClients, ordererClients, channelID, txID)
+	return internalInvoke(signer, proposal, peerClients, ordererClients, channelID, txID, wait)
 }
 
+// internalInvoke wait nil means waiting for all peers in 100 seconds
 func internalInvoke(signer msp.SigningIdentity, proposal *peer.Proposal, peers []*client.PeerClient,
-	orderers []*client.OrdererClient, channelID string, txID string) (*peer.ProposalResponse, error) {
+	orderers []*client.OrdererClient, channelID string, txID string, wait chaincode.WaitStrategy) (*peer.ProposalResponse, error) {
 	resp, err := internalQueryAll(signer, proposal, peers)
 	if err != nil {
-		return nil, fmt.Errorf("invoke from peers error -> %v", err)
+		return nil, fmt.Errorf("invoke from peers error -> %w", err)
 	}
 
 	env, err := protoutil.CreateSignedTx(proposal, signer, resp...)
@@ -201,70 +200,14 @@ func internalInvoke(signer msp.SigningIdentity, proposal *peer.Proposal, peers [
 		return nil, fmt.Errorf("failed to create signed transaction -> %v", err)
 	}
 
-	//
-	//            orderers
-	//
-	var endorserClients []peer.EndorserClient
-	var deliverClients []peer.DeliverClient
-	var certificate tls.Certificate
-	for pi := range peers {
-		certificate = peers[pi].Certificate()
-		endorserClient, err := peers[pi].Endorser()
-		if err != nil {
-			return nil, err
-		}
-		endorserClients = append(endorserClients, endorserClient)
-
-		deliverClient, err := peers[pi].PeerDeliver()
-		if err != nil {
-			return nil, err
-		}
-		deliverClients = append(deliverClients, deliverClient)
+	if wait == nil {
+		wait = chaincode.WaitForAll(100 * time.Second)
 	}
 
-	dg := chaincode.NewDeliverGroup(
-		deliverClients,
-		signer,
-		certificate,
-		channelID,
-		txID,
-	)
-
-	for _, orderer := range orderers {
-		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
-		defer cancel()
-		err = dg.Connect(ctx)
-		if err != nil {
-			// return nil, err
-			log.Println(err)
-			continue
-		}
-
-		ordererClient, err := orderer.Broadcast()
-		if err != nil {
-			// return nil, err
-			log.Println(err)
-			continue
-		}
-		err = ordererClient.Send(env)
-		if err != nil {
-			// return nil, err
-			log.Println(err)
-			continue
-		}
-
-		if dg != nil && ctx != nil {
-			err = dg.Wait(ctx)
-			if err != nil {
-				// return nil, fmt.Errorf("dg.Wait() -> %v", err)
-				log.Printf("dg.Wait() -> %v\n", err)
-				continue
-			}
-		}
-		return resp[0], nil
+	if err = chaincode.BroadcastAndWait(env, signer, wait, peers, channelID, txID, orderers); err != nil {
+		return nil, err
 	}
-
-	return nil, fmt.Errorf("failed send envelop to all orderers")
+	return resp[0], nil
 }
 
 func signProposal(proposal *peer.Proposal, signer msp.SigningIdentity) (*peer.SignedProposal, error) {
@@ -0,0 +1,56 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Asutorufa/fabricsdk/chaincode"
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/Asutorufa/fabricsdk/internal/testutil"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-protos-go/peer/lifecycle"
+	"google.golang.org/grpc"
+)
+
+// fakeEndorser respond every proposal with status
+type fakeEndorser struct{ status int32 }
+
+func (e *fakeEndorser) ProcessProposal(context.Context, *peer.SignedProposal) (*peer.ProposalResponse, error) {
+	return &peer.ProposalResponse{Response: &peer.Response{Status: e.status, Message: "status"}}, nil
+}
+
+func newFakePeer(t *testing.T, status int32) *client.PeerClient {
+	s := grpc.NewServer()
+	peer.RegisterEndorserServer(s, &fakeEndorser{status: status})
+	return testutil.NewPeerClient(t, testutil.Serve(t, s))
+}
+
+func TestInternalQueryAll(t *testing.T) {
+	signer := testutil.NewSigner(t, "Org1MSP", "Admin@org1.example.com")
+	proposal, txID, err := createProposal(&lifecycle.QueryInstalledChaincodesArgs{}, signer, "QueryInstalledChaincodes", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok1, ok2 := newFakePeer(t, 200), newFakePeer(t, 200)
+	resps, err := internalQueryAll(signer, proposal, []*client.PeerClient{ok1, ok2})
+	if err != nil || len(resps) != 2 {
+		t.Fatalf("resps: %d, %v", len(resps), err)
+	}
+
+	// every peer is checked, not only the first one
+	failed := newFakePeer(t, 500)
+	unimplemented := testutil.NewPeerClient(t, testutil.Serve(t, grpc.NewServer()))
+	_, err = internalQueryAll(signer, proposal, []*client.PeerClient{ok1, failed, unimplemented})
+	var e *chaincode.EndorseError
+	if !errors.As(err, &e) || e.TxID != txID || len(e.Failures) != 2 {
+		t.Fatalf("err: %v", err)
+	}
+	if f := e.Failures[0]; f.Address != failed.Address() || f.Status != 500 {
+		t.Errorf("failed peer: %+v", f)
+	}
+	if f := e.Failures[1]; f.Address != unimplemented.Address() || f.Err == nil {
+		t.Errorf("unimplemented peer: %+v", f)
+	}
+}
@@ -15,7 +15,7 @@ func GetInstalledPackage(
 	mspOpt chaincode.MSPOpt,
 	peer []chaincode.Endpoint,
 ) (*peer.ProposalResponse, error) {
-	signer, err := chaincode.GetSigner(mspOpt.Path, mspOpt.ID)
+	signer, err := chaincode.GetSignerByOpt(mspOpt)
 	if err != nil {
 		return nil, fmt.Errorf("get signer failed: %v", err)
 	}
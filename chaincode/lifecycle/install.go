@@ -21,7 +21,7 @@ func Install(
 		return nil, fmt.Errorf("read chaincode package from [%s] error -> %v", chainOpt.Path, err)
 	}
 
-	signer, err := chaincode.GetSigner(mspOpt.Path, mspOpt.ID)
+	signer, err := chaincode.GetSignerByOpt(mspOpt)
 	if err != nil {
 		return nil, fmt.Errorf("get signer error -> %v", err)
 	}
@@ -26,7 +26,7 @@ func QueryApproved(
 		Sequence: chainOpt.Sequence,
 	}
 
-	signer, err := chaincode.GetSigner(mspOpt.Path, mspOpt.ID)
+	signer, err := chaincode.GetSignerByOpt(mspOpt)
 	if err != nil {
 		return nil, err
 	}
@@ -1,8 +1,14 @@
 package lifecycle
 
 import (
+	"context"
+	"fmt"
+	"strings"
+
 	"github.com/Asutorufa/fabricsdk/chaincode"
+	"github.com/Asutorufa/fabricsdk/client"
 	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/common"
 	"github.com/hyperledger/fabric-protos-go/peer"
 	"github.com/hyperledger/fabric-protos-go/peer/lifecycle"
 )
@@ -27,7 +33,7 @@ func QueryCommitted(
 		args = &lifecycle.QueryChaincodeDefinitionsArgs{}
 	}
 
-	signer, err := chaincode.GetSigner(mspOpt.Path, mspOpt.ID)
+	signer, err := chaincode.GetSignerByOpt(mspOpt)
 	if err != nil {
 		return nil, err
 	}
@@ -52,3 +58,74 @@ func QueryCommitted2(
 	}
 	return QueryCommitted(chainOpt, mspOpt, channelID, ep)
 }
+
+//QueryEndorsementPlan query the committed definition of chainOpt.Name,
+// create a plan from its endorsement policy for chaincode.InternalInvokeWithPlan or chaincode.ChainOpt.Plan,
+// a channel config policy reference is resolved from the channel config fetched from peer
+// peersByMSP msp id -> peers of the msp
+func QueryEndorsementPlan(
+	chainOpt chaincode.ChainOpt,
+	mspOpt chaincode.MSPOpt,
+	channelID string,
+	peer []chaincode.Endpoint,
+	peersByMSP map[string][]*client.PeerClient,
+) (*chaincode.EndorsementPlan, error) {
+	if chainOpt.Name == "" {
+		return nil, fmt.Errorf("chaincode name is empty")
+	}
+
+	resp, err := QueryCommitted(chainOpt, mspOpt, channelID, peer)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Response.Status != 200 {
+		return nil, fmt.Errorf("query committed failed: %s", resp.Response.Message)
+	}
+
+	result := &lifecycle.QueryChaincodeDefinitionResult{}
+	if err = proto.Unmarshal(resp.Response.Payload, result); err != nil {
+		return nil, fmt.Errorf("unmarshal chaincode definition failed: %v", err)
+	}
+
+	var config *common.Config
+	if policyReference(result.ValidationParameter) != "" {
+		if config, err = fetchChannelConfig(mspOpt, channelID, peer); err != nil {
+			return nil, err
+		}
+	}
+
+	return chaincode.NewEndorsementPlanFromValidationParameter(result.ValidationParameter, config, peersByMSP)
+}
+
+// policyReference channel config policy reference of the validation parameter, empty if it is not a reference
+func policyReference(validationParameter []byte) string {
+	ap := &peer.ApplicationPolicy{}
+	if err := proto.Unmarshal(validationParameter, ap); err != nil {
+		return ""
+	}
+	return ap.GetChannelConfigPolicyReference()
+}
+
+// fetchChannelConfig fetch the channel config from the first peer which has it
+func fetchChannelConfig(mspOpt chaincode.MSPOpt, channelID string, peers []chaincode.Endpoint) (*common.Config, error) {
+	peerClients := chaincode.GetPeerClients(peers)
+	if len(peerClients) == 0 {
+		return nil, fmt.Errorf("peer clients' number is 0")
+	}
+	defer chaincode.CloseClients(peerClients)
+
+	ctx, cancel := context.WithTimeout(context.Background(), chaincode.DefaultCommitTimeout)
+	defer cancel()
+
+	var errs []string
+	for _, pc := range peerClients {
+		config, err := chaincode.FetchChannelConfig(ctx, mspOpt, channelID, pc)
+		if err == nil {
+			return config, nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", pc.Address(), err))
+	}
+
+	return nil, fmt.Errorf("fetch channel config failed: %s", strings.Join(errs, "; "))
+}
@@ -23,7 +23,7 @@ func QueryInstalled(
 	mspOpt chaincode.MSPOpt,
 	peer []chaincode.Endpoint,
 ) (*peer.ProposalResponse, error) {
-	signer, err := chaincode.GetSigner(mspOpt.Path, mspOpt.ID)
+	signer, err := chaincode.GetSignerByOpt(mspOpt)
 	if err != nil {
 		return nil, err
 	}
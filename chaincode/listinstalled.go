@@ -17,7 +17,7 @@ func ListInstalled(mspOpt MSPOpt, peers []Endpoint) (*peer.ProposalResponse, err
 
 //InternalListInstalled list installed chaincodes
 func InternalListInstalled(mspOpt MSPOpt, peers []*client.PeerClient) (*peer.ProposalResponse, error) {
-	signer, err := GetSigner(mspOpt.Path, mspOpt.ID)
+	signer, err := GetSignerByOpt(mspOpt)
 	if err != nil {
 		return nil, fmt.Errorf("get signer failed: %v", err)
 	}
@@ -17,7 +17,7 @@ func ListInstantiated(channelID string, mspOpt MSPOpt, peers []Endpoint) (*peer.
 
 //InternalListInstantiated list in use chaincodes
 func InternalListInstantiated(channelID string, mspOpt MSPOpt, peers []*client.PeerClient) (*peer.ProposalResponse, error) {
-	signer, err := GetSigner(mspOpt.Path, mspOpt.ID)
+	signer, err := GetSignerByOpt(mspOpt)
 	if err != nil {
 		return nil, fmt.Errorf("get signer failed: %v", err)
 	}
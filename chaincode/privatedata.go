@@ -0,0 +1,139 @@
+package chaincode
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/ledger/rwset"
+	"github.com/hyperledger/fabric-protos-go/ledger/rwset/kvrwset"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/protoutil"
+)
+
+//BlockTransaction a endorser transaction of a block with its read write sets
+type BlockTransaction struct {
+	// Index position of the transaction in the block
+	Index      uint64
+	TxID       string
+	Code       peer.TxValidationCode
+	Namespaces []*NamespaceRWSet
+}
+
+//Namespace rwset of namespace, nil if not touched
+func (t *BlockTransaction) Namespace(namespace string) *NamespaceRWSet {
+	for _, ns := range t.Namespaces {
+		if ns.Namespace == namespace {
+			return ns
+		}
+	}
+	return nil
+}
+
+//BlockTransactions decode the endorser transactions of a full block,
+// private read write sets of event.PrivateData are set to CollectionRWSet.Private of their collections
+func BlockTransactions(event *BlockEvent) ([]*BlockTransaction, error) {
+	block := event.Block
+	if block == nil || block.Header == nil || block.Data == nil {
+		return nil, fmt.Errorf("block %d is not a full block", event.Number)
+	}
+
+	var codes []byte
+	if len(block.Metadata.GetMetadata()) > int(common.BlockMetadataIndex_TRANSACTIONS_FILTER) {
+		codes = block.Metadata.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER]
+	}
+
+	var txs []*BlockTransaction
+	for i, data := range block.Data.Data {
+		env, err := protoutil.GetEnvelopeFromBlock(data)
+		if err != nil {
+			return nil, fmt.Errorf("get envelope %d of block %d failed: %v", i, block.Header.Number, err)
+		}
+
+		payload, err := protoutil.UnmarshalPayload(env.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshal payload %d of block %d failed: %v", i, block.Header.Number, err)
+		}
+
+		chdr, err := protoutil.UnmarshalChannelHeader(payload.GetHeader().GetChannelHeader())
+		if err != nil {
+			return nil, fmt.Errorf("unmarshal channel header %d of block %d failed: %v", i, block.Header.Number, err)
+		}
+		if common.HeaderType(chdr.Type) != common.HeaderType_ENDORSER_TRANSACTION {
+			continue
+		}
+
+		t := &BlockTransaction{Index: uint64(i), TxID: chdr.TxId, Code: peer.TxValidationCode_NOT_VALIDATED}
+		if i < len(codes) {
+			t.Code = peer.TxValidationCode(codes[i])
+		}
+
+		tx, err := protoutil.UnmarshalTransaction(payload.Data)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshal transaction [%s] failed: %v", chdr.TxId, err)
+		}
+
+		for _, action := range tx.Actions {
+			_, cca, err := protoutil.GetPayloads(action)
+			if err != nil {
+				return nil, fmt.Errorf("get chaincode action of transaction [%s] failed: %v", chdr.TxId, err)
+			}
+			if len(cca.Results) == 0 {
+				continue
+			}
+
+			txRWSet := &rwset.TxReadWriteSet{}
+			if err = proto.Unmarshal(cca.Results, txRWSet); err != nil {
+				return nil, fmt.Errorf("unmarshal read write set of transaction [%s] failed: %v", chdr.TxId, err)
+			}
+
+			for _, nsRWSet := range txRWSet.NsRwset {
+				ns, err := decodeNamespaceRWSet(nsRWSet)
+				if err != nil {
+					return nil, err
+				}
+				t.Namespaces = append(t.Namespaces, ns)
+			}
+		}
+
+		if pvt := event.PrivateData[t.Index]; pvt != nil {
+			if err = t.setPrivateData(pvt); err != nil {
+				return nil, fmt.Errorf("decode private data of transaction [%s] failed: %v", chdr.TxId, err)
+			}
+		}
+
+		txs = append(txs, t)
+	}
+
+	return txs, nil
+}
+
+func (t *BlockTransaction) setPrivateData(pvt *rwset.TxPvtReadWriteSet) error {
+	for _, nsPvt := range pvt.NsPvtRwset {
+		ns := t.Namespace(nsPvt.Namespace)
+		if ns == nil {
+			return fmt.Errorf("namespace [%s] of private data is not in the transaction", nsPvt.Namespace)
+		}
+
+		for _, collPvt := range nsPvt.CollectionPvtRwset {
+			var coll *CollectionRWSet
+			for _, c := range ns.Collections {
+				if c.Collection == collPvt.CollectionName {
+					coll = c
+				}
+			}
+			if coll == nil {
+				return fmt.Errorf("collection [%s/%s] of private data is not in the transaction",
+					nsPvt.Namespace, collPvt.CollectionName)
+			}
+
+			kv := &kvrwset.KVRWSet{}
+			if err := proto.Unmarshal(collPvt.Rwset, kv); err != nil {
+				return fmt.Errorf("unmarshal private read write set of collection [%s/%s] failed: %v",
+					nsPvt.Namespace, collPvt.CollectionName, err)
+			}
+			coll.Private = kv
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package chaincode
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+func TestFetchBlocksWithPrivateData(t *testing.T) {
+	l, pc, oc := newFakeLedger(t, peer.TxValidationCode_VALID)
+	close(l.release)
+	mspOpt := newTestMSPOpt(t)
+
+	l.commit("config")
+	commit, err := InternalInvokeAsync(ChainOpt{Name: "basic"}, mspOpt, [][]byte{[]byte("put"), []byte("a"), []byte("1")},
+		nil, "mychannel", []*client.PeerClient{pc}, []*client.OrdererClient{oc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = commit.Status(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	blocks, err := FetchBlocks(context.Background(), mspOpt, "mychannel", pc, 0, 1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 2 || blocks[0].Number != 0 || blocks[1].Number != 1 {
+		t.Fatalf("blocks: %v", blocks)
+	}
+
+	txs, err := BlockTransactions(blocks[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 1 || txs[0].TxID != commit.TxID() || txs[0].Code != peer.TxValidationCode_VALID {
+		t.Fatalf("transactions: %v", txs)
+	}
+
+	ns := txs[0].Namespace("basic")
+	if ns == nil || len(ns.Collections) != 1 {
+		t.Fatalf("namespaces: %v", txs[0].Namespaces)
+	}
+	private := ns.Collections[0].Private
+	if private == nil || len(private.Writes) != 1 || private.Writes[0].Key != "a" || string(private.Writes[0].Value) != "1" {
+		t.Errorf("private data: %v", private)
+	}
+
+	// without private data only the hashes are known
+	blocks, err = FetchBlocks(context.Background(), mspOpt, "mychannel", pc, 1, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txs, err = BlockTransactions(blocks[0]); err != nil {
+		t.Fatal(err)
+	}
+	if coll := txs[0].Namespace("basic").Collections[0]; coll.Private != nil || len(coll.HashedWrites) != 1 {
+		t.Errorf("collection: %+v", coll)
+	}
+
+	// not committed yet
+	if _, err = FetchBlocks(context.Background(), mspOpt, "mychannel", pc, 1, 2, true); err == nil {
+		t.Error("fetched blocks not committed")
+	}
+	if _, err = FetchBlocks(context.Background(), mspOpt, "mychannel", pc, 1, 0, true); err == nil {
+		t.Error("fetched invalid range")
+	}
+}
+
+func TestListenBlocksWithPrivateData(t *testing.T) {
+	l, pc, oc := newFakeLedger(t, peer.TxValidationCode_VALID)
+	close(l.release)
+	mspOpt := newTestMSPOpt(t)
+
+	blocks := make(chan *BlockEvent, 1)
+	listener, err := ListenBlocks(context.Background(), mspOpt, "mychannel", []*client.PeerClient{pc},
+		func(b *BlockEvent) error {
+			blocks <- b
+			return nil
+		}, ListenWithPrivateData(), ListenFrom(SeekOldest()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	commit, err := InternalInvokeAsync(ChainOpt{Name: "basic"}, mspOpt, [][]byte{[]byte("put"), []byte("b"), []byte("2")},
+		nil, "mychannel", []*client.PeerClient{pc}, []*client.OrdererClient{oc})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b *BlockEvent
+	select {
+	case b = <-blocks:
+	case <-time.After(3 * time.Second):
+		t.Fatal("no block received")
+	}
+	if b.Block == nil || len(b.PrivateData) != 1 {
+		t.Fatalf("block: %+v", b)
+	}
+
+	txs, err := BlockTransactions(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 1 || txs[0].TxID != commit.TxID() {
+		t.Fatalf("transactions: %v", txs)
+	}
+	if private := txs[0].Namespace("basic").Collections[0].Private; private == nil || string(private.Writes[0].Value) != "2" {
+		t.Errorf("private data: %v", private)
+	}
+}
@@ -1,9 +1,13 @@
 package chaincode
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/Asutorufa/fabricsdk/client"
 
@@ -12,6 +16,9 @@ import (
 	"github.com/hyperledger/fabric/protoutil"
 )
 
+//DefaultQueryTimeout timeout of querying peers by default, peers are queried concurrently
+const DefaultQueryTimeout = 30 * time.Second
+
 // Query2 .
 func Query2(chaincode ChainOpt, mspOpt MSPOpt, args [][]byte, privateData map[string][]byte,
 	channelID string, peers []EndpointWithPath) (*peer.ProposalResponse, error) {
@@ -43,7 +50,112 @@ func Query(chaincode ChainOpt, mspOpt MSPOpt, args [][]byte, privateData map[str
 		return nil, err
 	}
 
-	resp := proposalResponse[0]
+	return checkQueryResponse(proposalResponse[0])
+}
+
+//InternalQuery query from chaincode with connected peer clients, return the first response
+func InternalQuery(chaincode ChainOpt, mspOpt MSPOpt, args [][]byte,
+	privateData map[string][]byte, channelID string,
+	peers []*client.PeerClient) (*peer.ProposalResponse, error) {
+	proposalResponse, err := internalQuery(chaincode, mspOpt, args, privateData, channelID, peers)
+	if err != nil {
+		return nil, err
+	}
+
+	return checkQueryResponse(proposalResponse[0])
+}
+
+//QueryQuorum query from chaincode on every peer, see InternalQueryQuorum
+func QueryQuorum(chaincode ChainOpt, mspOpt MSPOpt, args [][]byte, privateData map[string][]byte,
+	channelID string, peers []Endpoint, required int) (*peer.ProposalResponse, error) {
+	peerClients := GetPeerClients(peers)
+	if len(peerClients) == 0 {
+		return nil, fmt.Errorf("peer clients' number is 0")
+	}
+	defer CloseClients(peerClients)
+
+	return InternalQueryQuorum(chaincode, mspOpt, args, privateData, channelID, peerClients, required)
+}
+
+//InternalQueryQuorum query from chaincode on every peer, peers can be of different orgs,
+// return the response once at least required peers returned the identical successful payload,
+// otherwise a QuorumError with the diverging peers, protect reads against a stale or malicious peer,
+// required <= 0 means all of peers
+func InternalQueryQuorum(chaincode ChainOpt, mspOpt MSPOpt, args [][]byte,
+	privateData map[string][]byte, channelID string,
+	peers []*client.PeerClient, required int) (*peer.ProposalResponse, error) {
+	if required <= 0 {
+		required = len(peers)
+	}
+	if required > len(peers) {
+		return nil, fmt.Errorf("%d peers required to agree, only %d peers are queried", required, len(peers))
+	}
+
+	txid, endorsements, failures, err := queryOnPeers(chaincode, mspOpt, args, privateData, channelID, peers)
+	if err != nil {
+		return nil, err
+	}
+
+	// group successful responses by payload, in the order of peers
+	var groups [][]endorsement
+	for _, e := range endorsements {
+		if e.response.Response == nil || e.response.Response.Status != int32(common.Status_SUCCESS) {
+			continue
+		}
+
+		found := false
+		for i, g := range groups {
+			if bytes.Equal(g[0].response.Response.Payload, e.response.Response.Payload) {
+				groups[i], found = append(g, e), true
+				break
+			}
+		}
+		if !found {
+			groups = append(groups, []endorsement{e})
+		}
+	}
+
+	var agreed []endorsement
+	reached := 0
+	for _, g := range groups {
+		if len(g) >= required {
+			reached++
+		}
+		if len(g) > len(agreed) {
+			agreed = g
+		}
+	}
+	if reached == 1 {
+		return agreed[0].response, nil
+	}
+
+	qe := &QuorumError{TxID: txid, Required: required, Failures: failures}
+	for _, e := range endorsements {
+		if containsEndorsement(agreed, e) {
+			qe.Agreed = append(qe.Agreed, e.peer.Address())
+			continue
+		}
+
+		d := DivergingPeer{Address: e.peer.Address(), Status: e.response.GetResponse().GetStatus(),
+			Message: e.response.GetResponse().GetMessage(), Payload: e.response.GetResponse().GetPayload()}
+		if e.response.Response == nil {
+			d.Message = "nil response"
+		}
+		qe.Diverging = append(qe.Diverging, d)
+	}
+	return nil, qe
+}
+
+func containsEndorsement(endorsements []endorsement, e endorsement) bool {
+	for _, x := range endorsements {
+		if x.peer == e.peer {
+			return true
+		}
+	}
+	return false
+}
+
+func checkQueryResponse(resp *peer.ProposalResponse) (*peer.ProposalResponse, error) {
 
 	if resp == nil {
 		return nil, errors.New("received nil proposal response")
@@ -54,7 +166,7 @@ func Query(chaincode ChainOpt, mspOpt MSPOpt, args [][]byte, privateData map[str
 	}
 
 	if resp.Response.Status != int32(common.Status_SUCCESS) {
-		return nil, fmt.Errorf("query failed with status: %d - %s", resp.Response.Status, resp.Response.Message)
+		return nil, &EndorseError{Failures: []PeerFailure{{Status: resp.Response.Status, Message: resp.Response.Message}}}
 	}
 
 	return resp, nil
@@ -73,21 +185,35 @@ func query(chaincode ChainOpt, mspOpt MSPOpt, args [][]byte, privateData map[str
 func internalQuery(chaincode ChainOpt, mspOpt MSPOpt, args [][]byte,
 	privateData map[string][]byte, channelID string,
 	peers []*client.PeerClient) ([]*peer.ProposalResponse, error) {
-	invocation := getChaincodeInvocationSpec(
-		chaincode.Path,
-		chaincode.Name,
-		chaincode.IsInit,
-		chaincode.Version,
-		peer.ChaincodeSpec_GOLANG,
-		args,
-	)
-	signer, err := GetSigner(mspOpt.Path, mspOpt.ID)
+	_, endorsements, _, err := queryOnPeers(chaincode, mspOpt, args, privateData, channelID, peers)
+	if err != nil {
+		return nil, err
+	}
+
+	var proposalResponse []*peer.ProposalResponse
+	for _, e := range endorsements {
+		proposalResponse = append(proposalResponse, e.response)
+	}
+	return proposalResponse, nil
+}
+
+// queryOnPeers send the query proposal to all peers concurrently in chaincode.QueryTimeout,
+// endorsements are in the order of peers, failures are peers can't be called or timed out,
+// error if no peer responded
+func queryOnPeers(chaincode ChainOpt, mspOpt MSPOpt, args [][]byte,
+	privateData map[string][]byte, channelID string,
+	peers []*client.PeerClient) (string, []endorsement, []PeerFailure, error) {
+	invocation, err := chaincode.invocationSpec(args)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	signer, err := GetSignerByOpt(mspOpt)
 	if err != nil {
-		return nil, fmt.Errorf("GetSigner() -> %v", err)
+		return "", nil, nil, fmt.Errorf("GetSignerByOpt() -> %v", err)
 	}
 	creator, err := signer.Serialize()
 	if err != nil {
-		return nil, fmt.Errorf("signer.Serialize() -> %v", err)
+		return "", nil, nil, fmt.Errorf("signer.Serialize() -> %v", err)
 	}
 
 	prop, txid, err := protoutil.CreateChaincodeProposalWithTxIDAndTransient(
@@ -104,35 +230,54 @@ func internalQuery(chaincode ChainOpt, mspOpt MSPOpt, args [][]byte,
 		//这个 transient 字段会从通道交易中被排除。
 	)
 	if err != nil {
-		return nil, fmt.Errorf("protoutil.CreateChaincodeProposalWithTxIDAndTransient() -> %v", err)
+		return "", nil, nil, fmt.Errorf("protoutil.CreateChaincodeProposalWithTxIDAndTransient() -> %v", err)
 	}
-	fmt.Printf("txid: %s\n", txid)
 
 	signedProp, err := protoutil.GetSignedProposal(prop, signer)
 	if err != nil {
-		return nil, fmt.Errorf("protoutil.GetSignedProposal() -> %v", err)
+		return "", nil, nil, fmt.Errorf("protoutil.GetSignedProposal() -> %v", err)
 	}
 
-	var proposalResponse []*peer.ProposalResponse
+	ctx, cancel := context.WithTimeout(context.Background(), chaincode.queryTimeout())
+	defer cancel()
+
+	resps := make([]*peer.ProposalResponse, len(peers))
+	errs := make([]error, len(peers))
+	var wg sync.WaitGroup
 	for pi := range peers {
+		wg.Add(1)
+		go func(pi int) {
+			defer wg.Done()
 
-		endorserClient, err := peers[pi].Endorser()
-		if err != nil {
-			return nil, fmt.Errorf("peerClient.Endorser() -> %v", err)
-		}
+			endorserClient, err := peers[pi].Endorser()
+			if err != nil {
+				errs[pi] = fmt.Errorf("get endorser from peer client failed: %v", err)
+				return
+			}
 
-		resp, err := endorserClient.ProcessProposal(context.Background(), signedProp)
-		if err != nil {
-			fmt.Printf("get endorser from peer client failed: %v", err)
+			resps[pi], errs[pi] = endorserClient.ProcessProposal(ctx, signedProp)
+		}(pi)
+	}
+	wg.Wait()
+
+	var endorsements []endorsement
+	var failures []PeerFailure
+	for pi := range peers {
+		if errs[pi] != nil {
+			failures = append(failures, PeerFailure{Address: peers[pi].Address(), Err: errs[pi]})
 			continue
 		}
-		proposalResponse = append(proposalResponse, resp)
+		endorsements = append(endorsements, endorsement{peer: peers[pi], response: resps[pi]})
 	}
 
-	if len(proposalResponse) == 0 {
-		return nil, errors.New("all peers process proposal failed")
+	if len(endorsements) == 0 {
+		var details []string
+		for _, f := range failures {
+			details = append(details, f.String())
+		}
+		return "", nil, nil, fmt.Errorf("all peers process proposal failed: %s", strings.Join(details, "; "))
 	}
 
-	return proposalResponse, nil
+	return txid, endorsements, failures, nil
 
 }
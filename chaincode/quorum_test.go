@@ -0,0 +1,138 @@
+package chaincode
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+func TestInternalQueryQuorum(t *testing.T) {
+	mspOpt := newTestMSPOpt(t)
+
+	// peers returning payloads, "" echoes the argument
+	newPeers := func(payloads ...string) []*client.PeerClient {
+		var peers []*client.PeerClient
+		for _, p := range payloads {
+			l, pc, _ := newFakeLedger(t, peer.TxValidationCode_VALID)
+			if p != "" {
+				l.payload = []byte(p)
+			}
+			peers = append(peers, pc)
+		}
+		return peers
+	}
+
+	tests := []struct {
+		name      string
+		payloads  []string
+		required  int
+		args      []string
+		agreed    int
+		diverging []int
+	}{
+		{"all agree", []string{"", "", ""}, 0, []string{"get", "a"}, 3, nil},
+		{"quorum with a stale peer", []string{"", "stale", ""}, 2, []string{"get", "a"}, 2, nil},
+		{"all required with a stale peer", []string{"", "stale", ""}, 0, []string{"get", "a"}, 2, []int{1}},
+		{"no quorum", []string{"", "stale", "evil"}, 2, []string{"get", "a"}, 1, []int{1, 2}},
+		{"two quorums", []string{"", "", "evil", "evil"}, 2, []string{"get", "a"}, 2, []int{2, 3}},
+		{"failed", []string{"", ""}, 1, []string{"fail"}, 0, []int{0, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			peers := newPeers(tt.payloads...)
+			var args [][]byte
+			for _, a := range tt.args {
+				args = append(args, []byte(a))
+			}
+
+			resp, err := InternalQueryQuorum(ChainOpt{Name: "basic"}, mspOpt, args, nil, "mychannel", peers, tt.required)
+			if tt.diverging == nil {
+				if err != nil || string(resp.Response.Payload) != "a" {
+					t.Fatalf("resp: %v, err: %v", resp, err)
+				}
+				return
+			}
+
+			var qe *QuorumError
+			if !errors.As(err, &qe) || resp != nil {
+				t.Fatalf("resp: %v, err: %v", resp, err)
+			}
+			if len(qe.Agreed) != tt.agreed || len(qe.Diverging) != len(tt.diverging) || qe.TxID == "" {
+				t.Fatalf("quorum error: %+v", qe)
+			}
+			for i, d := range tt.diverging {
+				if qe.Diverging[i].Address != peers[d].Address() {
+					t.Errorf("diverging peer %d: %s, want %s", i, qe.Diverging[i].Address, peers[d].Address())
+				}
+			}
+		})
+	}
+}
+
+func TestInternalQueryQuorumFailures(t *testing.T) {
+	l, pc, _ := newFakeLedger(t, peer.TxValidationCode_VALID)
+	down, _ := l.serveDeliver(t)
+	mspOpt := newTestMSPOpt(t)
+	args := [][]byte{[]byte("get"), []byte("a")}
+
+	// deliver only peer can't endorse
+	resp, err := InternalQueryQuorum(ChainOpt{Name: "basic"}, mspOpt, args, nil, "mychannel", []*client.PeerClient{pc, down}, 1)
+	if err != nil || string(resp.Response.Payload) != "a" {
+		t.Errorf("resp: %v, err: %v", resp, err)
+	}
+
+	_, err = InternalQueryQuorum(ChainOpt{Name: "basic"}, mspOpt, args, nil, "mychannel", []*client.PeerClient{pc, down}, 2)
+	var qe *QuorumError
+	if !errors.As(err, &qe) || len(qe.Failures) != 1 || qe.Failures[0].Address != down.Address() {
+		t.Errorf("err: %v", err)
+	}
+
+	if _, err = InternalQueryQuorum(ChainOpt{Name: "basic"}, mspOpt, args, nil, "mychannel", []*client.PeerClient{pc}, 2); err == nil {
+		t.Error("required more peers than queried")
+	}
+}
+
+func TestInternalQueryQuorumConcurrently(t *testing.T) {
+	mspOpt := newTestMSPOpt(t)
+	args := [][]byte{[]byte("get"), []byte("a")}
+
+	var peers []*client.PeerClient
+	for i := 0; i < 3; i++ {
+		l, pc, _ := newFakeLedger(t, peer.TxValidationCode_VALID)
+		l.endorseDelay = 200 * time.Millisecond
+		peers = append(peers, pc)
+	}
+
+	start := time.Now()
+	resp, err := InternalQueryQuorum(ChainOpt{Name: "basic"}, mspOpt, args, nil, "mychannel", peers, 0)
+	if err != nil || string(resp.Response.Payload) != "a" {
+		t.Fatalf("resp: %v, err: %v", resp, err)
+	}
+	if elapsed := time.Since(start); elapsed >= 400*time.Millisecond {
+		t.Errorf("queried in %v, peers are not queried concurrently", elapsed)
+	}
+
+	// a hanging peer is a failure once timed out
+	l, hanging, _ := newFakeLedger(t, peer.TxValidationCode_VALID)
+	l.endorseDelay = time.Minute
+	start = time.Now()
+	resp, err = InternalQueryQuorum(ChainOpt{Name: "basic", QueryTimeout: 500 * time.Millisecond}, mspOpt, args, nil, "mychannel",
+		append(peers, hanging), 3)
+	if err != nil || string(resp.Response.Payload) != "a" {
+		t.Fatalf("resp: %v, err: %v", resp, err)
+	}
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("queried in %v, the hanging peer is not timed out", elapsed)
+	}
+
+	_, err = InternalQueryQuorum(ChainOpt{Name: "basic", QueryTimeout: 100 * time.Millisecond}, mspOpt, args, nil, "mychannel",
+		[]*client.PeerClient{hanging}, 1)
+	if err == nil || !strings.Contains(err.Error(), hanging.Address()) {
+		t.Errorf("err: %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package chaincode
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/hyperledger/fabric-protos-go/common"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/protoutil"
+)
+
+//Receipt audit reference of a committed transaction,
+// fabric blocks carry no commit time, neither Timestamp nor CommittedAt is the time the transaction is committed on the ledger,
+// the block is identified by BlockNumber and TxIndex
+type Receipt struct {
+	TxID        string
+	BlockNumber uint64
+	// TxIndex position of the transaction in the block
+	TxIndex uint64
+	Code    peer.TxValidationCode
+	// Timestamp timestamp of the channel header, set by the clock of the client when the proposal is created
+	Timestamp time.Time
+	// CommittedAt local time the commit is received by this client, later than the commit by delivering delays
+	CommittedAt time.Time
+	// EndorsingPeers addresses of the peers endorsed the transaction
+	EndorsingPeers []string
+	// Endorsers identities signed the endorsements, in the order of the endorsements
+	Endorsers []*mb.SerializedIdentity
+	// Response the first proposal response
+	Response *peer.ProposalResponse
+	// Event chaincode event set by the chaincode, nil if no event
+	Event *peer.ChaincodeEvent
+}
+
+//Successful the transaction is committed as valid
+func (r *Receipt) Successful() bool {
+	return r.Code == peer.TxValidationCode_VALID
+}
+
+//InvokeWithReceipt Invoke, return the receipt once committed
+func InvokeWithReceipt(chaincode ChainOpt, mspOpt MSPOpt, args [][]byte,
+	privateData map[string][]byte, channelID string,
+	peers []Endpoint, orderers []Endpoint) (*Receipt, error) {
+	peerClients := GetPeerClients(peers)
+	if len(peerClients) == 0 {
+		return nil, fmt.Errorf("peer clients' number is 0")
+	}
+	defer CloseClients(peerClients)
+
+	ordererClients := GetOrdererClients(orderers)
+	if len(ordererClients) == 0 {
+		return nil, fmt.Errorf("orderer clients' number is 0")
+	}
+	defer CloseClients(ordererClients)
+
+	return InternalInvokeWithReceipt(chaincode, mspOpt, args, privateData, channelID, peerClients, ordererClients)
+}
+
+//InternalInvokeWithReceipt InternalInvoke, return the receipt once committed,
+// a invalidated transaction returns its receipt with a ValidationError, a failed endorsement is a EndorseError,
+// chaincode.Wait must wait for some peer, NoWait is rejected before endorsing,
+// see Receipt for the times it carries
+func InternalInvokeWithReceipt(chaincode ChainOpt, mspOpt MSPOpt, args [][]byte,
+	privateData map[string][]byte, channelID string,
+	peers []*client.PeerClient, orderers []*client.OrdererClient,
+) (*Receipt, error) {
+	signer, err := GetSignerByOpt(mspOpt)
+	if err != nil {
+		return nil, err
+	}
+
+	// peers of a plan are known after endorsing, checked once sent then
+	if chaincode.Plan == nil {
+		groups, err := chaincode.waitStrategy().Groups(peers)
+		if err != nil {
+			return nil, fmt.Errorf("get peers to wait for failed: %v", err)
+		}
+		if len(groups) == 0 {
+			return nil, fmt.Errorf("no peer to wait for the commit, a receipt needs chaincode.Wait waiting for some peer, not NoWait")
+		}
+	}
+
+	tx, status, _, _, err := invokeWithAttempts(chaincode, signer, args, privateData, channelID, peers, orderers)
+	if tx == nil {
+		return nil, err
+	}
+	if status == nil {
+		return nil, fmt.Errorf("transaction [%s] is sent but no peer is waited for its commit, "+
+			"a receipt needs chaincode.Wait waiting for some peer, get its status by GetTransactionStatus", tx.txID)
+	}
+
+	receipt, rerr := newReceipt(tx, status)
+	if rerr != nil {
+		return nil, rerr
+	}
+	return receipt, err
+}
+
+//Receipt wait for the commit and return the receipt,
+// a committed but invalidated transaction is not a error, check Receipt.Successful
+func (c *Commit) Receipt(ctx context.Context) (*Receipt, error) {
+	status, err := c.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return newReceipt(c.tx, status)
+}
+
+func newReceipt(tx *endorsedTx, status *CommitStatus) (*Receipt, error) {
+	r := &Receipt{
+		TxID:        tx.txID,
+		BlockNumber: status.BlockNumber,
+		TxIndex:     status.TxIndex,
+		Code:        status.Code,
+		CommittedAt: time.Now(),
+		Response:    tx.response,
+	}
+	for _, p := range tx.peers {
+		r.EndorsingPeers = append(r.EndorsingPeers, p.Address())
+	}
+
+	var err error
+	if r.Timestamp, r.Endorsers, err = envelopeEndorsers(tx.env); err != nil {
+		return nil, fmt.Errorf("decode transaction [%s] failed: %v", tx.txID, err)
+	}
+
+	simulation, err := DecodeSimulation(tx.response)
+	if err != nil {
+		return nil, fmt.Errorf("decode proposal response of transaction [%s] failed: %v", tx.txID, err)
+	}
+	r.Event = simulation.Event
+
+	return r, nil
+}
+
+// envelopeEndorsers timestamp of the channel header and endorser identities of the transaction env
+func envelopeEndorsers(env *common.Envelope) (time.Time, []*mb.SerializedIdentity, error) {
+	payload, err := protoutil.UnmarshalPayload(env.Payload)
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("unmarshal payload failed: %v", err)
+	}
+
+	chdr, err := protoutil.UnmarshalChannelHeader(payload.GetHeader().GetChannelHeader())
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("unmarshal channel header failed: %v", err)
+	}
+
+	tx, err := protoutil.UnmarshalTransaction(payload.Data)
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("unmarshal transaction failed: %v", err)
+	}
+
+	var endorsers []*mb.SerializedIdentity
+	for _, action := range tx.Actions {
+		ccPayload, _, err := protoutil.GetPayloads(action)
+		if err != nil {
+			return time.Time{}, nil, fmt.Errorf("get chaincode action failed: %v", err)
+		}
+
+		for _, e := range ccPayload.GetAction().GetEndorsements() {
+			id, err := protoutil.UnmarshalSerializedIdentity(e.Endorser)
+			if err != nil {
+				return time.Time{}, nil, fmt.Errorf("unmarshal endorser failed: %v", err)
+			}
+			endorsers = append(endorsers, id)
+		}
+	}
+
+	return chdr.GetTimestamp().AsTime(), endorsers, nil
+}
@@ -0,0 +1,93 @@
+package chaincode
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+func TestInternalInvokeWithReceipt(t *testing.T) {
+	l, pc, oc := newFakeLedger(t, peer.TxValidationCode_VALID)
+	close(l.release)
+	mspOpt := newTestMSPOpt(t)
+
+	l.commit("tx0")
+	start := time.Now().Add(-time.Second)
+
+	r, err := InternalInvokeWithReceipt(ChainOpt{Name: "basic"}, mspOpt, [][]byte{[]byte("put"), []byte("a"), []byte("1")},
+		nil, "mychannel", []*client.PeerClient{pc}, []*client.OrdererClient{oc})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.TxID == "" || r.BlockNumber != 1 || r.TxIndex != 0 || !r.Successful() {
+		t.Errorf("receipt: %+v", r)
+	}
+	if r.Timestamp.Before(start) || r.Timestamp.After(time.Now()) || r.CommittedAt.Before(r.Timestamp) {
+		t.Errorf("timestamp: %v, committed at: %v", r.Timestamp, r.CommittedAt)
+	}
+	if len(r.EndorsingPeers) != 1 || r.EndorsingPeers[0] != pc.Address() {
+		t.Errorf("endorsing peers: %v", r.EndorsingPeers)
+	}
+	if len(r.Endorsers) != 1 || r.Endorsers[0].Mspid != "Org1MSP" || len(r.Endorsers[0].IdBytes) == 0 {
+		t.Errorf("endorsers: %v", r.Endorsers)
+	}
+	if r.Event == nil || r.Event.EventName != "put" || string(r.Event.Payload) != "1" {
+		t.Errorf("event: %v", r.Event)
+	}
+	if string(r.Response.Response.Payload) != "1" {
+		t.Errorf("response: %v", r.Response)
+	}
+
+	// invalidated, the receipt is returned with the error
+	l.codes = []peer.TxValidationCode{peer.TxValidationCode_ENDORSEMENT_POLICY_FAILURE}
+	r, err = InternalInvokeWithReceipt(ChainOpt{Name: "basic"}, mspOpt, [][]byte{[]byte("set"), []byte("a")},
+		nil, "mychannel", []*client.PeerClient{pc}, []*client.OrdererClient{oc})
+	var v *ValidationError
+	if !errors.As(err, &v) || r == nil || r.Successful() || r.BlockNumber != 2 || r.Event != nil {
+		t.Errorf("receipt: %+v, err: %v", r, err)
+	}
+
+	// endorsement failed
+	r, err = InternalInvokeWithReceipt(ChainOpt{Name: "basic"}, mspOpt, [][]byte{[]byte("fail")},
+		nil, "mychannel", []*client.PeerClient{pc}, []*client.OrdererClient{oc})
+	var e *EndorseError
+	if !errors.As(err, &e) || r != nil {
+		t.Errorf("receipt: %+v, err: %v", r, err)
+	}
+
+	// not waited, rejected before sending
+	height := l.height()
+	_, err = InternalInvokeWithReceipt(ChainOpt{Name: "basic", Wait: NoWait()}, mspOpt, [][]byte{[]byte("set"), []byte("a")},
+		nil, "mychannel", []*client.PeerClient{pc}, []*client.OrdererClient{oc})
+	if err == nil || !strings.Contains(err.Error(), "NoWait") || l.height() != height {
+		t.Errorf("receipt without waiting: %v, height: %d, want %d", err, l.height(), height)
+	}
+}
+
+func TestCommitReceipt(t *testing.T) {
+	l, pc, oc := newFakeLedger(t, peer.TxValidationCode_MVCC_READ_CONFLICT)
+	close(l.release)
+
+	commit, err := InternalInvokeAsync(ChainOpt{Name: "basic"}, newTestMSPOpt(t), [][]byte{[]byte("put"), []byte("b"), []byte("2")},
+		nil, "mychannel", []*client.PeerClient{pc}, []*client.OrdererClient{oc})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	r, err := commit.Receipt(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.TxID != commit.TxID() || r.Code != peer.TxValidationCode_MVCC_READ_CONFLICT || r.Successful() || r.Event == nil {
+		t.Errorf("receipt: %+v", r)
+	}
+}
@@ -0,0 +1,286 @@
+package chaincode
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/common/crypto"
+	"github.com/hyperledger/fabric/core/scc/qscc"
+	"github.com/hyperledger/fabric/msp"
+	"github.com/hyperledger/fabric/protoutil"
+)
+
+//NewNonce random nonce of proposals, persist it before invoking to retry with the same txid
+func NewNonce() ([]byte, error) {
+	nonce, err := crypto.GetRandomNonce()
+	if err != nil {
+		return nil, fmt.Errorf("get random nonce failed: %v", err)
+	}
+	return nonce, nil
+}
+
+//ComputeTxID txid of proposals created by the signer of mspOpt with the nonce
+func ComputeTxID(mspOpt MSPOpt, nonce []byte) (string, error) {
+	signer, err := GetSignerByOpt(mspOpt)
+	if err != nil {
+		return "", err
+	}
+
+	creator, err := signer.Serialize()
+	if err != nil {
+		return "", fmt.Errorf("serialize signer failed: %v", err)
+	}
+
+	return protoutil.ComputeTxID(nonce, creator), nil
+}
+
+//Transaction a endorsed and signed transaction, send it again by SubmitTransaction keeps the txid
+type Transaction struct {
+	TxID      string
+	ChannelID string
+	Envelope  *common.Envelope
+	// Response the first proposal response, nil for transactions from LoadTransaction
+	Response *peer.ProposalResponse
+}
+
+//Bytes serialized envelope to persist, see LoadTransaction
+func (t *Transaction) Bytes() ([]byte, error) {
+	data, err := proto.Marshal(t.Envelope)
+	if err != nil {
+		return nil, fmt.Errorf("marshal envelope failed: %v", err)
+	}
+	return data, nil
+}
+
+//LoadTransaction transaction persisted by Transaction.Bytes
+func LoadTransaction(data []byte) (*Transaction, error) {
+	env, err := protoutil.UnmarshalEnvelope(data)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal envelope failed: %v", err)
+	}
+
+	chdr, err := protoutil.ChannelHeader(env)
+	if err != nil {
+		return nil, fmt.Errorf("get channel header failed: %v", err)
+	}
+
+	return &Transaction{TxID: chdr.TxId, ChannelID: chdr.ChannelId, Envelope: env}, nil
+}
+
+//PrepareTransaction endorse the proposal created with nonce, the transaction is not sent,
+// transaction is nil if the endorsement fails, the failed response is returned with a EndorseError
+func PrepareTransaction(chaincode ChainOpt, mspOpt MSPOpt, args [][]byte,
+	privateData map[string][]byte, channelID string, nonce []byte,
+	peers []*client.PeerClient) (*Transaction, *peer.ProposalResponse, error) {
+	signer, err := GetSignerByOpt(mspOpt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tx, resp, err := endorseOnPeers(chaincode, signer, args, privateData, channelID, nonce, peers)
+	if tx == nil {
+		return nil, resp, err
+	}
+
+	return &Transaction{TxID: tx.txID, ChannelID: channelID, Envelope: tx.env, Response: tx.response}, resp, nil
+}
+
+//SubmitTransaction send the transaction at most once,
+// if the txid is already on the ledger of any peer, return its status without sending,
+// otherwise send the same envelope again and wait by wait, nil wait means WaitForAll peers,
+// error if the status can't be got from any peer
+func SubmitTransaction(tx *Transaction, mspOpt MSPOpt, wait WaitStrategy,
+	peers []*client.PeerClient, orderers []*client.OrdererClient) (*CommitStatus, error) {
+	signer, err := GetSignerByOpt(mspOpt)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := GetTransactionStatus(mspOpt, tx.ChannelID, tx.TxID, peers)
+	if status != nil {
+		return status, nil
+	}
+	if !errors.Is(err, ErrUnknownTransaction) {
+		return nil, err
+	}
+
+	return submitTransaction(tx, signer, mspOpt, wait, peers, orderers)
+}
+
+// submitTransaction send the transaction not found on the ledger
+func submitTransaction(tx *Transaction, signer msp.SigningIdentity, mspOpt MSPOpt, wait WaitStrategy,
+	peers []*client.PeerClient, orderers []*client.OrdererClient) (*CommitStatus, error) {
+	if wait == nil {
+		wait = WaitForAll(DefaultCommitTimeout)
+	}
+
+	waiter, err := NewCommitWaiter(wait, signer, tx.ChannelID, tx.TxID, peers)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = Broadcast(tx.Envelope, orderers); err != nil {
+		waiter.Close()
+		return nil, err
+	}
+
+	status, err := waiter.WaitStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	// committed by a previous sending, the code of this one is DUPLICATE_TXID
+	if status != nil && status.Code == peer.TxValidationCode_DUPLICATE_TXID {
+		committed, err := GetTransactionStatus(mspOpt, tx.ChannelID, tx.TxID, peers)
+		if err != nil {
+			return nil, fmt.Errorf("get status of duplicated transaction [%s] failed: %w", tx.TxID, err)
+		}
+		return committed, nil
+	}
+
+	return status, nil
+}
+
+// prepared transactions of InternalInvokeWithNonce not found committed yet, txid -> *Transaction,
+// a retry with the same nonce sends the endorsed envelope again instead of endorsing again
+var prepared sync.Map
+
+//InternalInvokeWithNonce invoke at most once, the txid is computed from the nonce,
+// invoke again with the same nonce after a failure (eg: timeout) returns the status on the ledger without endorsing,
+// if the transaction is not on the ledger, the envelope endorsed by the first invocation of this process is sent again,
+// use PrepareTransaction and SubmitTransaction to retry across processes,
+// response is nil if the transaction is already on the ledger and not endorsed by this process
+func InternalInvokeWithNonce(chaincode ChainOpt, mspOpt MSPOpt, args [][]byte,
+	privateData map[string][]byte, channelID string, nonce []byte,
+	peers []*client.PeerClient, orderers []*client.OrdererClient,
+) (*peer.ProposalResponse, *CommitStatus, error) {
+	if len(nonce) == 0 {
+		return nil, nil, fmt.Errorf("nonce is empty")
+	}
+
+	signer, err := GetSignerByOpt(mspOpt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	txID, err := ComputeTxID(MSPOpt{Signer: signer}, nonce)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var resp *peer.ProposalResponse
+	if v, ok := prepared.Load(txID); ok {
+		resp = v.(*Transaction).Response
+	}
+
+	status, err := GetTransactionStatus(mspOpt, channelID, txID, peers)
+	if status != nil {
+		prepared.Delete(txID)
+		return resp, status, nil
+	}
+	if !errors.Is(err, ErrUnknownTransaction) {
+		return resp, nil, err
+	}
+
+	v, ok := prepared.Load(txID)
+	if !ok {
+		tx, resp, err := PrepareTransaction(chaincode, mspOpt, args, privateData, channelID, nonce, peers)
+		if tx == nil {
+			return resp, nil, err
+		}
+		// a concurrent invocation with the same nonce may have prepared it first
+		v, _ = prepared.LoadOrStore(txID, tx)
+	}
+	tx := v.(*Transaction)
+
+	status, err = submitTransaction(tx, signer, mspOpt, chaincode.waitStrategy(), peers, orderers)
+	if err != nil {
+		return tx.Response, nil, err
+	}
+	prepared.Delete(txID)
+
+	return tx.Response, status, statusError(status)
+}
+
+//GetTransactionStatus status of the transaction on the ledger by qscc GetBlockByTxID,
+// BlockNumber and TxIndex are of the block the transaction is committed in,
+// a error of ErrUnknownTransaction is returned if no peer returned the block, eg: not committed yet,
+// other errors if no peer can be queried
+func GetTransactionStatus(mspOpt MSPOpt, channelID, txID string, peers []*client.PeerClient) (*CommitStatus, error) {
+	var failures []string
+	responded := false
+	for _, p := range peers {
+		resps, err := internalQuery(ChainOpt{Name: "qscc"}, mspOpt,
+			[][]byte{[]byte(qscc.GetBlockByTxID), []byte(channelID), []byte(txID)}, nil, channelID, []*client.PeerClient{p})
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("peer [%s]: %v", p.Address(), err))
+			continue
+		}
+
+		resp := resps[0]
+		if resp.Response == nil {
+			failures = append(failures, fmt.Sprintf("peer [%s]: received proposal response with nil response", p.Address()))
+			continue
+		}
+
+		if resp.Response.Status != int32(common.Status_SUCCESS) {
+			responded = true
+			failures = append(failures, fmt.Sprintf("peer [%s]: %d - %s", p.Address(), resp.Response.Status, resp.Response.Message))
+			continue
+		}
+
+		block, err := protoutil.UnmarshalBlock(resp.Response.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshal block of transaction [%s] failed: %v", txID, err)
+		}
+		return blockTransactionStatus(block, txID)
+	}
+
+	if len(peers) == 0 {
+		return nil, fmt.Errorf("get status of transaction [%s] failed: no peer", txID)
+	}
+	if responded {
+		return nil, fmt.Errorf("%w [%s]: %s", ErrUnknownTransaction, txID, strings.Join(failures, "; "))
+	}
+	return nil, fmt.Errorf("get status of transaction [%s] failed: %s", txID, strings.Join(failures, "; "))
+}
+
+// blockTransactionStatus status of the transaction txID in block
+func blockTransactionStatus(block *common.Block, txID string) (*CommitStatus, error) {
+	if block.Header == nil || block.Data == nil {
+		return nil, fmt.Errorf("block of transaction [%s] has no header or data", txID)
+	}
+
+	var codes []byte
+	if len(block.Metadata.GetMetadata()) > int(common.BlockMetadataIndex_TRANSACTIONS_FILTER) {
+		codes = block.Metadata.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER]
+	}
+
+	for i, data := range block.Data.Data {
+		env, err := protoutil.GetEnvelopeFromBlock(data)
+		if err != nil {
+			return nil, fmt.Errorf("get envelope %d of block %d failed: %v", i, block.Header.Number, err)
+		}
+
+		chdr, err := protoutil.ChannelHeader(env)
+		if err != nil {
+			return nil, fmt.Errorf("get channel header %d of block %d failed: %v", i, block.Header.Number, err)
+		}
+		if chdr.TxId != txID {
+			continue
+		}
+
+		if i >= len(codes) {
+			return nil, fmt.Errorf("block %d has no validation code of transaction [%s]", block.Header.Number, txID)
+		}
+		return &CommitStatus{TxID: txID, Code: peer.TxValidationCode(codes[i]), BlockNumber: block.Header.Number, TxIndex: uint64(i)}, nil
+	}
+
+	return nil, fmt.Errorf("transaction [%s] is not in block %d", txID, block.Header.Number)
+}
@@ -0,0 +1,184 @@
+package chaincode
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/Asutorufa/fabricsdk/internal/testutil"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"google.golang.org/grpc"
+)
+
+func TestSubmitTransaction(t *testing.T) {
+	l, pc, oc := newFakeLedger(t, peer.TxValidationCode_VALID)
+	mspOpt := newTestMSPOpt(t)
+	peers, orderers := []*client.PeerClient{pc}, []*client.OrdererClient{oc}
+
+	nonce, err := NewNonce()
+	if err != nil {
+		t.Fatal(err)
+	}
+	txID, err := ComputeTxID(mspOpt, nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, _, err := PrepareTransaction(ChainOpt{Name: "basic"}, mspOpt, [][]byte{[]byte("pay"), []byte("10")},
+		nil, "mychannel", nonce, peers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.TxID != txID || string(tx.Response.Response.Payload) != "10" {
+		t.Errorf("txid: %s, want %s", tx.TxID, txID)
+	}
+
+	data, err := tx.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// blocks are held, the wait times out but the transaction is committed
+	if _, err = SubmitTransaction(tx, mspOpt, WaitForAll(50*time.Millisecond), peers, orderers); err == nil {
+		t.Fatal("submit without commit event")
+	}
+	close(l.release)
+
+	loaded, err := LoadTransaction(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.TxID != txID || loaded.ChannelID != "mychannel" {
+		t.Errorf("loaded: %+v", loaded)
+	}
+
+	status, err := SubmitTransaction(loaded, mspOpt, nil, peers, orderers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Successful() || status.TxID != txID {
+		t.Errorf("status: %+v", status)
+	}
+	if l.height() != 1 {
+		t.Errorf("committed transaction is sent again, blocks: %d", l.height())
+	}
+}
+
+func TestSubmitTransactionDuplicated(t *testing.T) {
+	l, pc, oc := newFakeLedger(t, peer.TxValidationCode_VALID)
+	close(l.release)
+	mspOpt := newTestMSPOpt(t)
+
+	tx, _, err := PrepareTransaction(ChainOpt{Name: "basic"}, mspOpt, [][]byte{[]byte("pay")},
+		nil, "mychannel", nil, []*client.PeerClient{pc})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// committed between the ledger check and sending
+	l.commit(tx.TxID)
+
+	if err = Broadcast(tx.Envelope, []*client.OrdererClient{oc}); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := GetTransactionStatus(mspOpt, "mychannel", tx.TxID, []*client.PeerClient{pc})
+	if err != nil || status == nil || !status.Successful() {
+		t.Errorf("status: %+v, %v", status, err)
+	}
+}
+
+func TestInternalInvokeWithNonce(t *testing.T) {
+	l, pc, oc := newFakeLedger(t, peer.TxValidationCode_VALID)
+	close(l.release)
+	mspOpt := newTestMSPOpt(t)
+	peers, orderers := []*client.PeerClient{pc}, []*client.OrdererClient{oc}
+
+	nonce, err := NewNonce()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, status, err := InternalInvokeWithNonce(ChainOpt{Name: "basic"}, mspOpt, [][]byte{[]byte("pay"), []byte("10")},
+		nil, "mychannel", nonce, peers, orderers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || !status.Successful() || status.BlockNumber != 0 {
+		t.Errorf("resp: %v, status: %+v", resp, status)
+	}
+
+	resp, status, err = InternalInvokeWithNonce(ChainOpt{Name: "basic"}, mspOpt, [][]byte{[]byte("pay"), []byte("10")},
+		nil, "mychannel", nonce, peers, orderers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp != nil || !status.Successful() {
+		t.Errorf("retry resp: %v, status: %+v", resp, status)
+	}
+	if l.height() != 1 {
+		t.Errorf("invoked twice, blocks: %d", l.height())
+	}
+}
+
+func TestInternalInvokeWithNonceResend(t *testing.T) {
+	l, pc, oc := newFakeLedger(t, peer.TxValidationCode_VALID)
+	close(l.release)
+	mspOpt := newTestMSPOpt(t)
+	peers := []*client.PeerClient{pc}
+
+	nonce, err := NewNonce()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// no orderer accepts the first sending
+	_, _, err = InternalInvokeWithNonce(ChainOpt{Name: "basic"}, mspOpt, [][]byte{[]byte("pay"), []byte("10")},
+		nil, "mychannel", nonce, peers, nil)
+	var be *BroadcastError
+	if !errors.As(err, &be) {
+		t.Fatalf("invoke without orderers: %v", err)
+	}
+
+	// the retry sends the envelope endorsed by the first invocation
+	resp, status, err := InternalInvokeWithNonce(ChainOpt{Name: "basic"}, mspOpt, [][]byte{[]byte("pay"), []byte("10")},
+		nil, "mychannel", nonce, peers, []*client.OrdererClient{oc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || string(resp.Response.Payload) != "10" || !status.Successful() {
+		t.Errorf("resp: %v, status: %+v", resp, status)
+	}
+	if n := atomic.LoadInt32(&l.proposals); n != 1 {
+		t.Errorf("endorsed %d times", n)
+	}
+}
+
+func TestGetTransactionStatus(t *testing.T) {
+	l, pc, _ := newFakeLedger(t, peer.TxValidationCode_VALID)
+	mspOpt := newTestMSPOpt(t)
+
+	l.commit("tx0")
+	l.commit("tx1")
+
+	status, err := GetTransactionStatus(mspOpt, "mychannel", "tx1", []*client.PeerClient{pc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Successful() || status.BlockNumber != 1 || status.TxIndex != 0 {
+		t.Errorf("status: %+v", status)
+	}
+
+	if _, err = GetTransactionStatus(mspOpt, "mychannel", "tx2", []*client.PeerClient{pc}); !errors.Is(err, ErrUnknownTransaction) {
+		t.Errorf("status of not committed transaction: %v", err)
+	}
+
+	// the peer can't be queried, the status is not unknown but failed
+	broken := testutil.NewPeerClient(t, testutil.Serve(t, grpc.NewServer()))
+	_, err = GetTransactionStatus(mspOpt, "mychannel", "tx1", []*client.PeerClient{broken})
+	if err == nil || errors.Is(err, ErrUnknownTransaction) {
+		t.Errorf("status from broken peer: %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package chaincode
+
+import (
+	"log"
+	"time"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/msp"
+)
+
+//DefaultRetryPolicy 3 attempts, backoff from 100ms to 2s
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, Backoff: 100 * time.Millisecond, MaxBackoff: 2 * time.Second}
+
+//RetryPolicy re-run the endorsement with a new proposal and resubmit,
+// only for transactions invalidated by MVCC_READ_CONFLICT or PHANTOM_READ_CONFLICT,
+// the validation code is unknown with NoWait, so nothing is retried
+type RetryPolicy struct {
+	// MaxAttempts attempts including the first one, less than 2 means no retry
+	MaxAttempts int
+	// Backoff delay before the first retry, doubled for every next retry
+	Backoff time.Duration
+	// MaxBackoff max delay between retries, 0 means no limit
+	MaxBackoff time.Duration
+}
+
+//Retryable the transaction invalidated with code can be retried with a new proposal
+func (p *RetryPolicy) Retryable(code peer.TxValidationCode) bool {
+	return isConflict(code)
+}
+
+func (p *RetryPolicy) maxAttempts() int {
+	if p == nil || p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// backoff delay before the attempt, attempt starts from 2
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.Backoff
+	for i := 2; i < attempt; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			break
+		}
+	}
+
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+//InternalInvokeWithAttempts InternalInvoke, also return how many attempts were made, retried by chaincode.Retry,
+// every attempt is a new proposal with a new txid, the response is of the last attempt,
+// if the endorsement fails, the failed response is returned with a EndorseError
+func InternalInvokeWithAttempts(chaincode ChainOpt, mspOpt MSPOpt, args [][]byte,
+	privateData map[string][]byte, channelID string,
+	peers []*client.PeerClient, orderers []*client.OrdererClient,
+) (*peer.ProposalResponse, int, error) {
+	signer, err := GetSignerByOpt(mspOpt)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	tx, _, resp, attempt, err := invokeWithAttempts(chaincode, signer, args, privateData, channelID, peers, orderers)
+	if tx == nil {
+		return resp, attempt, err
+	}
+	if err != nil {
+		return nil, attempt, err
+	}
+	return tx.response, attempt, nil
+}
+
+// invokeWithAttempts tx and status of the last attempt, status is nil if not waited,
+// tx is nil if the endorsement failed, resp is the failed response of the EndorseError then
+func invokeWithAttempts(chaincode ChainOpt, signer msp.SigningIdentity, args [][]byte,
+	privateData map[string][]byte, channelID string,
+	peers []*client.PeerClient, orderers []*client.OrdererClient,
+) (*endorsedTx, *CommitStatus, *peer.ProposalResponse, int, error) {
+	policy := chaincode.Retry
+	for attempt := 1; ; attempt++ {
+		tx, resp, err := endorseOnPeers(chaincode, signer, args, privateData, channelID, nil, peers)
+		if tx == nil {
+			return nil, nil, resp, attempt, err
+		}
+
+		// connect before sending, the block may be delivered before connected
+		waiter, err := NewCommitWaiter(chaincode.waitStrategy(), tx.signer, channelID, tx.txID, tx.peers)
+		if err != nil {
+			return nil, nil, nil, attempt, err
+		}
+
+		if err = Broadcast(tx.env, orderers); err != nil {
+			waiter.Close()
+			return nil, nil, nil, attempt, err
+		}
+
+		status, err := waiter.WaitStatus()
+		if err != nil {
+			return nil, nil, nil, attempt, err
+		}
+		if status == nil || status.Successful() {
+			return tx, status, nil, attempt, nil
+		}
+
+		if !policy.Retryable(status.Code) || attempt >= policy.maxAttempts() {
+			return tx, status, nil, attempt, statusError(status)
+		}
+
+		delay := policy.backoff(attempt + 1)
+		log.Printf("transaction [%s] invalidated with status (%s), retry in %v", tx.txID, status.Code, delay)
+		time.Sleep(delay)
+	}
+}
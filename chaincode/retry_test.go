@@ -0,0 +1,68 @@
+package chaincode
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := &RetryPolicy{MaxAttempts: 5, Backoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond}
+	for i, w := range want {
+		if d := p.backoff(i + 2); d != w {
+			t.Errorf("backoff of attempt %d: %v, want %v", i+2, d, w)
+		}
+	}
+
+	var none *RetryPolicy
+	if none.maxAttempts() != 1 {
+		t.Errorf("nil policy attempts: %d", none.maxAttempts())
+	}
+}
+
+func TestInternalInvokeWithAttempts(t *testing.T) {
+	mvcc, phantom := peer.TxValidationCode_MVCC_READ_CONFLICT, peer.TxValidationCode_PHANTOM_READ_CONFLICT
+	retry := &RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}
+
+	tests := []struct {
+		name     string
+		retry    *RetryPolicy
+		codes    []peer.TxValidationCode
+		attempts int
+		err      string
+	}{
+		{"no conflict", retry, nil, 1, ""},
+		{"conflicts", retry, []peer.TxValidationCode{mvcc, phantom}, 3, ""},
+		{"too many conflicts", retry, []peer.TxValidationCode{mvcc, mvcc, mvcc, mvcc}, 3, "MVCC_READ_CONFLICT"},
+		{"no retry", nil, []peer.TxValidationCode{mvcc}, 1, "MVCC_READ_CONFLICT"},
+		{"not retryable", retry, []peer.TxValidationCode{peer.TxValidationCode_ENDORSEMENT_POLICY_FAILURE}, 1, "ENDORSEMENT_POLICY_FAILURE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, pc, oc := newFakeLedger(t, peer.TxValidationCode_VALID)
+			l.codes = tt.codes
+			close(l.release)
+
+			resp, attempts, err := InternalInvokeWithAttempts(ChainOpt{Name: "basic", Retry: tt.retry}, newTestMSPOpt(t),
+				[][]byte{[]byte("set"), []byte("a")}, nil, "mychannel", []*client.PeerClient{pc}, []*client.OrdererClient{oc})
+			if attempts != tt.attempts || l.height() != tt.attempts {
+				t.Errorf("attempts: %d, height: %d, want %d", attempts, l.height(), tt.attempts)
+			}
+
+			if tt.err == "" {
+				if err != nil || string(resp.Response.Payload) != "a" {
+					t.Errorf("resp: %v, err: %v", resp, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("err: %v, want %s", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,442 @@
+package chaincode
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/common"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/common/policydsl"
+)
+
+//Layout one way to satisfy a endorsement policy, group -> number of endorsements needed from the group
+type Layout map[string]int
+
+//EndorsementPlan peers to endorse on, the policy is satisfied if any layout is satisfied
+type EndorsementPlan struct {
+	// Groups group -> peers, eg: msp id -> peers of the msp, or groups of discovery
+	Groups map[string][]*client.PeerClient
+	// Layouts sorted by number of endorsements, the smallest first
+	Layouts []Layout
+}
+
+//NewEndorsementPlan new plan, layouts are sorted by number of endorsements
+func NewEndorsementPlan(groups map[string][]*client.PeerClient, layouts []Layout) *EndorsementPlan {
+	layouts = append([]Layout(nil), layouts...)
+	sort.SliceStable(layouts, func(i, j int) bool { return layouts[i].size() < layouts[j].size() })
+	return &EndorsementPlan{Groups: groups, Layouts: layouts}
+}
+
+//NewEndorsementPlanFromPolicy plan from a signature policy, eg: policydsl.FromString("AND('Org1MSP.peer','Org2MSP.peer')")
+// peersByMSP msp id -> peers of the msp
+func NewEndorsementPlanFromPolicy(policy *common.SignaturePolicyEnvelope, peersByMSP map[string][]*client.PeerClient) (*EndorsementPlan, error) {
+	layouts, err := policyLayouts(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewEndorsementPlan(peersByMSP, layouts), nil
+}
+
+//NewEndorsementPlanFromValidationParameter plan from the validation parameter of a committed chaincode definition,
+// channel config policy references (eg: the default /Channel/Application/Endorsement) are resolved from config,
+// see FetchChannelConfig, config can be nil if the validation parameter is a signature policy
+func NewEndorsementPlanFromValidationParameter(validationParameter []byte, config *common.Config,
+	peersByMSP map[string][]*client.PeerClient) (*EndorsementPlan, error) {
+	ap := &peer.ApplicationPolicy{}
+	if err := proto.Unmarshal(validationParameter, ap); err != nil {
+		return nil, fmt.Errorf("unmarshal application policy failed: %v", err)
+	}
+
+	switch p := ap.Type.(type) {
+	case *peer.ApplicationPolicy_SignaturePolicy:
+		return NewEndorsementPlanFromPolicy(p.SignaturePolicy, peersByMSP)
+	case *peer.ApplicationPolicy_ChannelConfigPolicyReference:
+		if config == nil {
+			return nil, fmt.Errorf("channel config is needed to resolve policy reference [%s]", p.ChannelConfigPolicyReference)
+		}
+
+		policy, err := ChannelConfigPolicy(config, p.ChannelConfigPolicyReference)
+		if err != nil {
+			return nil, err
+		}
+		return NewEndorsementPlanFromPolicy(policy, peersByMSP)
+	}
+
+	return nil, fmt.Errorf("unsupported application policy type: %T", ap.Type)
+}
+
+//ChannelConfigPolicy resolve a policy of the channel config to a signature policy,
+// reference eg: /Channel/Application/Endorsement, a relative reference is relative to /Channel/Application,
+// implicit meta policies (ANY, ALL, MAJORITY) are expanded to the signature policies of the sub groups
+func ChannelConfigPolicy(config *common.Config, reference string) (*common.SignaturePolicyEnvelope, error) {
+	path := strings.Split(strings.Trim(reference, "/"), "/")
+	if !strings.HasPrefix(reference, "/") {
+		path = append([]string{"Channel", "Application"}, path...)
+	}
+	if len(path) < 2 || path[0] != "Channel" {
+		return nil, fmt.Errorf("invalid channel config policy reference [%s]", reference)
+	}
+
+	group := config.GetChannelGroup()
+	for _, name := range path[1 : len(path)-1] {
+		if group = group.GetGroups()[name]; group == nil {
+			return nil, fmt.Errorf("group [%s] of policy reference [%s] not found", name, reference)
+		}
+	}
+
+	policy, err := groupPolicy(group, path[len(path)-1])
+	if err != nil {
+		return nil, fmt.Errorf("resolve policy reference [%s] failed: %v", reference, err)
+	}
+	return policy, nil
+}
+
+// groupPolicy signature policy name of group
+func groupPolicy(group *common.ConfigGroup, name string) (*common.SignaturePolicyEnvelope, error) {
+	policy := group.GetPolicies()[name].GetPolicy()
+	if policy == nil {
+		return nil, fmt.Errorf("policy [%s] not found", name)
+	}
+
+	switch common.Policy_PolicyType(policy.Type) {
+	case common.Policy_SIGNATURE:
+		env := &common.SignaturePolicyEnvelope{}
+		if err := proto.Unmarshal(policy.Value, env); err != nil {
+			return nil, fmt.Errorf("unmarshal signature policy [%s] failed: %v", name, err)
+		}
+		return env, nil
+	case common.Policy_IMPLICIT_META:
+		meta := &common.ImplicitMetaPolicy{}
+		if err := proto.Unmarshal(policy.Value, meta); err != nil {
+			return nil, fmt.Errorf("unmarshal implicit meta policy [%s] failed: %v", name, err)
+		}
+		return implicitMetaPolicy(group, meta)
+	}
+
+	return nil, fmt.Errorf("unsupported policy type of [%s]: %d", name, policy.Type)
+}
+
+// implicitMetaPolicy n out of the sub policies of the sub groups, as fabric evaluates implicit meta policies
+func implicitMetaPolicy(group *common.ConfigGroup, meta *common.ImplicitMetaPolicy) (*common.SignaturePolicyEnvelope, error) {
+	var names []string
+	for name := range group.GetGroups() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no sub group for implicit meta policy %s %s", meta.Rule, meta.SubPolicy)
+	}
+
+	env := &common.SignaturePolicyEnvelope{}
+	var rules []*common.SignaturePolicy
+	for _, name := range names {
+		sub, err := groupPolicy(group.Groups[name], meta.SubPolicy)
+		if err != nil {
+			return nil, fmt.Errorf("sub group [%s]: %v", name, err)
+		}
+
+		rules = append(rules, shiftSignedBy(sub.Rule, int32(len(env.Identities))))
+		env.Identities = append(env.Identities, sub.Identities...)
+	}
+
+	var n int
+	switch meta.Rule {
+	case common.ImplicitMetaPolicy_ANY:
+		n = 1
+	case common.ImplicitMetaPolicy_ALL:
+		n = len(rules)
+	case common.ImplicitMetaPolicy_MAJORITY:
+		n = len(rules)/2 + 1
+	default:
+		return nil, fmt.Errorf("unknown implicit meta policy rule: %s", meta.Rule)
+	}
+
+	env.Rule = policydsl.NOutOf(int32(n), rules)
+	return env, nil
+}
+
+// shiftSignedBy copy of rule which identity indexes are shifted by offset
+func shiftSignedBy(rule *common.SignaturePolicy, offset int32) *common.SignaturePolicy {
+	switch r := rule.GetType().(type) {
+	case *common.SignaturePolicy_SignedBy:
+		return policydsl.SignedBy(r.SignedBy + offset)
+	case *common.SignaturePolicy_NOutOf_:
+		var rules []*common.SignaturePolicy
+		for _, sub := range r.NOutOf.Rules {
+			rules = append(rules, shiftSignedBy(sub, offset))
+		}
+		return policydsl.NOutOf(r.NOutOf.N, rules)
+	}
+	return rule
+}
+
+func (l Layout) size() int {
+	var n int
+	for _, c := range l {
+		n += c
+	}
+	return n
+}
+
+func (l Layout) key() string {
+	var groups []string
+	for g, c := range l {
+		groups = append(groups, fmt.Sprintf("%s:%d", g, c))
+	}
+	sort.Strings(groups)
+	return strings.Join(groups, ",")
+}
+
+// covers every group of o needs no more endorsements than l
+func (l Layout) covers(o Layout) bool {
+	for g, c := range o {
+		if l[g] < c {
+			return false
+		}
+	}
+	return true
+}
+
+// policyLayouts expand the policy to minimal layouts, group is the msp id of principals
+func policyLayouts(policy *common.SignaturePolicyEnvelope) ([]Layout, error) {
+	var mspIDs []string
+	for _, principal := range policy.Identities {
+		mspID, err := principalMSPID(principal)
+		if err != nil {
+			return nil, err
+		}
+		mspIDs = append(mspIDs, mspID)
+	}
+
+	layouts, err := ruleLayouts(policy.Rule, mspIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// remove duplicated and non minimal layouts
+	var minimal []Layout
+	seen := map[string]bool{}
+	for i, l := range layouts {
+		if seen[l.key()] {
+			continue
+		}
+		seen[l.key()] = true
+
+		redundant := false
+		for j, o := range layouts {
+			if i != j && l.covers(o) && l.key() != o.key() {
+				redundant = true
+				break
+			}
+		}
+		if !redundant {
+			minimal = append(minimal, l)
+		}
+	}
+
+	return minimal, nil
+}
+
+func principalMSPID(principal *mb.MSPPrincipal) (string, error) {
+	switch principal.PrincipalClassification {
+	case mb.MSPPrincipal_ROLE:
+		role := &mb.MSPRole{}
+		if err := proto.Unmarshal(principal.Principal, role); err != nil {
+			return "", fmt.Errorf("unmarshal msp role failed: %v", err)
+		}
+		return role.MspIdentifier, nil
+	case mb.MSPPrincipal_ORGANIZATION_UNIT:
+		ou := &mb.OrganizationUnit{}
+		if err := proto.Unmarshal(principal.Principal, ou); err != nil {
+			return "", fmt.Errorf("unmarshal organization unit failed: %v", err)
+		}
+		return ou.MspIdentifier, nil
+	case mb.MSPPrincipal_IDENTITY:
+		sid := &mb.SerializedIdentity{}
+		if err := proto.Unmarshal(principal.Principal, sid); err != nil {
+			return "", fmt.Errorf("unmarshal identity failed: %v", err)
+		}
+		return sid.Mspid, nil
+	}
+
+	return "", fmt.Errorf("unsupported principal classification: %v", principal.PrincipalClassification)
+}
+
+func ruleLayouts(rule *common.SignaturePolicy, mspIDs []string) ([]Layout, error) {
+	switch r := rule.Type.(type) {
+	case *common.SignaturePolicy_SignedBy:
+		if int(r.SignedBy) >= len(mspIDs) {
+			return nil, fmt.Errorf("identity index %d out of range", r.SignedBy)
+		}
+		return []Layout{{mspIDs[r.SignedBy]: 1}}, nil
+
+	case *common.SignaturePolicy_NOutOf_:
+		subs := make([][]Layout, len(r.NOutOf.Rules))
+		for i, sub := range r.NOutOf.Rules {
+			l, err := ruleLayouts(sub, mspIDs)
+			if err != nil {
+				return nil, err
+			}
+			subs[i] = l
+		}
+
+		var layouts []Layout
+		for _, combination := range combinations(len(subs), int(r.NOutOf.N)) {
+			product := []Layout{{}}
+			for _, i := range combination {
+				var next []Layout
+				for _, p := range product {
+					for _, l := range subs[i] {
+						merged := Layout{}
+						for g, c := range p {
+							merged[g] += c
+						}
+						// every signature only satisfies one principal
+						for g, c := range l {
+							merged[g] += c
+						}
+						next = append(next, merged)
+					}
+				}
+				product = next
+			}
+			layouts = append(layouts, product...)
+		}
+		return layouts, nil
+	}
+
+	return nil, fmt.Errorf("unsupported signature policy type: %T", rule.Type)
+}
+
+// combinations all k of n indexes
+func combinations(n, k int) [][]int {
+	if k <= 0 {
+		return [][]int{{}}
+	}
+	if k > n {
+		return nil
+	}
+
+	var res [][]int
+	var f func(start int, cur []int)
+	f = func(start int, cur []int) {
+		if len(cur) == k {
+			res = append(res, append([]int(nil), cur...))
+			return
+		}
+		for i := start; i < n; i++ {
+			f(i+1, append(cur, i))
+		}
+	}
+	f(0, nil)
+	return res
+}
+
+// endorsement a successful endorsement of a peer
+type endorsement struct {
+	peer     *client.PeerClient
+	response *peer.ProposalResponse
+}
+
+// endorse try layouts one by one, a peer is sent the proposal at most once,
+// return endorsements once a layout is satisfied,
+// failed peers are replaced by alternates of the same group,
+// a peer not responding in timeout is failed too, so the next layout can be tried,
+// a EndorseError of the failed peers is returned if no layout is satisfied,
+// with the last failed response if some peer responded with a failed status
+func (p *EndorsementPlan) endorse(signedProp *peer.SignedProposal, txid string,
+	timeout time.Duration) ([]endorsement, *peer.ProposalResponse, error) {
+	done := map[*client.PeerClient]*peer.ProposalResponse{}
+	failed := map[*client.PeerClient]bool{}
+	var failures []PeerFailure
+	var lastFailure *peer.ProposalResponse
+
+	for _, layout := range p.Layouts {
+		var endorsements []endorsement
+		used := map[*client.PeerClient]bool{}
+		satisfied := true
+
+		groups := make([]string, 0, len(layout))
+		for g := range layout {
+			groups = append(groups, g)
+		}
+		sort.Strings(groups)
+
+		for _, g := range groups {
+			need := layout[g]
+			for _, pc := range p.Groups[g] {
+				if need == 0 {
+					break
+				}
+				if failed[pc] || used[pc] {
+					continue
+				}
+
+				resp, ok := done[pc]
+				if !ok {
+					var err error
+					resp, err = endorseOne(pc, signedProp, timeout)
+					if err != nil {
+						log.Printf("endorse on peer [%s] of group [%s] failed: %v", pc.Address(), g, err)
+						failures = append(failures, PeerFailure{Address: pc.Address(), Err: err})
+						failed[pc] = true
+						continue
+					}
+
+					if resp.Response.Status >= shim.ERRORTHRESHOLD {
+						failures = append(failures, PeerFailure{
+							Address: pc.Address(), Status: resp.Response.Status, Message: resp.Response.Message})
+						lastFailure = resp
+						failed[pc] = true
+						continue
+					}
+					done[pc] = resp
+				}
+
+				used[pc] = true
+				endorsements = append(endorsements, endorsement{peer: pc, response: resp})
+				need--
+			}
+
+			if need > 0 {
+				satisfied = false
+				break
+			}
+		}
+
+		if satisfied {
+			return endorsements, nil, nil
+		}
+	}
+
+	if len(failures) > 0 {
+		return nil, lastFailure, endorseError(txid, failures)
+	}
+	return nil, nil, fmt.Errorf("endorsement policy can't be satisfied by peers of the plan")
+}
+
+func endorseOne(pc *client.PeerClient, signedProp *peer.SignedProposal, timeout time.Duration) (*peer.ProposalResponse, error) {
+	endorser, err := pc.Endorser()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := endorseWithTimeout(endorser, signedProp, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Response == nil {
+		return nil, fmt.Errorf("received proposal response with nil response")
+	}
+
+	return resp, nil
+}
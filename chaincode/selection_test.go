@@ -0,0 +1,291 @@
+package chaincode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/Asutorufa/fabricsdk/internal/testutil"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/common/policydsl"
+	"github.com/hyperledger/fabric/protoutil"
+	"google.golang.org/grpc"
+)
+
+func TestPolicyLayouts(t *testing.T) {
+	tests := []struct {
+		policy string
+		want   []string
+	}{
+		{"AND('Org1MSP.peer','Org2MSP.peer')", []string{"Org1MSP:1,Org2MSP:1"}},
+		{"OR('Org1MSP.peer','Org2MSP.member')", []string{"Org1MSP:1", "Org2MSP:1"}},
+		{"OutOf(2,'Org1MSP.peer','Org2MSP.peer','Org3MSP.peer')",
+			[]string{"Org1MSP:1,Org2MSP:1", "Org1MSP:1,Org3MSP:1", "Org2MSP:1,Org3MSP:1"}},
+		{"OR('Org1MSP.peer',AND('Org1MSP.peer','Org2MSP.peer'))", []string{"Org1MSP:1"}},
+		{"AND('Org1MSP.peer','Org1MSP.admin')", []string{"Org1MSP:2"}},
+	}
+
+	for _, tt := range tests {
+		policy, err := policydsl.FromString(tt.policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		layouts, err := policyLayouts(policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for _, l := range layouts {
+			got = append(got, l.key())
+		}
+		sort.Strings(got)
+
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: %v", tt.policy, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: %v", tt.policy, got)
+				break
+			}
+		}
+	}
+}
+
+type fakeSelectionEndorser struct {
+	status int32
+	calls  int32
+	// hang the proposal is not responded until the client gives up
+	hang bool
+}
+
+func (f *fakeSelectionEndorser) ProcessProposal(ctx context.Context, _ *peer.SignedProposal) (*peer.ProposalResponse, error) {
+	atomic.AddInt32(&f.calls, 1)
+	if f.hang {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return &peer.ProposalResponse{Response: &peer.Response{Status: f.status}}, nil
+}
+
+func newSelectionPeer(t *testing.T, status int32) (*client.PeerClient, *fakeSelectionEndorser) {
+	e := &fakeSelectionEndorser{status: status}
+	s := grpc.NewServer()
+	peer.RegisterEndorserServer(s, e)
+	return testutil.NewPeerClient(t, testutil.Serve(t, s)), e
+}
+
+func TestEndorsementPlan(t *testing.T) {
+	org1Failed, org1FailedEndorser := newSelectionPeer(t, 500)
+	org1, org1Endorser := newSelectionPeer(t, 200)
+	org2, org2Endorser := newSelectionPeer(t, 200)
+	org3, org3Endorser := newSelectionPeer(t, 200)
+
+	policy, err := policydsl.FromString("OutOf(2,'Org1MSP.peer','Org2MSP.peer','Org3MSP.peer')")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := NewEndorsementPlanFromPolicy(policy, map[string][]*client.PeerClient{
+		"Org1MSP": {org1Failed, org1},
+		"Org2MSP": {org2},
+		"Org3MSP": {org3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endorsements, failure, err := plan.endorse(&peer.SignedProposal{}, "tx1", DefaultEndorseTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failure != nil {
+		t.Fatalf("failure: %v", failure)
+	}
+
+	if len(endorsements) != 2 {
+		t.Errorf("endorsements: %d", len(endorsements))
+	}
+	if org1FailedEndorser.calls != 1 || org1Endorser.calls != 1 || org2Endorser.calls != 1 {
+		t.Errorf("calls: %d %d %d", org1FailedEndorser.calls, org1Endorser.calls, org2Endorser.calls)
+	}
+	if org3Endorser.calls != 0 {
+		t.Error("endorse on more peers than needed")
+	}
+
+	plan, err = NewEndorsementPlanFromPolicy(policy, map[string][]*client.PeerClient{"Org1MSP": {org1Failed}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, failure, err = plan.endorse(&peer.SignedProposal{}, "tx1", DefaultEndorseTimeout)
+	var e *EndorseError
+	if !errors.As(err, &e) || e.TxID != "tx1" || len(e.Failures) != 1 || e.Failures[0].Address != org1Failed.Address() {
+		t.Errorf("err: %v", err)
+	}
+	if failure == nil || failure.Response.Status != 500 {
+		t.Errorf("failure: %v", failure)
+	}
+
+	plan, err = NewEndorsementPlanFromPolicy(policy, map[string][]*client.PeerClient{"Org1MSP": {org1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = plan.endorse(&peer.SignedProposal{}, "tx1", DefaultEndorseTimeout); err == nil {
+		t.Error("policy satisfied by one org")
+	}
+}
+
+func TestEndorsementPlanTimeout(t *testing.T) {
+	org1, _ := newSelectionPeer(t, 200)
+	org2, org2Endorser := newSelectionPeer(t, 200)
+	org2Endorser.hang = true
+	org3, org3Endorser := newSelectionPeer(t, 200)
+
+	policy, err := policydsl.FromString("OutOf(2,'Org1MSP.peer','Org2MSP.peer','Org3MSP.peer')")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := NewEndorsementPlanFromPolicy(policy, map[string][]*client.PeerClient{
+		"Org1MSP": {org1},
+		"Org2MSP": {org2},
+		"Org3MSP": {org3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the hanging peer of the first layout is given up, the next layout is satisfied
+	start := time.Now()
+	endorsements, _, err := plan.endorse(&peer.SignedProposal{}, "tx1", 100*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("timeout is not used, returned after %v", elapsed)
+	}
+	if len(endorsements) != 2 || atomic.LoadInt32(&org2Endorser.calls) != 1 || atomic.LoadInt32(&org3Endorser.calls) != 1 {
+		t.Errorf("endorsements: %d, calls: %d %d", len(endorsements), org2Endorser.calls, org3Endorser.calls)
+	}
+}
+
+// testChannelConfig channel config of orgs, /Channel/Application/Endorsement is MAJORITY Endorsement of the orgs
+func testChannelConfig(t *testing.T, orgs ...string) *common.Config {
+	signature := func(policy string) *common.ConfigPolicy {
+		env, err := policydsl.FromString(policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &common.ConfigPolicy{Policy: &common.Policy{
+			Type: int32(common.Policy_SIGNATURE), Value: protoutil.MarshalOrPanic(env)}}
+	}
+
+	application := &common.ConfigGroup{
+		Groups: map[string]*common.ConfigGroup{},
+		Policies: map[string]*common.ConfigPolicy{
+			"Endorsement": {Policy: &common.Policy{
+				Type: int32(common.Policy_IMPLICIT_META),
+				Value: protoutil.MarshalOrPanic(&common.ImplicitMetaPolicy{
+					SubPolicy: "Endorsement", Rule: common.ImplicitMetaPolicy_MAJORITY}),
+			}},
+		},
+	}
+	for _, org := range orgs {
+		application.Groups[org] = &common.ConfigGroup{Policies: map[string]*common.ConfigPolicy{
+			"Endorsement": signature(fmt.Sprintf("OR('%s.peer')", org)),
+		}}
+	}
+	application.Policies["Admins"] = signature("OR('Org1MSP.admin')")
+
+	return &common.Config{ChannelGroup: &common.ConfigGroup{
+		Groups: map[string]*common.ConfigGroup{"Application": application},
+	}}
+}
+
+func TestChannelConfigPolicy(t *testing.T) {
+	config := testChannelConfig(t, "Org1MSP", "Org2MSP", "Org3MSP")
+
+	for _, reference := range []string{"/Channel/Application/Endorsement", "Endorsement"} {
+		policy, err := ChannelConfigPolicy(config, reference)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		layouts, err := policyLayouts(policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, l := range layouts {
+			got = append(got, l.key())
+		}
+		sort.Strings(got)
+		if want := []string{"Org1MSP:1,Org2MSP:1", "Org1MSP:1,Org3MSP:1", "Org2MSP:1,Org3MSP:1"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: layouts %v, want %v", reference, got, want)
+		}
+	}
+
+	if policy, err := ChannelConfigPolicy(config, "/Channel/Application/Admins"); err != nil || len(policy.Identities) != 1 {
+		t.Errorf("signature policy: %v, %v", policy, err)
+	}
+
+	for _, reference := range []string{"/Channel/Application/Readers", "/Channel/Orderer/Endorsement", "/Application/Endorsement"} {
+		if _, err := ChannelConfigPolicy(config, reference); err == nil {
+			t.Errorf("resolved %s", reference)
+		}
+	}
+
+	// the default endorsement policy of chaincode definitions
+	parameter := protoutil.MarshalOrPanic(&peer.ApplicationPolicy{Type: &peer.ApplicationPolicy_ChannelConfigPolicyReference{
+		ChannelConfigPolicyReference: "/Channel/Application/Endorsement"}})
+	if _, err := NewEndorsementPlanFromValidationParameter(parameter, nil, nil); err == nil {
+		t.Error("policy reference resolved without channel config")
+	}
+	plan, err := NewEndorsementPlanFromValidationParameter(parameter, config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Layouts) != 3 {
+		t.Errorf("layouts: %v", plan.Layouts)
+	}
+}
+
+func TestInvokeWithPlan(t *testing.T) {
+	l, pc, oc := newFakeLedger(t, peer.TxValidationCode_VALID)
+	close(l.release)
+	mspOpt := newTestMSPOpt(t)
+
+	l.commitConfig(t, testChannelConfig(t, "Org1MSP"))
+	l.commit("tx0")
+	config, err := FetchChannelConfig(context.Background(), mspOpt, "mychannel", pc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parameter := protoutil.MarshalOrPanic(&peer.ApplicationPolicy{Type: &peer.ApplicationPolicy_ChannelConfigPolicyReference{
+		ChannelConfigPolicyReference: "/Channel/Application/Endorsement"}})
+	plan, err := NewEndorsementPlanFromValidationParameter(parameter, config, map[string][]*client.PeerClient{"Org1MSP": {pc}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// no peers but the plan's
+	resp, err := InternalInvoke(ChainOpt{Name: "basic", Plan: plan}, mspOpt, [][]byte{[]byte("set"), []byte("a")},
+		nil, "mychannel", nil, []*client.OrdererClient{oc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp.Response.Payload) != "a" || l.height() != 3 {
+		t.Errorf("response: %v, height: %d", resp, l.height())
+	}
+}
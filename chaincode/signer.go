@@ -0,0 +1,86 @@
+package chaincode
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/hyperledger/fabric/msp"
+)
+
+//GetSignerFromPEM get a x509 signer from pem bytes, without any msp directory
+// certPEM signer certificate
+// keyPEM signer private key
+// caCerts root certificates, a certificate which is not self-signed is used as intermediate certificate
+func GetSignerFromPEM(mspID string, certPEM, keyPEM []byte, caCerts ...[]byte) (msp.SigningIdentity, error) {
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, fmt.Errorf("decode private key pem failed")
+	}
+
+	csp, err := sw.NewDefaultSecurityLevelWithKeystore(client.NewMemKeyStore())
+	if err != nil {
+		return nil, fmt.Errorf("create new bccsp failed: %v", err)
+	}
+
+	// not temporary, then the key can be found by the msp with the ski of the certificate
+	_, err = csp.KeyImport(keyBlock.Bytes, &bccsp.ECDSAPrivateKeyImportOpts{Temporary: false})
+	if err != nil {
+		return nil, fmt.Errorf("import private key failed: %v", err)
+	}
+
+	rootCerts, intermediateCerts, err := SplitCAChain(caCerts...)
+	if err != nil {
+		return nil, err
+	}
+
+	mspConfig, err := createSigner(mspID, [][]byte{certPEM}, rootCerts, nil, intermediateCerts, nil, nil, nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create msp config failed: %v", err)
+	}
+
+	amsp, err := client.NewSignerMSP(csp)
+	if err != nil {
+		return nil, fmt.Errorf("create new msp failed: %v", err)
+	}
+
+	err = amsp.Setup(mspConfig)
+	if err != nil {
+		return nil, fmt.Errorf("setup msp failed: %v", err)
+	}
+
+	return amsp.GetDefaultSigningIdentity()
+}
+
+//SplitCAChain split pem ca certificates to root certificates and intermediate certificates,
+// a certificate which is not self-signed is a intermediate certificate
+func SplitCAChain(caCerts ...[]byte) (roots, intermediates [][]byte, err error) {
+	for i := range caCerts {
+		rest := caCerts[i]
+		for {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("parse ca certificate failed: %v", err)
+			}
+
+			if cert.CheckSignatureFrom(cert) == nil {
+				roots = append(roots, pem.EncodeToMemory(block))
+			} else {
+				intermediates = append(intermediates, pem.EncodeToMemory(block))
+			}
+		}
+	}
+	return roots, intermediates, nil
+}
@@ -0,0 +1,167 @@
+package chaincode
+
+import (
+	"fmt"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/ledger/rwset"
+	"github.com/hyperledger/fabric-protos-go/ledger/rwset/kvrwset"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/protoutil"
+)
+
+//Simulation what a transaction would change, decoded from a proposal response, the transaction is not submitted
+type Simulation struct {
+	TxID        string
+	Response    *peer.ProposalResponse
+	ChaincodeID *peer.ChaincodeID
+	// Result payload returned by the chaincode
+	Result []byte
+	// Event chaincode event set by the chaincode, nil if no event
+	Event      *peer.ChaincodeEvent
+	Namespaces []*NamespaceRWSet
+}
+
+//NamespaceRWSet reads and writes of a namespace (chaincode), keys of private data are hashed
+type NamespaceRWSet struct {
+	Namespace      string
+	Reads          []*kvrwset.KVRead
+	RangeQueries   []*kvrwset.RangeQueryInfo
+	Writes         []*kvrwset.KVWrite
+	MetadataWrites []*kvrwset.KVMetadataWrite
+	Collections    []*CollectionRWSet
+}
+
+//CollectionRWSet hashed reads and writes of a private data collection
+type CollectionRWSet struct {
+	Collection     string
+	HashedReads    []*kvrwset.KVReadHash
+	HashedWrites   []*kvrwset.KVWriteHash
+	MetadataWrites []*kvrwset.KVMetadataWriteHash
+	// PvtRwSetHash hash of the private read write set
+	PvtRwSetHash []byte
+	// Private the private read write set, only for blocks delivered with private data, see BlockTransactions
+	Private *kvrwset.KVRWSet
+}
+
+//Namespace rwset of namespace, nil if not touched
+func (s *Simulation) Namespace(namespace string) *NamespaceRWSet {
+	for _, ns := range s.Namespaces {
+		if ns.Namespace == namespace {
+			return ns
+		}
+	}
+	return nil
+}
+
+//Simulate endorse the proposal on peers without sending it to orderers
+func Simulate(chaincode ChainOpt, mspOpt MSPOpt, args [][]byte,
+	privateData map[string][]byte, channelID string, peers []Endpoint) (*Simulation, error) {
+	peerClients := GetPeerClients(peers)
+	if len(peerClients) == 0 {
+		return nil, fmt.Errorf("peer clients' number is 0")
+	}
+	defer CloseClients(peerClients)
+
+	return InternalSimulate(chaincode, mspOpt, args, privateData, channelID, peerClients)
+}
+
+//InternalSimulate endorse the proposal on peers without sending it to orderers,
+// a EndorseError is returned if any peer fails
+func InternalSimulate(chaincode ChainOpt, mspOpt MSPOpt, args [][]byte,
+	privateData map[string][]byte, channelID string, peers []*client.PeerClient) (*Simulation, error) {
+	signer, err := GetSignerByOpt(mspOpt)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, _, err := endorseOnPeers(chaincode, signer, args, privateData, channelID, nil, peers)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := DecodeSimulation(tx.response)
+	if err != nil {
+		return nil, err
+	}
+	s.TxID = tx.txID
+	return s, nil
+}
+
+//DecodeSimulation decode ProposalResponsePayload -> ChaincodeAction -> TxReadWriteSet of resp
+func DecodeSimulation(resp *peer.ProposalResponse) (*Simulation, error) {
+	if resp == nil || resp.Response == nil {
+		return nil, fmt.Errorf("no response to decode")
+	}
+
+	prp, err := protoutil.UnmarshalProposalResponsePayload(resp.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal proposal response payload failed: %v", err)
+	}
+
+	action, err := protoutil.UnmarshalChaincodeAction(prp.Extension)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal chaincode action failed: %v", err)
+	}
+
+	s := &Simulation{Response: resp, ChaincodeID: action.ChaincodeId, Result: resp.Response.Payload}
+
+	if len(action.Events) > 0 {
+		if s.Event, err = protoutil.UnmarshalChaincodeEvents(action.Events); err != nil {
+			return nil, fmt.Errorf("unmarshal chaincode event failed: %v", err)
+		}
+	}
+
+	if len(action.Results) == 0 {
+		return s, nil
+	}
+
+	txRWSet := &rwset.TxReadWriteSet{}
+	if err = proto.Unmarshal(action.Results, txRWSet); err != nil {
+		return nil, fmt.Errorf("unmarshal tx read write set failed: %v", err)
+	}
+
+	for _, nsRWSet := range txRWSet.NsRwset {
+		ns, err := decodeNamespaceRWSet(nsRWSet)
+		if err != nil {
+			return nil, err
+		}
+		s.Namespaces = append(s.Namespaces, ns)
+	}
+
+	return s, nil
+}
+
+func decodeNamespaceRWSet(nsRWSet *rwset.NsReadWriteSet) (*NamespaceRWSet, error) {
+	kv := &kvrwset.KVRWSet{}
+	if err := proto.Unmarshal(nsRWSet.Rwset, kv); err != nil {
+		return nil, fmt.Errorf("unmarshal read write set of namespace [%s] failed: %v", nsRWSet.Namespace, err)
+	}
+
+	ns := &NamespaceRWSet{
+		Namespace:      nsRWSet.Namespace,
+		Reads:          kv.Reads,
+		RangeQueries:   kv.RangeQueriesInfo,
+		Writes:         kv.Writes,
+		MetadataWrites: kv.MetadataWrites,
+	}
+
+	for _, collRWSet := range nsRWSet.CollectionHashedRwset {
+		hashed := &kvrwset.HashedRWSet{}
+		if err := proto.Unmarshal(collRWSet.HashedRwset, hashed); err != nil {
+			return nil, fmt.Errorf("unmarshal hashed read write set of collection [%s/%s] failed: %v",
+				nsRWSet.Namespace, collRWSet.CollectionName, err)
+		}
+
+		ns.Collections = append(ns.Collections, &CollectionRWSet{
+			Collection:     collRWSet.CollectionName,
+			HashedReads:    hashed.HashedReads,
+			HashedWrites:   hashed.HashedWrites,
+			MetadataWrites: hashed.MetadataWrites,
+			PvtRwSetHash:   collRWSet.PvtRwsetHash,
+		})
+	}
+
+	return ns, nil
+}
@@ -0,0 +1,62 @@
+package chaincode
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+func TestInternalSimulate(t *testing.T) {
+	l, pc, _ := newFakeLedger(t, peer.TxValidationCode_VALID)
+	mspOpt := newTestMSPOpt(t)
+
+	s, err := InternalSimulate(ChainOpt{Name: "basic"}, mspOpt, [][]byte{[]byte("put"), []byte("a"), []byte("1")},
+		nil, "mychannel", []*client.PeerClient{pc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.height() != 0 {
+		t.Errorf("simulation is submitted")
+	}
+
+	if s.TxID == "" || string(s.Result) != "1" || s.ChaincodeID.Name != "basic" {
+		t.Errorf("simulation: %+v", s)
+	}
+	if s.Event == nil || s.Event.EventName != "put" || string(s.Event.Payload) != "1" {
+		t.Errorf("event: %v", s.Event)
+	}
+
+	ns := s.Namespace("basic")
+	if ns == nil || s.Namespace("other") != nil {
+		t.Fatalf("namespaces: %v", s.Namespaces)
+	}
+	if len(ns.Reads) != 1 || ns.Reads[0].Key != "a" || ns.Reads[0].Version.BlockNum != 1 {
+		t.Errorf("reads: %v", ns.Reads)
+	}
+	if len(ns.Writes) != 1 || ns.Writes[0].Key != "a" || string(ns.Writes[0].Value) != "1" {
+		t.Errorf("writes: %v", ns.Writes)
+	}
+	if len(ns.Collections) != 1 || ns.Collections[0].Collection != "private" ||
+		len(ns.Collections[0].HashedWrites) != 1 || string(ns.Collections[0].HashedWrites[0].KeyHash) != "a" {
+		t.Errorf("collections: %v", ns.Collections)
+	}
+
+	// no read write set
+	s, err = InternalSimulate(ChainOpt{Name: "basic"}, mspOpt, [][]byte{[]byte("get"), []byte("a")},
+		nil, "mychannel", []*client.PeerClient{pc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Namespaces) != 0 || s.Event != nil {
+		t.Errorf("simulation: %+v", s)
+	}
+
+	_, err = InternalSimulate(ChainOpt{Name: "basic"}, mspOpt, [][]byte{[]byte("fail")},
+		nil, "mychannel", []*client.PeerClient{pc})
+	var e *EndorseError
+	if !errors.As(err, &e) {
+		t.Errorf("err: %v", err)
+	}
+}
@@ -0,0 +1,430 @@
+package chaincode
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/orderer"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/msp"
+)
+
+//DefaultSubmitConcurrency transactions a Submitter keeps in flight by default
+const DefaultSubmitConcurrency = 100
+
+// reconnectDelay delay before reconnecting a interrupted deliver stream
+const reconnectDelay = time.Second
+
+//Submitter submit transactions of a channel through long-lived streams,
+// one broadcast stream per orderer and one filtered deliver stream per commit peer are shared by all transactions,
+// Submit is safe for concurrent use, transactions of concurrent calls are pipelined on the streams
+type Submitter struct {
+	signer      msp.SigningIdentity
+	channelID   string
+	endorsers   []*client.PeerClient
+	orderers    []*ordererStream
+	commitPeers []*client.PeerClient
+	concurrency int
+	timeout     time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	// slots one for every transaction in flight, Submit blocks while it is full
+	slots chan struct{}
+	// next orderer to broadcast to, round robin
+	next uint32
+
+	mutex   sync.Mutex
+	pending map[string]*pendingCommit
+}
+
+type pendingCommit struct {
+	commit *Commit
+	timer  *time.Timer
+}
+
+//WithConcurrency max transactions in flight, from endorsing to committed,
+// Submit blocks until a transaction in flight is resolved, 0 means DefaultSubmitConcurrency
+func WithConcurrency(n int) func(*Submitter) {
+	return func(s *Submitter) {
+		s.concurrency = n
+	}
+}
+
+//WithCommitTimeout timeout of a transaction from being accepted by the orderer to committed,
+// the timer starts once the orderer accepted it, time of endorsing and broadcasting is bounded by ctx of Submit,
+// 0 means DefaultCommitTimeout
+func WithCommitTimeout(timeout time.Duration) func(*Submitter) {
+	return func(s *Submitter) {
+		s.timeout = timeout
+	}
+}
+
+//WithCommitPeers peers which deliver streams notify commits, default is the endorsing peers
+func WithCommitPeers(peers ...*client.PeerClient) func(*Submitter) {
+	return func(s *Submitter) {
+		s.commitPeers = peers
+	}
+}
+
+//NewSubmitter connect deliver streams of commit peers, broadcast streams are connected at the first sending,
+// transactions are endorsed by all of peers, clients are not closed by Submitter.Close
+func NewSubmitter(mspOpt MSPOpt, channelID string, peers []*client.PeerClient, orderers []*client.OrdererClient,
+	opts ...func(*Submitter)) (*Submitter, error) {
+	if len(peers) == 0 {
+		return nil, fmt.Errorf("peer clients' number is 0")
+	}
+	if len(orderers) == 0 {
+		return nil, fmt.Errorf("orderer clients' number is 0")
+	}
+
+	signer, err := GetSignerByOpt(mspOpt)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Submitter{
+		signer:      signer,
+		channelID:   channelID,
+		endorsers:   peers,
+		commitPeers: peers,
+		pending:     map[string]*pendingCommit{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.concurrency <= 0 {
+		s.concurrency = DefaultSubmitConcurrency
+	}
+	if s.timeout <= 0 {
+		s.timeout = DefaultCommitTimeout
+	}
+	if len(s.commitPeers) == 0 {
+		return nil, fmt.Errorf("no commit peer")
+	}
+
+	s.slots = make(chan struct{}, s.concurrency)
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	for _, o := range orderers {
+		s.orderers = append(s.orderers, &ordererStream{client: o})
+	}
+
+	// connect before any transaction is sent, no commit is missed
+	connected := 0
+	var lastErr error
+	for _, p := range s.commitPeers {
+		stream, err := connectFilteredDeliver(s.ctx, p, s.signer, s.channelID)
+		if err != nil {
+			lastErr = fmt.Errorf("peer [%s]: %v", p.Address(), err)
+			log.Printf("connect to deliver of commit peer failed, retry in background: %v", lastErr)
+		} else {
+			connected++
+		}
+		go s.listen(p, stream)
+	}
+
+	if connected == 0 {
+		s.cancel()
+		return nil, fmt.Errorf("connect to deliver of commit peers failed: %v", lastErr)
+	}
+
+	return s, nil
+}
+
+//Submit endorse and send the transaction, return once the orderer accepted it,
+// the commit status is resolved by the first commit peer delivering the transaction, or timeout,
+// block if concurrency transactions are in flight until one of them is resolved or ctx is done
+func (s *Submitter) Submit(ctx context.Context, chaincode ChainOpt, args [][]byte,
+	privateData map[string][]byte) (*Commit, error) {
+	select {
+	case s.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-s.ctx.Done():
+		return nil, fmt.Errorf("submitter is closed")
+	}
+
+	tx, _, err := endorseOnPeers(chaincode, s.signer, args, privateData, s.channelID, nil, s.endorsers)
+	if err != nil {
+		<-s.slots
+		return nil, err
+	}
+
+	c := &Commit{txID: tx.txID, response: tx.response, tx: tx, done: make(chan struct{})}
+	if err = s.register(c); err != nil {
+		<-s.slots
+		return nil, err
+	}
+
+	if err = s.broadcast(ctx, tx.txID, tx.env); err != nil {
+		s.resolve(tx.txID, nil, err)
+		return nil, err
+	}
+
+	s.startTimer(tx.txID)
+	return c, nil
+}
+
+//InFlight transactions sent but not resolved yet
+func (s *Submitter) InFlight() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.pending)
+}
+
+//Close close the streams, transactions in flight are resolved with error
+func (s *Submitter) Close() {
+	s.cancel()
+
+	s.mutex.Lock()
+	var txIDs []string
+	for txID := range s.pending {
+		txIDs = append(txIDs, txID)
+	}
+	s.mutex.Unlock()
+
+	for _, txID := range txIDs {
+		s.resolve(txID, nil, fmt.Errorf("submitter is closed before transaction [%s] committed", txID))
+	}
+}
+
+func (s *Submitter) register(c *Commit) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.ctx.Err() != nil {
+		return fmt.Errorf("submitter is closed")
+	}
+
+	s.pending[c.txID] = &pendingCommit{commit: c}
+	return nil
+}
+
+// startTimer start the commit timeout of a accepted transaction, unless it is resolved already
+func (s *Submitter) startTimer(txID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	p, ok := s.pending[txID]
+	if !ok {
+		return
+	}
+
+	p.timer = time.AfterFunc(s.timeout, func() {
+		s.resolve(txID, nil, &TimeoutError{TxID: txID, Stage: StageCommit})
+	})
+}
+
+// resolve the pending commit once, release its slot
+func (s *Submitter) resolve(txID string, status *CommitStatus, err error) {
+	s.mutex.Lock()
+	p, ok := s.pending[txID]
+	delete(s.pending, txID)
+	s.mutex.Unlock()
+
+	if !ok {
+		return
+	}
+
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+	p.commit.status, p.commit.err = status, err
+	close(p.commit.done)
+	<-s.slots
+}
+
+// broadcast send to orderers one by one until one of them accepts it, starting from the next one
+func (s *Submitter) broadcast(ctx context.Context, txID string, env *common.Envelope) error {
+	var failures []OrdererFailure
+	start := int(atomic.AddUint32(&s.next, 1))
+	for i := range s.orderers {
+		o := s.orderers[(start+i)%len(s.orderers)]
+
+		accepted, err := o.send(s.ctx, env)
+		if err != nil {
+			log.Printf("send to orderer [%s] failed: %v", o.client.Address(), err)
+			failures = append(failures, OrdererFailure{Address: o.client.Address(), Err: err})
+			continue
+		}
+
+		var failure *OrdererFailure
+		select {
+		case failure = <-accepted:
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return &TimeoutError{TxID: txID, Stage: StageBroadcast}
+			}
+			return ctx.Err()
+		case <-s.ctx.Done():
+			return fmt.Errorf("submitter is closed")
+		}
+
+		if failure != nil {
+			log.Printf("orderer rejected the transaction: %v", failure)
+			failures = append(failures, *failure)
+			continue
+		}
+		return nil
+	}
+
+	return &BroadcastError{TxID: txID, Failures: failures}
+}
+
+// listen resolve commits from the deliver stream of pc, reconnect from the next block once interrupted
+func (s *Submitter) listen(pc *client.PeerClient, stream peer.Deliver_DeliverFilteredClient) {
+	// next block to seek from once reconnected, nil means the newest
+	var next *orderer.SeekPosition
+	for {
+		var err error
+		if stream == nil {
+			stream, err = connectFilteredDeliverFrom(s.ctx, pc, s.signer, s.channelID, next)
+		}
+		if err == nil {
+			err = s.recvCommits(stream, &next)
+		}
+		stream = nil
+
+		if s.ctx.Err() != nil {
+			return
+		}
+		log.Printf("deliver of commit peer [%s] is interrupted, reconnect: %v", pc.Address(), err)
+
+		select {
+		case <-time.After(reconnectDelay):
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Submitter) recvCommits(stream peer.Deliver_DeliverFilteredClient, next **orderer.SeekPosition) error {
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return fmt.Errorf("receive from deliver filtered failed: %v", err)
+		}
+
+		switch r := resp.Type.(type) {
+		case *peer.DeliverResponse_FilteredBlock:
+			block := r.FilteredBlock
+			*next = SeekBlock(block.Number + 1)
+
+			for i, tx := range block.FilteredTransactions {
+				s.resolve(tx.Txid, &CommitStatus{
+					TxID: tx.Txid, Code: tx.TxValidationCode, BlockNumber: block.Number, TxIndex: uint64(i)}, nil)
+			}
+		case *peer.DeliverResponse_Status:
+			return fmt.Errorf("deliver completed with status (%s)", r.Status)
+		default:
+			return fmt.Errorf("received unexpected response type (%T)", r)
+		}
+	}
+}
+
+// ordererStream long-lived broadcast stream of a orderer, reconnected at the next sending once broken
+type ordererStream struct {
+	client *client.OrdererClient
+
+	mutex   sync.Mutex
+	current *broadcastStream
+}
+
+// broadcastStream the orderer responds in the order of sending, waiting is the fifo of senders,
+// sendMutex serializes sending, waiting is guarded by the mutex of ordererStream
+type broadcastStream struct {
+	stream    orderer.AtomicBroadcast_BroadcastClient
+	sendMutex sync.Mutex
+	waiting   []chan *OrdererFailure
+}
+
+// send pipeline env on the stream, the returned chan receives nil once env is accepted
+func (o *ordererStream) send(ctx context.Context, env *common.Envelope) (<-chan *OrdererFailure, error) {
+	b, err := o.stream(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	accepted := make(chan *OrdererFailure, 1)
+
+	// the sender is queued before sending, the response may be received before Send returns
+	b.sendMutex.Lock()
+	o.mutex.Lock()
+	b.waiting = append(b.waiting, accepted)
+	o.mutex.Unlock()
+	err = b.stream.Send(env)
+	b.sendMutex.Unlock()
+
+	if err != nil {
+		// the stream is broken, waiting senders are failed by recv
+		o.mutex.Lock()
+		if o.current == b {
+			o.current = nil
+		}
+		o.mutex.Unlock()
+		_ = b.stream.CloseSend()
+		return nil, fmt.Errorf("send envelope failed: %v", err)
+	}
+
+	return accepted, nil
+}
+
+// stream the current broadcast stream, connect a new one if there is none
+func (o *ordererStream) stream(ctx context.Context) (*broadcastStream, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.current == nil {
+		stream, err := o.client.BroadcastWithContext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get broadcast from orderer client failed: %v", err)
+		}
+		o.current = &broadcastStream{stream: stream}
+		go o.recv(o.current)
+	}
+
+	return o.current, nil
+}
+
+func (o *ordererStream) recv(b *broadcastStream) {
+	for {
+		resp, err := b.stream.Recv()
+
+		o.mutex.Lock()
+		if err != nil {
+			if o.current == b {
+				o.current = nil
+			}
+			waiting := b.waiting
+			b.waiting = nil
+			o.mutex.Unlock()
+
+			for _, w := range waiting {
+				w <- &OrdererFailure{Address: o.client.Address(), Err: fmt.Errorf("receive broadcast response failed: %v", err)}
+			}
+			return
+		}
+
+		if len(b.waiting) == 0 {
+			o.mutex.Unlock()
+			log.Printf("received unexpected broadcast response: %s", resp.Status)
+			continue
+		}
+		w := b.waiting[0]
+		b.waiting = b.waiting[1:]
+		o.mutex.Unlock()
+
+		if resp.Status != common.Status_SUCCESS {
+			w <- &OrdererFailure{Address: o.client.Address(), Status: resp.Status, Info: resp.Info}
+		} else {
+			w <- nil
+		}
+	}
+}
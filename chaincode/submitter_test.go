@@ -0,0 +1,162 @@
+package chaincode
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+func TestSubmitter(t *testing.T) {
+	l, pc, oc := newFakeLedger(t, peer.TxValidationCode_VALID)
+	close(l.release)
+
+	s, err := NewSubmitter(newTestMSPOpt(t), "mychannel", []*client.PeerClient{pc}, []*client.OrdererClient{oc},
+		WithConcurrency(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	const n = 50
+	var wg sync.WaitGroup
+	blocks := make(chan uint64, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			commit, err := s.Submit(context.Background(), ChainOpt{Name: "basic"}, [][]byte{[]byte("set"), []byte("a")}, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			status, err := commit.Status(context.Background())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if !status.Successful() || status.TxID != commit.TxID() {
+				t.Errorf("status: %+v", status)
+			}
+			blocks <- status.BlockNumber
+		}()
+	}
+	wg.Wait()
+	close(blocks)
+
+	seen := map[uint64]bool{}
+	for b := range blocks {
+		seen[b] = true
+	}
+	if len(seen) != n || l.height() != n {
+		t.Errorf("committed blocks: %d, height: %d, want %d", len(seen), l.height(), n)
+	}
+	if atomic.LoadInt32(&l.broadcasts) != 1 {
+		t.Errorf("broadcast streams: %d, want 1", atomic.LoadInt32(&l.broadcasts))
+	}
+	if s.InFlight() != 0 {
+		t.Errorf("in flight: %d", s.InFlight())
+	}
+
+	if _, err = s.Submit(context.Background(), ChainOpt{Name: "basic"}, [][]byte{[]byte("fail")}, nil); err == nil {
+		t.Error("failed endorsement is submitted")
+	}
+}
+
+func TestSubmitterBackPressure(t *testing.T) {
+	l, pc, oc := newFakeLedger(t, peer.TxValidationCode_VALID)
+
+	s, err := NewSubmitter(newTestMSPOpt(t), "mychannel", []*client.PeerClient{pc}, []*client.OrdererClient{oc},
+		WithConcurrency(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var commits []*Commit
+	for i := 0; i < 2; i++ {
+		commit, err := s.Submit(context.Background(), ChainOpt{Name: "basic"}, [][]byte{[]byte("set"), []byte("a")}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		commits = append(commits, commit)
+	}
+
+	// blocks are held, no slot is released
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err = s.Submit(ctx, ChainOpt{Name: "basic"}, [][]byte{[]byte("set"), []byte("a")}, nil); err != context.DeadlineExceeded {
+		t.Fatalf("submit over concurrency: %v", err)
+	}
+
+	close(l.release)
+	for _, c := range commits {
+		if _, err = c.Status(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err = s.Submit(context.Background(), ChainOpt{Name: "basic"}, [][]byte{[]byte("set"), []byte("a")}, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSubmitterTimeout(t *testing.T) {
+	_, pc, oc := newFakeLedger(t, peer.TxValidationCode_VALID)
+
+	s, err := NewSubmitter(newTestMSPOpt(t), "mychannel", []*client.PeerClient{pc}, []*client.OrdererClient{oc},
+		WithCommitTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commit, err := s.Submit(context.Background(), ChainOpt{Name: "basic"}, [][]byte{[]byte("set"), []byte("a")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = commit.Status(context.Background()); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("status: %v", err)
+	}
+
+	commit, err = s.Submit(context.Background(), ChainOpt{Name: "basic"}, [][]byte{[]byte("set"), []byte("b")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	if _, err = commit.Status(context.Background()); err == nil || !strings.Contains(err.Error(), "closed") {
+		t.Errorf("status after closed: %v", err)
+	}
+}
+
+func TestSubmitterTimeoutAfterAccepted(t *testing.T) {
+	l, pc, oc := newFakeLedger(t, peer.TxValidationCode_VALID)
+	close(l.release)
+	// the orderer accepts slower than the commit timeout, which starts once it is accepted
+	l.acceptDelay = 150 * time.Millisecond
+
+	s, err := NewSubmitter(newTestMSPOpt(t), "mychannel", []*client.PeerClient{pc}, []*client.OrdererClient{oc},
+		WithCommitTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	commit, err := s.Submit(context.Background(), ChainOpt{Name: "basic"}, [][]byte{[]byte("set"), []byte("a")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err := commit.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Successful() {
+		t.Errorf("status: %+v", status)
+	}
+}
@@ -0,0 +1,111 @@
+package chaincode
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+//ValidateProposalResponses check proposal responses before they are sent to orderers,
+// every response must be successful and signed by its endorser,
+// payloads (proposal hash, read write set, events and response) of all responses must be the same
+// peers address of the peer which returned the response, same order as responses, only used in errors
+func ValidateProposalResponses(responses []*peer.ProposalResponse, peers []string) error {
+	if len(responses) == 0 {
+		return fmt.Errorf("no proposal response")
+	}
+
+	name := func(i int) string {
+		if i < len(peers) && peers[i] != "" {
+			return peers[i]
+		}
+		return fmt.Sprintf("#%d", i)
+	}
+
+	for i, resp := range responses {
+		if resp.Response == nil {
+			return fmt.Errorf("proposal response of peer [%s] has no response", name(i))
+		}
+
+		if resp.Response.Status >= shim.ERRORTHRESHOLD {
+			return &EndorseError{Failures: []PeerFailure{{
+				Address: name(i), Status: resp.Response.Status, Message: resp.Response.Message}}}
+		}
+
+		if err := VerifyEndorsement(resp); err != nil {
+			return fmt.Errorf("verify endorsement of peer [%s] failed: %v", name(i), err)
+		}
+	}
+
+	// group peers by payload, more than one group means endorsers disagree
+	var payloads [][]byte
+	var groups [][]string
+	for i, resp := range responses {
+		found := false
+		for j := range payloads {
+			if bytes.Equal(payloads[j], resp.Payload) {
+				groups[j] = append(groups[j], name(i))
+				found = true
+				break
+			}
+		}
+		if !found {
+			payloads = append(payloads, resp.Payload)
+			groups = append(groups, []string{name(i)})
+		}
+	}
+
+	if len(groups) > 1 {
+		var s []string
+		for _, g := range groups {
+			s = append(s, "["+strings.Join(g, ", ")+"]")
+		}
+		return fmt.Errorf("proposal response payloads do not match: %s", strings.Join(s, " != "))
+	}
+
+	return nil
+}
+
+//VerifyEndorsement verify the signature of the endorsement over payload and endorser,
+// only x509 (ecdsa) endorsers are supported
+func VerifyEndorsement(resp *peer.ProposalResponse) error {
+	if resp.Endorsement == nil {
+		return fmt.Errorf("no endorsement")
+	}
+
+	sid := &mb.SerializedIdentity{}
+	if err := proto.Unmarshal(resp.Endorsement.Endorser, sid); err != nil {
+		return fmt.Errorf("unmarshal endorser failed: %v", err)
+	}
+
+	block, _ := pem.Decode(sid.IdBytes)
+	if block == nil {
+		return fmt.Errorf("endorser of [%s] is not a pem certificate", sid.Mspid)
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("parse endorser certificate failed: %v", err)
+	}
+
+	pub, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("unsupported endorser public key: %T", cert.PublicKey)
+	}
+
+	digest := sha256.Sum256(append(append([]byte{}, resp.Payload...), resp.Endorsement.Endorser...))
+	if !ecdsa.VerifyASN1(pub, digest[:], resp.Endorsement.Signature) {
+		return fmt.Errorf("invalid signature of endorser [%s]", cert.Subject.CommonName)
+	}
+
+	return nil
+}
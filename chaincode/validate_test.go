@@ -0,0 +1,69 @@
+package chaincode
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Asutorufa/fabricsdk/internal/testutil"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/msp"
+	"github.com/hyperledger/fabric/protoutil"
+)
+
+func TestValidateProposalResponses(t *testing.T) {
+	client := testutil.NewSigner(t, "Org1MSP", "User1@org1.example.com")
+	creator, err := client.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prop, _, err := protoutil.CreateChaincodeProposal(common.HeaderType_ENDORSER_TRANSACTION, "mychannel",
+		&peer.ChaincodeInvocationSpec{ChaincodeSpec: &peer.ChaincodeSpec{
+			ChaincodeId: &peer.ChaincodeID{Name: "basic"},
+			Input:       &peer.ChaincodeInput{Args: [][]byte{[]byte("set")}},
+		}}, creator)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endorse := func(endorser msp.SigningIdentity, payload string, status int32) *peer.ProposalResponse {
+		response := &peer.Response{Status: status, Payload: []byte(payload)}
+		resp, err := protoutil.CreateProposalResponse(prop.Header, prop.Payload, response, []byte(payload), nil,
+			&peer.ChaincodeID{Name: "basic"}, endorser)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Response = response
+		return resp
+	}
+
+	org1 := testutil.NewSigner(t, "Org1MSP", "peer0.org1.example.com")
+	org2 := testutil.NewSigner(t, "Org2MSP", "peer0.org2.example.com")
+	org3 := testutil.NewSigner(t, "Org3MSP", "peer0.org3.example.com")
+	peers := []string{"peer0.org1:7051", "peer0.org2:9051", "peer0.org3:11051"}
+
+	if err = ValidateProposalResponses([]*peer.ProposalResponse{
+		endorse(org1, "a", 200), endorse(org2, "a", 200), endorse(org3, "a", 200)}, peers); err != nil {
+		t.Errorf("consistent responses: %v", err)
+	}
+
+	err = ValidateProposalResponses([]*peer.ProposalResponse{
+		endorse(org1, "a", 200), endorse(org2, "b", 200), endorse(org3, "a", 200)}, peers)
+	if err == nil || !strings.Contains(err.Error(), "[peer0.org1:7051, peer0.org3:11051] != [peer0.org2:9051]") {
+		t.Errorf("mismatched responses: %v", err)
+	}
+
+	err = ValidateProposalResponses([]*peer.ProposalResponse{
+		endorse(org1, "a", 200), endorse(org2, "a", 500)}, peers)
+	if err == nil || !strings.Contains(err.Error(), "peer0.org2:9051") {
+		t.Errorf("failed response: %v", err)
+	}
+
+	tampered := endorse(org2, "a", 200)
+	tampered.Endorsement.Signature = endorse(org1, "a", 200).Endorsement.Signature
+	err = ValidateProposalResponses([]*peer.ProposalResponse{endorse(org1, "a", 200), tampered}, peers)
+	if err == nil || !strings.Contains(err.Error(), "verify endorsement of peer [peer0.org2:9051]") {
+		t.Errorf("tampered signature: %v", err)
+	}
+}
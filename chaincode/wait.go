@@ -0,0 +1,356 @@
+package chaincode
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/orderer"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/msp"
+)
+
+//DefaultCommitTimeout timeout of wait strategies with zero timeout
+const DefaultCommitTimeout = time.Minute
+
+//CommitWaitGroup wait for Required valid commits from Peers
+type CommitWaitGroup struct {
+	Peers    []*client.PeerClient
+	Required int
+}
+
+//WaitStrategy how to wait for a transaction to be committed,
+// the transaction is committed once every group got enough valid commits
+type WaitStrategy interface {
+	// Groups peers to wait on, endorsers are peers the transaction is endorsed by,
+	// endorsers is empty for transactions without endorsement, eg: channel config update
+	Groups(endorsers []*client.PeerClient) ([]CommitWaitGroup, error)
+	// Timeout of the waiting, 0 means DefaultCommitTimeout
+	Timeout() time.Duration
+}
+
+type waitStrategy struct {
+	timeout time.Duration
+	groups  func(endorsers []*client.PeerClient) ([]CommitWaitGroup, error)
+}
+
+func (w *waitStrategy) Groups(endorsers []*client.PeerClient) ([]CommitWaitGroup, error) {
+	return w.groups(endorsers)
+}
+
+func (w *waitStrategy) Timeout() time.Duration { return w.timeout }
+
+//NoWait return once the transaction is sent to the orderer
+func NoWait() WaitStrategy {
+	return &waitStrategy{groups: func([]*client.PeerClient) ([]CommitWaitGroup, error) { return nil, nil }}
+}
+
+//WaitForAny wait until the transaction is committed on any endorsing peer
+func WaitForAny(timeout time.Duration) WaitStrategy {
+	return &waitStrategy{timeout: timeout, groups: func(endorsers []*client.PeerClient) ([]CommitWaitGroup, error) {
+		if len(endorsers) == 0 {
+			return nil, fmt.Errorf("no endorsing peer to wait for")
+		}
+		return []CommitWaitGroup{{Peers: endorsers, Required: 1}}, nil
+	}}
+}
+
+//WaitForAll wait until the transaction is committed on all endorsing peers
+func WaitForAll(timeout time.Duration) WaitStrategy {
+	return &waitStrategy{timeout: timeout, groups: func(endorsers []*client.PeerClient) ([]CommitWaitGroup, error) {
+		if len(endorsers) == 0 {
+			return nil, fmt.Errorf("no endorsing peer to wait for")
+		}
+		return []CommitWaitGroup{{Peers: endorsers, Required: len(endorsers)}}, nil
+	}}
+}
+
+//WaitForNPerOrg wait until the transaction is committed on n peers of every org,
+// peersByOrg org -> peers, orgs with less than n peers need all of their peers
+func WaitForNPerOrg(timeout time.Duration, n int, peersByOrg map[string][]*client.PeerClient) WaitStrategy {
+	return &waitStrategy{timeout: timeout, groups: func([]*client.PeerClient) ([]CommitWaitGroup, error) {
+		var groups []CommitWaitGroup
+		for org, peers := range peersByOrg {
+			if len(peers) == 0 {
+				return nil, fmt.Errorf("no peer of org [%s] to wait for", org)
+			}
+
+			required := n
+			if required > len(peers) {
+				required = len(peers)
+			}
+			groups = append(groups, CommitWaitGroup{Peers: peers, Required: required})
+		}
+		return groups, nil
+	}}
+}
+
+//WaitForPeers wait until the transaction is committed on all of the peers, endorsing or not
+func WaitForPeers(timeout time.Duration, peers ...*client.PeerClient) WaitStrategy {
+	return &waitStrategy{timeout: timeout, groups: func([]*client.PeerClient) ([]CommitWaitGroup, error) {
+		if len(peers) == 0 {
+			return nil, fmt.Errorf("no peer to wait for")
+		}
+		return []CommitWaitGroup{{Peers: peers, Required: len(peers)}}, nil
+	}}
+}
+
+//CommitStatus the transaction is committed in block BlockNumber with validation code Code
+type CommitStatus struct {
+	TxID        string
+	Code        peer.TxValidationCode
+	BlockNumber uint64
+	// TxIndex position of the transaction in the block
+	TxIndex uint64
+}
+
+//Successful the transaction is committed as valid
+func (s *CommitStatus) Successful() bool {
+	return s.Code == peer.TxValidationCode_VALID
+}
+
+// commitResult result of one peer, status is nil if err is not nil
+type commitResult struct {
+	peer   *client.PeerClient
+	status *CommitStatus
+	err    error
+}
+
+//CommitWaiter deliver connections created before the transaction is sent,
+// so no block is missed between sending and waiting
+type CommitWaiter struct {
+	groups []CommitWaitGroup
+	txID   string
+	// config wait for the next config block instead of txID,
+	// the orderer wraps config updates into new config transactions without txid
+	config  bool
+	ctx     context.Context
+	cancel  context.CancelFunc
+	results chan commitResult
+	// started peers which results will be sent to results
+	started int
+}
+
+//NewCommitWaiter connect to deliver service of peers chosen by strategy, call it before sending the transaction,
+// nil strategy means NoWait
+func NewCommitWaiter(strategy WaitStrategy, signer msp.SigningIdentity, channelID, txID string,
+	endorsers []*client.PeerClient) (*CommitWaiter, error) {
+	return newCommitWaiter(strategy, signer, channelID, txID, false, endorsers)
+}
+
+//NewConfigCommitWaiter wait for the next config block of the channel, for channel config updates,
+// strategy must not depend on endorsing peers, eg: WaitForPeers
+func NewConfigCommitWaiter(strategy WaitStrategy, signer msp.SigningIdentity, channelID string) (*CommitWaiter, error) {
+	return newCommitWaiter(strategy, signer, channelID, "", true, nil)
+}
+
+func newCommitWaiter(strategy WaitStrategy, signer msp.SigningIdentity, channelID, txID string,
+	config bool, endorsers []*client.PeerClient) (*CommitWaiter, error) {
+	if strategy == nil {
+		strategy = NoWait()
+	}
+
+	groups, err := strategy.Groups(endorsers)
+	if err != nil {
+		return nil, fmt.Errorf("get peers to wait for failed: %v", err)
+	}
+
+	timeout := strategy.Timeout()
+	if timeout <= 0 {
+		timeout = DefaultCommitTimeout
+	}
+
+	w := &CommitWaiter{groups: groups, txID: txID, config: config}
+	w.ctx, w.cancel = context.WithTimeout(context.Background(), timeout)
+
+	var peers []*client.PeerClient
+	seen := map[*client.PeerClient]bool{}
+	for _, g := range groups {
+		for _, p := range g.Peers {
+			if !seen[p] {
+				seen[p] = true
+				peers = append(peers, p)
+			}
+		}
+	}
+
+	w.results = make(chan commitResult, len(peers))
+	for _, p := range peers {
+		stream, err := connectFilteredDeliver(w.ctx, p, signer, channelID)
+		if err != nil {
+			w.results <- commitResult{peer: p, err: err}
+		} else {
+			go w.recv(p, stream)
+		}
+		w.started++
+	}
+
+	return w, nil
+}
+
+func connectFilteredDeliver(ctx context.Context, pc *client.PeerClient, signer msp.SigningIdentity,
+	channelID string) (peer.Deliver_DeliverFilteredClient, error) {
+	return connectFilteredDeliverFrom(ctx, pc, signer, channelID, nil)
+}
+
+// connectFilteredDeliverFrom deliver filtered blocks from start, nil start means the newest block
+func connectFilteredDeliverFrom(ctx context.Context, pc *client.PeerClient, signer msp.SigningIdentity,
+	channelID string, start *orderer.SeekPosition) (peer.Deliver_DeliverFilteredClient, error) {
+	dc, err := pc.PeerDeliver()
+	if err != nil {
+		return nil, fmt.Errorf("get deliver client failed: %v", err)
+	}
+
+	stream, err := dc.DeliverFiltered(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("connect to deliver filtered failed: %v", err)
+	}
+
+	env := createSeekEnvelope(channelID, pc.Certificate(), signer, start)
+	if env == nil {
+		return nil, fmt.Errorf("create deliver envelope failed")
+	}
+
+	if err = stream.Send(env); err != nil {
+		return nil, fmt.Errorf("send deliver seek info envelope failed: %v", err)
+	}
+
+	return stream, nil
+}
+
+func (w *CommitWaiter) recv(pc *client.PeerClient, stream peer.Deliver_DeliverFilteredClient) {
+	// the newest block when connected, committed before the transaction is sent
+	first := true
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			w.results <- commitResult{peer: pc, err: fmt.Errorf("receive from deliver filtered failed: %v", err)}
+			return
+		}
+
+		switch r := resp.Type.(type) {
+		case *peer.DeliverResponse_FilteredBlock:
+			skip := w.config && first
+			first = false
+			if skip {
+				continue
+			}
+
+			for i, tx := range r.FilteredBlock.FilteredTransactions {
+				if !w.match(tx) {
+					continue
+				}
+
+				w.results <- commitResult{peer: pc, status: &CommitStatus{
+					TxID:        tx.Txid,
+					Code:        tx.TxValidationCode,
+					BlockNumber: r.FilteredBlock.Number,
+					TxIndex:     uint64(i),
+				}}
+				return
+			}
+		case *peer.DeliverResponse_Status:
+			w.results <- commitResult{peer: pc, err: fmt.Errorf("deliver completed with status (%s) before txid received", r.Status)}
+			return
+		default:
+			w.results <- commitResult{peer: pc, err: fmt.Errorf("received unexpected response type (%T)", r)}
+			return
+		}
+	}
+}
+
+func (w *CommitWaiter) match(tx *peer.FilteredTransaction) bool {
+	if w.config {
+		return tx.Type == common.HeaderType_CONFIG
+	}
+	return tx.Txid == w.txID
+}
+
+//Wait wait until every group got enough valid commits,
+// return error if the transaction is invalidated, a group can't be satisfied, or timeout
+func (w *CommitWaiter) Wait() error {
+	status, err := w.WaitStatus()
+	if err != nil {
+		return err
+	}
+
+	return statusError(status)
+}
+
+//WaitStatus wait until every group got enough valid commits or the transaction is invalidated,
+// return status of the first commit, nil if there is no peer to wait for, eg: NoWait
+func (w *CommitWaiter) WaitStatus() (*CommitStatus, error) {
+	defer w.cancel()
+
+	committed := make([]int, len(w.groups))
+	failed := make([]int, len(w.groups))
+	var lastErr error
+
+	satisfied := func() bool {
+		for i, g := range w.groups {
+			if committed[i] < g.Required {
+				return false
+			}
+		}
+		return true
+	}
+
+	var status *CommitStatus
+	for received := 0; !satisfied(); received++ {
+		if received == w.started {
+			return nil, fmt.Errorf("wait for commit of transaction [%s] failed: %v", w.txID, lastErr)
+		}
+
+		var r commitResult
+		select {
+		case r = <-w.results:
+		case <-w.ctx.Done():
+			return nil, &TimeoutError{TxID: w.txID, Stage: StageCommit}
+		}
+
+		if r.status != nil {
+			if status == nil {
+				status = r.status
+			}
+
+			// all peers get the same validation code
+			if !r.status.Successful() {
+				return r.status, nil
+			}
+		}
+
+		for i, g := range w.groups {
+			if !containsPeer(g.Peers, r.peer) {
+				continue
+			}
+
+			if r.err != nil {
+				failed[i]++
+				lastErr = fmt.Errorf("peer [%s]: %v", r.peer.Address(), r.err)
+				if len(g.Peers)-failed[i] < g.Required {
+					return nil, fmt.Errorf("wait for commit of transaction [%s] failed: %v", w.txID, lastErr)
+				}
+				continue
+			}
+			committed[i]++
+		}
+	}
+
+	return status, nil
+}
+
+//Close stop waiting, eg: the transaction can't be sent
+func (w *CommitWaiter) Close() {
+	w.cancel()
+}
+
+func containsPeer(peers []*client.PeerClient, p *client.PeerClient) bool {
+	for _, pp := range peers {
+		if pp == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package chaincode
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/Asutorufa/fabricsdk/internal/testutil"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"google.golang.org/grpc"
+)
+
+// fakeCommitPeer deliver blocks after the seek info is received, or fail
+type fakeCommitPeer struct {
+	blocks []*peer.FilteredBlock
+	fail   bool
+}
+
+func (f *fakeCommitPeer) Deliver(peer.Deliver_DeliverServer) error { return nil }
+
+func (f *fakeCommitPeer) DeliverWithPrivateData(peer.Deliver_DeliverWithPrivateDataServer) error {
+	return nil
+}
+
+func (f *fakeCommitPeer) DeliverFiltered(stream peer.Deliver_DeliverFilteredServer) error {
+	if _, err := stream.Recv(); err != nil {
+		return err
+	}
+
+	if f.fail {
+		return fmt.Errorf("peer is down")
+	}
+
+	for _, b := range f.blocks {
+		if err := stream.Send(&peer.DeliverResponse{Type: &peer.DeliverResponse_FilteredBlock{FilteredBlock: b}}); err != nil {
+			return err
+		}
+	}
+
+	<-stream.Context().Done()
+	return nil
+}
+
+func newCommitPeer(t *testing.T, f *fakeCommitPeer) *client.PeerClient {
+	s := grpc.NewServer()
+	peer.RegisterDeliverServer(s, f)
+	return testutil.NewPeerClient(t, testutil.Serve(t, s))
+}
+
+func committedBlock(number uint64, txID string, code peer.TxValidationCode) *peer.FilteredBlock {
+	return &peer.FilteredBlock{Number: number, FilteredTransactions: []*peer.FilteredTransaction{
+		{Txid: txID, Type: common.HeaderType_ENDORSER_TRANSACTION, TxValidationCode: code},
+	}}
+}
+
+func TestCommitWaiter(t *testing.T) {
+	signer := testutil.NewSigner(t, "Org1MSP", "User1@org1.example.com")
+
+	valid := newCommitPeer(t, &fakeCommitPeer{blocks: []*peer.FilteredBlock{
+		committedBlock(1, "other", peer.TxValidationCode_VALID),
+		committedBlock(2, "tx1", peer.TxValidationCode_VALID),
+	}})
+	valid2 := newCommitPeer(t, &fakeCommitPeer{blocks: []*peer.FilteredBlock{committedBlock(2, "tx1", peer.TxValidationCode_VALID)}})
+	down := newCommitPeer(t, &fakeCommitPeer{fail: true})
+	silent := newCommitPeer(t, &fakeCommitPeer{})
+	invalid := newCommitPeer(t, &fakeCommitPeer{blocks: []*peer.FilteredBlock{
+		committedBlock(2, "tx1", peer.TxValidationCode_MVCC_READ_CONFLICT),
+	}})
+
+	tests := []struct {
+		name      string
+		strategy  WaitStrategy
+		endorsers []*client.PeerClient
+		err       string
+	}{
+		{"no wait", NoWait(), []*client.PeerClient{silent}, ""},
+		{"all", WaitForAll(time.Second), []*client.PeerClient{valid, valid2}, ""},
+		{"all with a down peer", WaitForAll(time.Second), []*client.PeerClient{valid, down}, "peer is down"},
+		{"any with a down peer", WaitForAny(time.Second), []*client.PeerClient{down, valid}, ""},
+		{"any without endorsers", WaitForAny(time.Second), nil, "no endorsing peer"},
+		{"invalid", WaitForAll(time.Second), []*client.PeerClient{invalid, valid}, "MVCC_READ_CONFLICT"},
+		{"n per org", WaitForNPerOrg(time.Second, 1, map[string][]*client.PeerClient{
+			"Org1MSP": {down, valid},
+			"Org2MSP": {valid2},
+		}), nil, ""},
+		{"peers timeout", WaitForPeers(100*time.Millisecond, valid, silent), nil, "timed out"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := NewCommitWaiter(tt.strategy, signer, "mychannel", "tx1", tt.endorsers)
+			if err == nil {
+				err = w.Wait()
+			}
+
+			if tt.err == "" && err != nil {
+				t.Errorf("wait: %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("wait: %v, want %s", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestConfigCommitWaiter(t *testing.T) {
+	signer := testutil.NewSigner(t, "Org1MSP", "User1@org1.example.com")
+
+	config := func(number uint64) *peer.FilteredBlock {
+		return &peer.FilteredBlock{Number: number, FilteredTransactions: []*peer.FilteredTransaction{
+			{Type: common.HeaderType_CONFIG, TxValidationCode: peer.TxValidationCode_VALID},
+		}}
+	}
+
+	// the newest config block is committed before the update
+	pc := newCommitPeer(t, &fakeCommitPeer{blocks: []*peer.FilteredBlock{config(1)}})
+	w, err := NewConfigCommitWaiter(WaitForPeers(100*time.Millisecond, pc), signer, "mychannel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Wait(); err == nil {
+		t.Error("newest config block is treated as the update")
+	}
+
+	pc = newCommitPeer(t, &fakeCommitPeer{blocks: []*peer.FilteredBlock{config(1), committedBlock(2, "tx", peer.TxValidationCode_VALID), config(3)}})
+	w, err = NewConfigCommitWaiter(WaitForPeers(time.Second, pc), signer, "mychannel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Wait(); err != nil {
+		t.Error(err)
+	}
+}
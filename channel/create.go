@@ -17,7 +17,7 @@ func Create(channelID string, txFile []byte, mspOpt chaincode.MSPOpt, orderers [
 		return nil, fmt.Errorf("get tx envelop failed: %v", err)
 	}
 
-	signer, err := chaincode.GetSigner(mspOpt.Path, mspOpt.ID)
+	signer, err := chaincode.GetSignerByOpt(mspOpt)
 	if err != nil {
 		return nil, fmt.Errorf("get signer failed: %v", err)
 	}
@@ -32,6 +32,7 @@ func Create(channelID string, txFile []byte, mspOpt chaincode.MSPOpt, orderers [
 			orderers[oi].Address,
 			orderers[oi].ServerNameOverride,
 			client.WithClientCert(orderers[oi].ClientKey, orderers[oi].ClientCrt),
+			client.WithClientKeyPasswordFunc(orderers[oi].ClientKeyPassword),
 			client.WithTLS(orderers[oi].Ca),
 		)
 		if err != nil {
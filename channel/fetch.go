@@ -16,6 +16,7 @@ func Fetch(mspOpt chaincode.MSPOpt, orderers chaincode.Endpoint, channelID strin
 		orderers.Address,
 		orderers.ServerNameOverride,
 		client.WithClientCert(orderers.ClientKey, orderers.ClientCrt),
+		client.WithClientKeyPasswordFunc(orderers.ClientKeyPassword),
 		client.WithTLS(orderers.Ca),
 		client.WithTimeout(orderers.Timeout),
 	)
@@ -28,7 +29,7 @@ func Fetch(mspOpt chaincode.MSPOpt, orderers chaincode.Endpoint, channelID strin
 		return nil, fmt.Errorf("get orderer client deliver error -> %v", err)
 	}
 
-	signer, err := chaincode.GetSigner(mspOpt.Path, mspOpt.ID)
+	signer, err := chaincode.GetSignerByOpt(mspOpt)
 	if err != nil {
 		return nil, fmt.Errorf("get signer error -> %v", err)
 	}
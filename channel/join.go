@@ -33,7 +33,7 @@ func Join(mspOpt chaincode.MSPOpt, peers chaincode.Endpoint, genesisBlock []byte
 }
 
 func exec(mspOpt chaincode.MSPOpt, peers chaincode.Endpoint, ccSpec *peer.ChaincodeSpec) (*peer.ProposalResponse, error) {
-	signer, err := chaincode.GetSigner(mspOpt.Path, mspOpt.ID)
+	signer, err := chaincode.GetSignerByOpt(mspOpt)
 	if err != nil {
 		return nil, fmt.Errorf("get signer error -> %v", err)
 	}
@@ -65,6 +65,7 @@ func exec(mspOpt chaincode.MSPOpt, peers chaincode.Endpoint, ccSpec *peer.Chainc
 		peers.ServerNameOverride,
 		client.WithTLS(peers.Ca),
 		client.WithClientCert(peers.ClientKey, peers.ClientCrt),
+		client.WithClientKeyPasswordFunc(peers.ClientKeyPassword),
 		client.WithTimeout(peers.Timeout),
 	)
 	if err != nil {
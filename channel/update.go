@@ -1,6 +1,7 @@
 package channel
 
 import (
+	"errors"
 	"fmt"
 	"log"
 
@@ -15,12 +16,20 @@ import (
 
 // Update update channel config
 func Update(channelID string, updateConfig []byte, mspOpt chaincode.MSPOpt, orderers []chaincode.Endpoint) error {
+	return UpdateAndWait(channelID, updateConfig, mspOpt, orderers, nil)
+}
+
+// UpdateAndWait update channel config, wait for the config block by wait, eg: chaincode.WaitForPeers
+// wait nil means not wait, orderers are tried one by one until one of them accepts the update,
+// a chaincode.BroadcastError is returned if none of them does
+func UpdateAndWait(channelID string, updateConfig []byte, mspOpt chaincode.MSPOpt, orderers []chaincode.Endpoint,
+	wait chaincode.WaitStrategy) error {
 	ctxEnv, err := protoutil.UnmarshalEnvelope(updateConfig)
 	if err != nil {
 		return fmt.Errorf("unmarshal envelope error -> %v", err)
 	}
 
-	signer, err := chaincode.GetSigner(mspOpt.Path, mspOpt.ID)
+	signer, err := chaincode.GetSignerByOpt(mspOpt)
 	if err != nil {
 		return fmt.Errorf("get msp signer error -> %v", err)
 	}
@@ -29,32 +38,48 @@ func Update(channelID string, updateConfig []byte, mspOpt chaincode.MSPOpt, orde
 		return fmt.Errorf("check envelop with error -> %v", err)
 	}
 
+	chdr, err := protoutil.ChannelHeader(chCrtEnv)
+	if err != nil {
+		return fmt.Errorf("get channel header error -> %v", err)
+	}
+
+	waiter, err := chaincode.NewConfigCommitWaiter(wait, signer, chdr.ChannelId)
+	if err != nil {
+		return fmt.Errorf("create commit waiter error -> %v", err)
+	}
+
+	// the clients are closed once the envelope is sent, the config block is waited from peers
+	var failures []chaincode.OrdererFailure
 	for oi := range orderers {
 		ordererClient, err := client.NewOrdererClientSelf(
 			orderers[oi].Address,
 			orderers[oi].ServerNameOverride,
 			client.WithClientCert(orderers[oi].ClientKey, orderers[oi].ClientCrt),
+			client.WithClientKeyPasswordFunc(orderers[oi].ClientKeyPassword),
 			client.WithTLS(orderers[oi].Ca),
+			client.WithTimeout(orderers[oi].Timeout),
 		)
 		if err != nil {
 			log.Printf("initialize new orderer [%s] client error -> %v\n", orderers[oi].Address, err)
+			failures = append(failures, chaincode.OrdererFailure{Address: orderers[oi].Address, Err: err})
 			continue
 		}
-		defer ordererClient.Close()
 
-		bc, err := ordererClient.Broadcast()
-		if err != nil {
-			log.Printf("get orderer broad cast error -> %v\n", err)
-			continue
-		}
-		err = bc.Send(chCrtEnv)
+		err = chaincode.Broadcast(chCrtEnv, []*client.OrdererClient{ordererClient})
+		ordererClient.Close()
 		if err != nil {
-			log.Printf("send envelop error -> %v\n", err)
+			var be *chaincode.BroadcastError
+			if errors.As(err, &be) {
+				failures = append(failures, be.Failures...)
+			} else {
+				failures = append(failures, chaincode.OrdererFailure{Address: orderers[oi].Address, Err: err})
+			}
 			continue
 		}
-		return nil
+		return waiter.Wait()
 	}
-	return fmt.Errorf("send envelop error")
+	waiter.Close()
+	return fmt.Errorf("send envelop error -> %w", &chaincode.BroadcastError{TxID: chdr.TxId, Failures: failures})
 }
 
 // copy from github.com/hyperledger/fabric/internal/peer/channel/update.go
@@ -0,0 +1,71 @@
+package channel
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Asutorufa/fabricsdk/chaincode"
+	"github.com/Asutorufa/fabricsdk/internal/testutil"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/orderer"
+	"github.com/hyperledger/fabric/protoutil"
+	"google.golang.org/grpc"
+)
+
+// fakeOrderer respond every broadcast envelope with status
+type fakeOrderer struct {
+	status cb.Status
+	envs   int32
+}
+
+func (o *fakeOrderer) Broadcast(stream orderer.AtomicBroadcast_BroadcastServer) error {
+	for {
+		if _, err := stream.Recv(); err != nil {
+			return nil
+		}
+		atomic.AddInt32(&o.envs, 1)
+
+		if err := stream.Send(&orderer.BroadcastResponse{Status: o.status, Info: o.status.String()}); err != nil {
+			return err
+		}
+	}
+}
+
+func (o *fakeOrderer) Deliver(orderer.AtomicBroadcast_DeliverServer) error {
+	return nil
+}
+
+func newFakeOrderer(t *testing.T, status cb.Status) (*fakeOrderer, chaincode.Endpoint) {
+	o := &fakeOrderer{status: status}
+	s := grpc.NewServer()
+	orderer.RegisterAtomicBroadcastServer(s, o)
+	return o, chaincode.Endpoint{Address: testutil.Serve(t, s)}
+}
+
+func TestUpdateAndWait(t *testing.T) {
+	env, err := protoutil.CreateSignedEnvelope(cb.HeaderType_CONFIG_UPDATE, "mychannel", nil,
+		&cb.ConfigUpdateEnvelope{ConfigUpdate: []byte("update")}, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	update := protoutil.MarshalOrPanic(env)
+	mspOpt := chaincode.MSPOpt{Signer: testutil.NewSigner(t, "Org1MSP", "Admin@org1.example.com")}
+
+	rejecting, rejectingEndpoint := newFakeOrderer(t, cb.Status_BAD_REQUEST)
+	accepting, acceptingEndpoint := newFakeOrderer(t, cb.Status_SUCCESS)
+
+	// the rejected update is sent to the next orderer
+	if err = UpdateAndWait("mychannel", update, mspOpt, []chaincode.Endpoint{rejectingEndpoint, acceptingEndpoint}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&rejecting.envs) != 1 || atomic.LoadInt32(&accepting.envs) != 1 {
+		t.Errorf("envelopes: %d %d", rejecting.envs, accepting.envs)
+	}
+
+	err = UpdateAndWait("mychannel", update, mspOpt, []chaincode.Endpoint{rejectingEndpoint, rejectingEndpoint}, nil)
+	var be *chaincode.BroadcastError
+	if !errors.As(err, &be) || len(be.Failures) != 2 || be.Status() != cb.Status_BAD_REQUEST {
+		t.Errorf("update rejected by all orderers: %v", err)
+	}
+}
@@ -20,10 +20,9 @@ type Client struct {
 
 //NewClient new grpc client
 func NewClient(address, override string, Opt ...func(config *grpcclient.ClientConfig)) (*Client, error) {
-	config := &grpcclient.ClientConfig{}
-
-	for oi := range Opt {
-		Opt[oi](config)
+	config, err := newClientConfig(Opt...)
+	if err != nil {
+		return nil, err
 	}
 
 	client := &Client{address: address, sn: override}
@@ -63,7 +62,6 @@ func NewClient(address, override string, Opt ...func(config *grpcclient.ClientCo
 	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
 	defer cancel()
 
-	var err error
 	client.grpcConn, err = grpc.DialContext(ctx, address, opt...)
 	if err != nil {
 		return nil, err
@@ -86,3 +84,8 @@ func (c *Client) Certificate() tls.Certificate {
 func (c *Client) Close() error {
 	return c.grpcConn.Close()
 }
+
+//Address address of the server, host:port
+func (c *Client) Address() string {
+	return c.address
+}
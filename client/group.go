@@ -8,7 +8,6 @@ import (
 	"time"
 
 	"github.com/hyperledger/fabric-protos-go/peer"
-	"github.com/hyperledger/fabric/bccsp/factory"
 	"github.com/hyperledger/fabric/msp"
 )
 
@@ -17,6 +16,8 @@ type GrpcTLSOpt struct {
 	ClientCrt []byte
 	ClientKey []byte
 	Ca        []byte
+	// ClientKeyPassword for encrypted ClientKey
+	ClientKeyPassword PasswordFunc
 
 	ServerNameOverride string
 	Timeout            time.Duration
@@ -30,9 +31,12 @@ type Endpoint struct {
 
 //Group peer and orderer group
 type Group struct {
-	peers    sync.Map
-	orderers sync.Map
-	signers  sync.Map
+	peers     sync.Map
+	orderers  sync.Map
+	signers   sync.Map
+	endpoints sync.Map
+	// mspPaths msp directories of signers loaded from directories, by msp id
+	mspPaths sync.Map
 }
 
 //NewGroup new clients group
@@ -46,13 +50,16 @@ func (g *Group) AddPeerClient(d Endpoint) error {
 		d.Address,
 		d.ServerNameOverride,
 		WithClientCert(d.ClientKey, d.ClientCrt),
+		WithClientKeyPasswordFunc(d.ClientKeyPassword),
 		WithTLS(d.Ca),
+		WithTimeout(d.Timeout),
 	)
 	if err != nil {
 		return fmt.Errorf("new client failed: %v", err)
 	}
 
 	g.peers.Store(d.Address, c)
+	g.endpoints.Store(d.Address, d)
 	return nil
 }
 
@@ -67,7 +74,7 @@ func (g *Group) GetPeerClients() []*PeerClient {
 		}
 
 		c = append(c, &PeerClient{*x})
-		return false
+		return true
 	})
 
 	return c
@@ -91,6 +98,7 @@ func (g *Group) GetPeerClient(address string) *PeerClient {
 //DeletePeerClient delete a peer client
 func (g *Group) DeletePeerClient(address string) {
 	g.peers.Delete(address)
+	g.endpoints.Delete(address)
 }
 
 //AddOrdererClient add a orderer client
@@ -99,13 +107,16 @@ func (g *Group) AddOrdererClient(d Endpoint) error {
 		d.Address,
 		d.ServerNameOverride,
 		WithClientCert(d.ClientKey, d.ClientCrt),
+		WithClientKeyPasswordFunc(d.ClientKeyPassword),
 		WithTLS(d.Ca),
+		WithTimeout(d.Timeout),
 	)
 	if err != nil {
 		return fmt.Errorf("new client failed: %v", err)
 	}
 
 	g.orderers.Store(d.Address, c)
+	g.endpoints.Store(d.Address, d)
 	return nil
 }
 
@@ -120,7 +131,7 @@ func (g *Group) GetOrderersClients() []*OrdererClient {
 		}
 
 		c = append(c, &OrdererClient{*x})
-		return false
+		return true
 	})
 
 	return c
@@ -144,6 +155,37 @@ func (g *Group) GetOrdererClient(address string) *OrdererClient {
 //DeleteOrdererClient delete a orderer client
 func (g *Group) DeleteOrdererClient(address string) {
 	g.orderers.Delete(address)
+	g.endpoints.Delete(address)
+}
+
+//GetEndpoints get endpoints of all peers and orderers
+func (g *Group) GetEndpoints() []Endpoint {
+	var e []Endpoint
+
+	g.endpoints.Range(func(key, value interface{}) bool {
+		x, ok := value.(Endpoint)
+		if ok {
+			e = append(e, x)
+		}
+		return true
+	})
+
+	return e
+}
+
+//GetSigners get all signers
+func (g *Group) GetSigners() []msp.SigningIdentity {
+	var s []msp.SigningIdentity
+
+	g.signers.Range(func(key, value interface{}) bool {
+		x, ok := value.(msp.SigningIdentity)
+		if ok {
+			s = append(s, x)
+		}
+		return true
+	})
+
+	return s
 }
 
 //EndorserProposal endorse proposal
@@ -193,17 +235,47 @@ func (g *Group) EndorserProposal(endorserAddress []string, sp *peer.SignedPropos
 	return proposalResponse
 }
 
-//AddSigner add a msp
+//AddSigner add the signer of msp directory, loaded as chaincode.GetSigner
 func (g *Group) AddSigner(mspID, mspPath string) error {
-	signer, err := getSigner(mspPath, mspID)
+	signer, err := GetMSPSigner(mspPath, mspID)
+	if err != nil {
+		return fmt.Errorf("get signer failed: %v", err)
+	}
+
+	g.signers.Store(mspID, signer)
+	g.mspPaths.Store(mspID, mspPath)
+	return nil
+}
+
+//AddSignerWithPassword add the signer of msp directory which keystore keys are encrypted,
+// loaded as chaincode.GetSignerWithPassword
+func (g *Group) AddSignerWithPassword(mspID, mspPath string, password PasswordFunc) error {
+	signer, err := GetMSPSignerWithPassword(mspPath, mspID, password)
 	if err != nil {
 		return fmt.Errorf("get signer failed: %v", err)
 	}
 
 	g.signers.Store(mspID, signer)
+	g.mspPaths.Store(mspID, mspPath)
 	return nil
 }
 
+//AddSignerIdentity add a signer, eg: identity from wallet, mspID is signer.GetMSPIdentifier()
+func (g *Group) AddSignerIdentity(signer msp.SigningIdentity) {
+	g.signers.Store(signer.GetMSPIdentifier(), signer)
+	g.mspPaths.Delete(signer.GetMSPIdentifier())
+}
+
+//GetMSPPaths msp directories of signers added by AddSigner and AddSignerWithPassword, by msp id
+func (g *Group) GetMSPPaths() map[string]string {
+	paths := map[string]string{}
+	g.mspPaths.Range(func(key, value interface{}) bool {
+		paths[key.(string)] = value.(string)
+		return true
+	})
+	return paths
+}
+
 //GetSigner get map signing
 func (g *Group) GetSigner(mspID string) *msp.SigningIdentity {
 	v, _ := g.signers.Load(mspID)
@@ -223,6 +295,7 @@ func (g *Group) GetSigner(mspID string) *msp.SigningIdentity {
 // TODO can't use fabric msp load
 func (g *Group) DeleteSigner(mspID string) {
 	g.signers.Delete(mspID)
+	g.mspPaths.Delete(mspID)
 }
 
 type clientCache struct {
@@ -240,22 +313,3 @@ func (c *clientCache) delete(address string) {
 func (c *clientCache) getSyncMap() *sync.Map {
 	return &c.stack
 }
-
-func getSigner(mspPath, mspID string) (msp.SigningIdentity, error) {
-	amsp, err := msp.New(msp.Options["bccsp"], factory.GetDefault())
-	if err != nil {
-		return nil, fmt.Errorf("create new msp failed: %v", err)
-	}
-
-	mspConfig, err := msp.GetLocalMspConfig(mspPath, factory.GetDefaultOpts(), mspID)
-	if err != nil {
-		return nil, fmt.Errorf("get local msp config failed: %v", err)
-	}
-
-	err = amsp.Setup(mspConfig)
-	if err != nil {
-		return nil, fmt.Errorf("setup msp failed: %v", err)
-	}
-
-	return amsp.GetDefaultSigningIdentity()
-}
@@ -96,6 +96,8 @@ type SecureOptions struct {
 	Certificate []byte
 	// PEM-encoded private key to be used for TLS communication
 	Key []byte
+	// KeyPassword returns the passphrase of Key if Key is encrypted
+	KeyPassword func() ([]byte, error)
 	// Set of PEM-encoded X509 certificate authorities used by clients to
 	// verify server certificates
 	ServerRootCAs [][]byte
@@ -0,0 +1,225 @@
+package client
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+//PasswordFunc return passphrase of a encrypted private key,
+// only called when the key is encrypted
+type PasswordFunc func() ([]byte, error)
+
+//Password static passphrase
+func Password(password []byte) PasswordFunc {
+	return func() ([]byte, error) { return password, nil }
+}
+
+var (
+	oidPBES2  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA224 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 8}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 10}
+	oidHMACWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+
+	oidAES128CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidDESEDE3CBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+)
+
+// encryptedPrivateKeyInfo RFC 5208
+type encryptedPrivateKeyInfo struct {
+	Algo          pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+// pbes2Params RFC 8018
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+// pbkdf2Params RFC 8018, prf default is hmacWithSHA1
+type pbkdf2Params struct {
+	Salt       []byte
+	Iterations int
+	KeyLength  int                      `asn1:"optional"`
+	PRF        pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+//IsEncryptedPEMKey whether the pem private key is encrypted pkcs#8 or legacy "Proc-Type: 4,ENCRYPTED"
+func IsEncryptedPEMKey(keyPEM []byte) bool {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return false
+	}
+	// legacy encrypted pem is deprecated, but still written by openssl and fabric
+	return block.Type == "ENCRYPTED PRIVATE KEY" || x509.IsEncryptedPEMBlock(block)
+}
+
+//DecryptPEMKey decrypt a encrypted pkcs#8 ("ENCRYPTED PRIVATE KEY", PBES2) or
+// legacy ("Proc-Type: 4,ENCRYPTED") pem private key, return the unencrypted pem key,
+// a unencrypted key is returned as it is, password is not called
+func DecryptPEMKey(keyPEM []byte, password PasswordFunc) ([]byte, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("decode private key pem failed")
+	}
+
+	if !IsEncryptedPEMKey(keyPEM) {
+		return keyPEM, nil
+	}
+
+	if password == nil {
+		return nil, fmt.Errorf("private key is encrypted, but no password is provided")
+	}
+
+	pwd, err := password()
+	if err != nil {
+		return nil, fmt.Errorf("get private key password failed: %v", err)
+	}
+
+	if block.Type == "ENCRYPTED PRIVATE KEY" {
+		der, err := decryptPKCS8(block.Bytes, pwd)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+	}
+
+	der, err := x509.DecryptPEMBlock(block, pwd)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt private key failed: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: der}), nil
+}
+
+// decryptPKCS8 decrypt pkcs#8 EncryptedPrivateKeyInfo, return pkcs#8 PrivateKeyInfo der
+func decryptPKCS8(der, password []byte) ([]byte, error) {
+	info := &encryptedPrivateKeyInfo{}
+	if _, err := asn1.Unmarshal(der, info); err != nil {
+		return nil, fmt.Errorf("unmarshal encrypted private key failed: %v", err)
+	}
+
+	if !info.Algo.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("unsupported private key encryption algorithm: %v", info.Algo.Algorithm)
+	}
+
+	params := &pbes2Params{}
+	if _, err := asn1.Unmarshal(info.Algo.Parameters.FullBytes, params); err != nil {
+		return nil, fmt.Errorf("unmarshal pbes2 parameters failed: %v", err)
+	}
+
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, fmt.Errorf("unsupported key derivation function: %v", params.KeyDerivationFunc.Algorithm)
+	}
+
+	kdf := &pbkdf2Params{}
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, kdf); err != nil {
+		return nil, fmt.Errorf("unmarshal pbkdf2 parameters failed: %v", err)
+	}
+
+	prf, err := pbkdf2PRF(kdf.PRF.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	newCipher, keyLen, err := pbes2Cipher(params.EncryptionScheme.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if kdf.KeyLength != 0 && kdf.KeyLength != keyLen {
+		return nil, fmt.Errorf("invalid key length %d of %v", kdf.KeyLength, params.EncryptionScheme.Algorithm)
+	}
+
+	var iv []byte
+	if _, err = asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, fmt.Errorf("unmarshal iv failed: %v", err)
+	}
+
+	block, err := newCipher(pbkdf2.Key(password, kdf.Salt, kdf.Iterations, keyLen, prf))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(iv) != block.BlockSize() || len(info.EncryptedData)%block.BlockSize() != 0 || len(info.EncryptedData) == 0 {
+		return nil, fmt.Errorf("invalid encrypted private key")
+	}
+
+	data := make([]byte, len(info.EncryptedData))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(data, info.EncryptedData)
+
+	data, err = pkcs7Unpad(data, block.BlockSize())
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = x509.ParsePKCS8PrivateKey(data); err != nil {
+		return nil, fmt.Errorf("decrypt private key failed, password incorrect? -> %v", err)
+	}
+
+	return data, nil
+}
+
+func pbkdf2PRF(oid asn1.ObjectIdentifier) (func() hash.Hash, error) {
+	switch {
+	case len(oid) == 0, oid.Equal(oidHMACWithSHA1):
+		return sha1.New, nil
+	case oid.Equal(oidHMACWithSHA224):
+		return sha256.New224, nil
+	case oid.Equal(oidHMACWithSHA256):
+		return sha256.New, nil
+	case oid.Equal(oidHMACWithSHA384):
+		return sha512.New384, nil
+	case oid.Equal(oidHMACWithSHA512):
+		return sha512.New, nil
+	}
+	return nil, fmt.Errorf("unsupported pbkdf2 prf: %v", oid)
+}
+
+func pbes2Cipher(oid asn1.ObjectIdentifier) (func([]byte) (cipher.Block, error), int, error) {
+	switch {
+	case oid.Equal(oidAES128CBC):
+		return aes.NewCipher, 16, nil
+	case oid.Equal(oidAES192CBC):
+		return aes.NewCipher, 24, nil
+	case oid.Equal(oidAES256CBC):
+		return aes.NewCipher, 32, nil
+	case oid.Equal(oidDESEDE3CBC):
+		return des.NewTripleDESCipher, 24, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported encryption scheme: %v", oid)
+}
+
+var errIncorrectPassword = errors.New("decrypt private key failed, password incorrect")
+
+func pkcs7Unpad(data []byte, blockSize int) ([]byte, error) {
+	n := int(data[len(data)-1])
+	if n == 0 || n > blockSize || n > len(data) {
+		return nil, errIncorrectPassword
+	}
+
+	for _, b := range data[len(data)-n:] {
+		if int(b) != n {
+			return nil, errIncorrectPassword
+		}
+	}
+
+	return data[:len(data)-n], nil
+}
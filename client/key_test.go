@@ -0,0 +1,91 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func readTestdata(t *testing.T, name string) []byte {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDecryptPEMKey(t *testing.T) {
+	block, _ := pem.Decode(readTestdata(t, "key.pem"))
+	want, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"key.pem", "key_pkcs8_aes256.pem", "key_pkcs8_des3.pem", "key_legacy.pem"} {
+		t.Run(name, func(t *testing.T) {
+			keyPEM, err := DecryptPEMKey(readTestdata(t, name), Password([]byte("passw0rd")))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if IsEncryptedPEMKey(keyPEM) {
+				t.Fatal("key is still encrypted")
+			}
+
+			if _, err = tls.X509KeyPair(readTestdata(t, "cert.pem"), keyPEM); err != nil {
+				t.Fatal(err)
+			}
+
+			block, _ := pem.Decode(keyPEM)
+			got, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				got, err = x509.ParseECPrivateKey(block.Bytes)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !want.(*ecdsa.PrivateKey).Equal(got) {
+				t.Error("decrypted key is not equal")
+			}
+		})
+	}
+}
+
+func TestDecryptPEMKeyWrongPassword(t *testing.T) {
+	for _, name := range []string{"key_pkcs8_aes256.pem", "key_pkcs8_des3.pem", "key_legacy.pem"} {
+		if _, err := DecryptPEMKey(readTestdata(t, name), Password([]byte("wrong"))); err == nil {
+			t.Errorf("%s: decrypt with wrong password", name)
+		}
+
+		if _, err := DecryptPEMKey(readTestdata(t, name), nil); err == nil {
+			t.Errorf("%s: decrypt without password", name)
+		}
+	}
+}
+
+func TestNewClientConfigWithPassword(t *testing.T) {
+	var called int
+	config, err := newClientConfig(
+		WithClientCert(readTestdata(t, "key_pkcs8_aes256.pem"), readTestdata(t, "cert.pem")),
+		WithClientKeyPasswordFunc(func() ([]byte, error) {
+			called++
+			return []byte("passw0rd"), nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if called != 1 {
+		t.Errorf("password callback called %d times", called)
+	}
+
+	if _, err = tls.X509KeyPair(config.SecOpts.Certificate, config.SecOpts.Key); err != nil {
+		t.Error(err)
+	}
+}
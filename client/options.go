@@ -1,6 +1,7 @@
 package client
 
 import (
+	"fmt"
 	"io/ioutil"
 	"log"
 	"time"
@@ -67,3 +68,40 @@ func WithClientCert(keyPEM, certPEM []byte) func(client *grpcclient.ClientConfig
 		client.SecOpts.Certificate = certPEM
 	}
 }
+
+//WithClientKeyPassword passphrase of the encrypted client key, pkcs#8 or legacy pem encryption
+func WithClientKeyPassword(password []byte) func(client *grpcclient.ClientConfig) {
+	if len(password) == 0 {
+		return func(client *grpcclient.ClientConfig) {}
+	}
+	return WithClientKeyPasswordFunc(Password(password))
+}
+
+//WithClientKeyPasswordFunc passphrase callback of the encrypted client key, only called if the key is encrypted
+func WithClientKeyPasswordFunc(password PasswordFunc) func(client *grpcclient.ClientConfig) {
+	return func(client *grpcclient.ClientConfig) {
+		if password == nil {
+			return
+		}
+		client.SecOpts.KeyPassword = password
+	}
+}
+
+// newClientConfig apply all options, then decrypt the client key if it is encrypted
+func newClientConfig(Opt ...func(config *grpcclient.ClientConfig)) (*grpcclient.ClientConfig, error) {
+	config := &grpcclient.ClientConfig{}
+
+	for oi := range Opt {
+		Opt[oi](config)
+	}
+
+	if config.SecOpts.RequireClientCert && IsEncryptedPEMKey(config.SecOpts.Key) {
+		key, err := DecryptPEMKey(config.SecOpts.Key, config.SecOpts.KeyPassword)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt client key failed: %v", err)
+		}
+		config.SecOpts.Key = key
+	}
+
+	return config, nil
+}
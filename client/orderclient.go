@@ -14,10 +14,9 @@ type OrdererClient struct {
 
 //NewOrdererClient create new orderer client
 func NewOrdererClient(address, override string, Opt ...func(config *grpcclient.ClientConfig)) (o *OrdererClient, err error) {
-	config := &grpcclient.ClientConfig{}
-
-	for index := range Opt {
-		Opt[index](config)
+	config, err := newClientConfig(Opt...)
+	if err != nil {
+		return nil, err
 	}
 
 	o = new(OrdererClient)
@@ -48,6 +47,11 @@ func (o *OrdererClient) Broadcast() (ordererProtos.AtomicBroadcast_BroadcastClie
 	return ordererProtos.NewAtomicBroadcastClient(o.grpcConn).Broadcast(context.TODO())
 }
 
+//BroadcastWithContext orderer broadcast client, the stream is closed once ctx is done
+func (o *OrdererClient) BroadcastWithContext(ctx context.Context) (ordererProtos.AtomicBroadcast_BroadcastClient, error) {
+	return ordererProtos.NewAtomicBroadcastClient(o.grpcConn).Broadcast(ctx)
+}
+
 //Deliver orderer deliver client
 func (o *OrdererClient) Deliver() (ordererProtos.AtomicBroadcast_DeliverClient, error) {
 	return ordererProtos.NewAtomicBroadcastClient(o.grpcConn).Deliver(context.TODO())
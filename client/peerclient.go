@@ -6,6 +6,8 @@ import (
 
 	"github.com/Asutorufa/fabricsdk/client/grpcclient"
 
+	"github.com/hyperledger/fabric-protos-go/discovery"
+	"github.com/hyperledger/fabric-protos-go/gateway"
 	"github.com/hyperledger/fabric-protos-go/peer"
 )
 
@@ -29,10 +31,9 @@ type PeerClient struct {
 
 //NewPeerClient create new peer client by fabric internal grpc client
 func NewPeerClient(address, override string, Opt ...func(*grpcclient.ClientConfig)) (p *PeerClient, err error) {
-	config := &grpcclient.ClientConfig{}
-
-	for index := range Opt {
-		Opt[index](config)
+	config, err := newClientConfig(Opt...)
+	if err != nil {
+		return nil, err
 	}
 
 	p = new(PeerClient)
@@ -87,3 +88,13 @@ func (pc *PeerClient) SnapshotClient() (peer.SnapshotClient, error) {
 // func (pc *PeerClient) Close() (err error) {
 // return pc.grpcConn.Close()
 // }
+
+// Gateway returns a client for the Gateway service, fabric 2.4+ peers
+func (pc *PeerClient) Gateway() (gateway.GatewayClient, error) {
+	return gateway.NewGatewayClient(pc.grpcConn), nil
+}
+
+// Discovery returns a client for the Discovery service
+func (pc *PeerClient) Discovery() (discovery.DiscoveryClient, error) {
+	return discovery.NewDiscoveryClient(pc.grpcConn), nil
+}
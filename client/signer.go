@@ -0,0 +1,129 @@
+package client
+
+import (
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/factory"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/hyperledger/fabric/msp"
+)
+
+// signerMSPOpts msp of signers built from pem without a msp directory, eg: wallet identities,
+// they have no admins which msp 1.4.3 requires
+var signerMSPOpts = &msp.BCCSPNewOpts{NewBaseOpts: msp.NewBaseOpts{Version: msp.MSPv1_3}}
+
+//NewSignerMSP new msp for the default signing identity of a msp config without admins,
+// keys are found in the keystore of csp
+func NewSignerMSP(csp bccsp.BCCSP) (msp.MSP, error) {
+	return msp.New(signerMSPOpts, csp)
+}
+
+//GetMSPSigner get the default signing identity of msp directory
+func GetMSPSigner(mspPath, mspID string) (msp.SigningIdentity, error) {
+	// every msp has its own keystore, factory.GetDefault() only can be initialized once
+	csp, err := sw.NewDefaultSecurityLevel(filepath.Join(mspPath, "keystore"))
+	if err != nil {
+		return nil, fmt.Errorf("create bccsp failed: %v", err)
+	}
+
+	return getMSPSigner(mspPath, mspID, csp)
+}
+
+//GetMSPSignerWithPassword get the default signing identity of msp directory which keystore keys are encrypted,
+// encrypted pkcs#8 and legacy "Proc-Type: 4,ENCRYPTED" pem keys are supported
+func GetMSPSignerWithPassword(mspPath, mspID string, password PasswordFunc) (msp.SigningIdentity, error) {
+	keystore := filepath.Join(mspPath, "keystore")
+	files, err := ioutil.ReadDir(keystore)
+	if err != nil {
+		return nil, fmt.Errorf("read keystore failed: %v", err)
+	}
+
+	csp, err := sw.NewDefaultSecurityLevelWithKeystore(NewMemKeyStore())
+	if err != nil {
+		return nil, fmt.Errorf("create bccsp failed: %v", err)
+	}
+
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(keystore, file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read key [%s] failed: %v", file.Name(), err)
+		}
+
+		// only keys are imported, others are skipped as the sw keystore does, eg: README, certificates
+		if block, _ := pem.Decode(data); block == nil || !strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			continue
+		}
+
+		keyPEM, err := DecryptPEMKey(data, password)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt key [%s] failed: %v", file.Name(), err)
+		}
+
+		block, _ := pem.Decode(keyPEM)
+		// not temporary, then the key can be found by the msp with the ski of the certificate
+		_, err = csp.KeyImport(block.Bytes, &bccsp.ECDSAPrivateKeyImportOpts{Temporary: false})
+		if err != nil {
+			return nil, fmt.Errorf("import key [%s] failed: %v", file.Name(), err)
+		}
+	}
+
+	return getMSPSigner(mspPath, mspID, csp)
+}
+
+func getMSPSigner(mspPath, mspID string, csp bccsp.BCCSP) (msp.SigningIdentity, error) {
+	amsp, err := msp.New(msp.Options["bccsp"], csp)
+	if err != nil {
+		return nil, fmt.Errorf("create new msp failed: %v", err)
+	}
+
+	mspConfig, err := msp.GetLocalMspConfig(mspPath, factory.GetDefaultOpts(), mspID)
+	if err != nil {
+		return nil, fmt.Errorf("get local msp config failed: %v", err)
+	}
+
+	err = amsp.Setup(mspConfig)
+	if err != nil {
+		return nil, fmt.Errorf("setup msp failed: %v", err)
+	}
+
+	return amsp.GetDefaultSigningIdentity()
+}
+
+// memKeyStore in-memory bccsp key store
+type memKeyStore struct {
+	keys sync.Map
+}
+
+//NewMemKeyStore in-memory bccsp key store, for keys not stored in a keystore directory
+func NewMemKeyStore() bccsp.KeyStore {
+	return &memKeyStore{}
+}
+
+func (m *memKeyStore) ReadOnly() bool {
+	return false
+}
+
+func (m *memKeyStore) GetKey(ski []byte) (bccsp.Key, error) {
+	k, ok := m.keys.Load(hex.EncodeToString(ski))
+	if !ok {
+		return nil, fmt.Errorf("key [%x] not found", ski)
+	}
+
+	return k.(bccsp.Key), nil
+}
+
+func (m *memKeyStore) StoreKey(k bccsp.Key) error {
+	m.keys.Store(hex.EncodeToString(k.SKI()), k)
+	return nil
+}
@@ -0,0 +1,344 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Asutorufa/fabricsdk/chaincode"
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/golang/protobuf/proto"
+	dp "github.com/hyperledger/fabric-protos-go/discovery"
+	"github.com/hyperledger/fabric-protos-go/gossip"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/msp"
+)
+
+//Peer a peer discovered
+type Peer struct {
+	MSPID string
+	// Endpoint host:port
+	Endpoint string
+	// Identity serialized identity of the peer
+	Identity []byte
+	// LedgerHeight and Chaincodes are only set for channel peers
+	LedgerHeight uint64
+	Chaincodes   []Chaincode
+}
+
+//Chaincode chaincode installed on a peer
+type Chaincode struct {
+	Name    string
+	Version string
+}
+
+//Config channel config
+type Config struct {
+	// MSPs msp id -> msp config, eg: RootCerts, TlsRootCerts
+	MSPs map[string]*mb.FabricMSPConfig
+	// Orderers msp id -> host:port
+	Orderers map[string][]string
+}
+
+//TLSRootCerts all tls root and intermediate certificates of a msp, for client.WithTLS
+func (c *Config) TLSRootCerts(mspID string) []byte {
+	conf, ok := c.MSPs[mspID]
+	if !ok {
+		return nil
+	}
+
+	var certs []byte
+	for _, cert := range append(conf.TlsRootCerts, conf.TlsIntermediateCerts...) {
+		certs = append(certs, cert...)
+	}
+	return certs
+}
+
+//Layout one way to satisfy the endorsement policy,
+// group name -> number of endorsements needed from the group
+type Layout = chaincode.Layout
+
+//EndorsementDescriptor endorsers of a chaincode invocation
+type EndorsementDescriptor struct {
+	Chaincode string
+	// EndorsersByGroups group name -> peers
+	EndorsersByGroups map[string][]*Peer
+	// Layouts the endorsement policy is satisfied if any layout is satisfied
+	Layouts []Layout
+}
+
+//Plan endorsement plan for chaincode.InternalInvokeWithPlan, connect creates the client of a endorser,
+// endorsers which can't be connected are skipped, connected clients are closed by the caller
+func (ed *EndorsementDescriptor) Plan(connect func(*Peer) (*client.PeerClient, error)) (*chaincode.EndorsementPlan, error) {
+	groups := map[string][]*client.PeerClient{}
+	for group, peers := range ed.EndorsersByGroups {
+		// prefer peers with higher ledger height
+		peers = append([]*Peer(nil), peers...)
+		sort.SliceStable(peers, func(i, j int) bool { return peers[i].LedgerHeight > peers[j].LedgerHeight })
+
+		for _, p := range peers {
+			pc, err := connect(p)
+			if err != nil {
+				log.Printf("connect to endorser [%s] failed: %v", p.Endpoint, err)
+				continue
+			}
+			groups[group] = append(groups[group], pc)
+		}
+	}
+
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("no endorser of chaincode [%s] is connected", ed.Chaincode)
+	}
+
+	return chaincode.NewEndorsementPlan(groups, ed.Layouts), nil
+}
+
+//DefaultTimeout default timeout of a discovery request
+const DefaultTimeout = 30 * time.Second
+
+//Client discovery client
+type Client struct {
+	peer    *client.PeerClient
+	signer  msp.SigningIdentity
+	timeout time.Duration
+}
+
+//WithTimeout timeout of every discovery request
+func WithTimeout(timeout time.Duration) func(*Client) {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+//NewClient new discovery client, send requests to peerClient signed by signer
+// Opt timeout of the requests, DefaultTimeout if not set
+func NewClient(peerClient *client.PeerClient, signer msp.SigningIdentity, Opt ...func(*Client)) *Client {
+	c := &Client{peer: peerClient, signer: signer, timeout: DefaultTimeout}
+	for oi := range Opt {
+		Opt[oi](c)
+	}
+	return c
+}
+
+//Peers peers of the channel
+func (c *Client) Peers(channelID string) ([]*Peer, error) {
+	r, err := c.query(&dp.Query{Channel: channelID, Query: &dp.Query_PeerQuery{PeerQuery: &dp.PeerMembershipQuery{}}})
+	if err != nil {
+		return nil, err
+	}
+
+	members, ok := r.Result.(*dp.QueryResult_Members)
+	if !ok {
+		return nil, fmt.Errorf("unexpected result type: %T", r.Result)
+	}
+
+	return parsePeersByOrg(members.Members.PeersByOrg)
+}
+
+//LocalPeers peers known by the peer, only for admins of the peer
+func (c *Client) LocalPeers() ([]*Peer, error) {
+	r, err := c.query(&dp.Query{Query: &dp.Query_LocalPeers{LocalPeers: &dp.LocalPeerQuery{}}})
+	if err != nil {
+		return nil, err
+	}
+
+	members, ok := r.Result.(*dp.QueryResult_Members)
+	if !ok {
+		return nil, fmt.Errorf("unexpected result type: %T", r.Result)
+	}
+
+	return parsePeersByOrg(members.Members.PeersByOrg)
+}
+
+//Config msps and orderers of the channel
+func (c *Client) Config(channelID string) (*Config, error) {
+	r, err := c.query(&dp.Query{Channel: channelID, Query: &dp.Query_ConfigQuery{ConfigQuery: &dp.ConfigQuery{}}})
+	if err != nil {
+		return nil, err
+	}
+
+	config, ok := r.Result.(*dp.QueryResult_ConfigResult)
+	if !ok {
+		return nil, fmt.Errorf("unexpected result type: %T", r.Result)
+	}
+
+	conf := &Config{MSPs: config.ConfigResult.Msps, Orderers: map[string][]string{}}
+	for mspID, endpoints := range config.ConfigResult.Orderers {
+		for _, e := range endpoints.Endpoint {
+			conf.Orderers[mspID] = append(conf.Orderers[mspID], net.JoinHostPort(e.Host, strconv.Itoa(int(e.Port))))
+		}
+	}
+
+	return conf, nil
+}
+
+//Endorsers endorsers of a chaincode invocation
+// calls the invoked chaincode, with collections it reads or writes,
+// followed by chaincodes it calls (chaincode to chaincode)
+// eg: &peer.ChaincodeCall{Name: "basic", CollectionNames: []string{"collection1"}}
+func (c *Client) Endorsers(channelID string, calls ...*peer.ChaincodeCall) (*EndorsementDescriptor, error) {
+	if len(calls) == 0 {
+		return nil, fmt.Errorf("no chaincode call")
+	}
+
+	r, err := c.query(&dp.Query{
+		Channel: channelID,
+		Query: &dp.Query_CcQuery{CcQuery: &dp.ChaincodeQuery{
+			Interests: []*peer.ChaincodeInterest{{Chaincodes: calls}},
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res, ok := r.Result.(*dp.QueryResult_CcQueryRes)
+	if !ok {
+		return nil, fmt.Errorf("unexpected result type: %T", r.Result)
+	}
+
+	if len(res.CcQueryRes.Content) == 0 {
+		return nil, fmt.Errorf("no endorsement descriptor of chaincode [%s]", calls[0].Name)
+	}
+
+	desc := res.CcQueryRes.Content[0]
+	ed := &EndorsementDescriptor{Chaincode: desc.Chaincode, EndorsersByGroups: map[string][]*Peer{}}
+
+	for group, peers := range desc.EndorsersByGroups {
+		for _, p := range peers.Peers {
+			pp, err := parsePeer(p)
+			if err != nil {
+				return nil, err
+			}
+			ed.EndorsersByGroups[group] = append(ed.EndorsersByGroups[group], pp)
+		}
+	}
+
+	for _, l := range desc.Layouts {
+		layout := Layout{}
+		for group, quantity := range l.QuantitiesByGroup {
+			layout[group] = int(quantity)
+		}
+		ed.Layouts = append(ed.Layouts, layout)
+	}
+
+	return ed, nil
+}
+
+// query send one query, return its result
+func (c *Client) query(q *dp.Query) (*dp.QueryResult, error) {
+	results, err := c.send(q)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) != 1 {
+		return nil, fmt.Errorf("expected 1 result, got %d", len(results))
+	}
+
+	if e, ok := results[0].Result.(*dp.QueryResult_Error); ok {
+		return nil, fmt.Errorf("discovery query failed: %s", e.Error.Content)
+	}
+
+	return results[0], nil
+}
+
+// send sign and send queries, return results in the same order of the queries
+func (c *Client) send(queries ...*dp.Query) ([]*dp.QueryResult, error) {
+	creator, err := c.signer.Serialize()
+	if err != nil {
+		return nil, fmt.Errorf("serialize signer failed: %v", err)
+	}
+
+	auth := &dp.AuthInfo{ClientIdentity: creator}
+	// required by the peer if the client tls certificate is used
+	if cert := c.peer.Certificate(); len(cert.Certificate) > 0 {
+		auth.ClientTlsCertHash = util.ComputeSHA256(cert.Certificate[0])
+	}
+
+	payload, err := proto.Marshal(&dp.Request{Authentication: auth, Queries: queries})
+	if err != nil {
+		return nil, fmt.Errorf("marshal discovery request failed: %v", err)
+	}
+
+	sig, err := c.signer.Sign(payload)
+	if err != nil {
+		return nil, fmt.Errorf("sign discovery request failed: %v", err)
+	}
+
+	dc, err := c.peer.Discovery()
+	if err != nil {
+		return nil, fmt.Errorf("get discovery client failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	resp, err := dc.Discover(ctx, &dp.SignedRequest{Payload: payload, Signature: sig})
+	if err != nil {
+		return nil, fmt.Errorf("discover failed: %v", err)
+	}
+
+	if len(resp.Results) != len(queries) {
+		return nil, fmt.Errorf("expected %d results, got %d", len(queries), len(resp.Results))
+	}
+
+	return resp.Results, nil
+}
+
+func parsePeersByOrg(peersByOrg map[string]*dp.Peers) ([]*Peer, error) {
+	var peers []*Peer
+	for _, ps := range peersByOrg {
+		for _, p := range ps.Peers {
+			pp, err := parsePeer(p)
+			if err != nil {
+				return nil, err
+			}
+			peers = append(peers, pp)
+		}
+	}
+	return peers, nil
+}
+
+func parsePeer(p *dp.Peer) (*Peer, error) {
+	sid := &mb.SerializedIdentity{}
+	if err := proto.Unmarshal(p.Identity, sid); err != nil {
+		return nil, fmt.Errorf("unmarshal peer identity failed: %v", err)
+	}
+
+	peer := &Peer{MSPID: sid.Mspid, Identity: p.Identity}
+
+	if p.MembershipInfo != nil {
+		msg := &gossip.GossipMessage{}
+		if err := proto.Unmarshal(p.MembershipInfo.Payload, msg); err != nil {
+			return nil, fmt.Errorf("unmarshal membership info failed: %v", err)
+		}
+
+		alive := msg.GetAliveMsg()
+		if alive == nil || alive.Membership == nil {
+			return nil, fmt.Errorf("membership info of peer [%s] is not a alive message", sid.Mspid)
+		}
+		peer.Endpoint = alive.Membership.Endpoint
+	}
+
+	if p.StateInfo != nil {
+		msg := &gossip.GossipMessage{}
+		if err := proto.Unmarshal(p.StateInfo.Payload, msg); err != nil {
+			return nil, fmt.Errorf("unmarshal state info failed: %v", err)
+		}
+
+		if state := msg.GetStateInfo(); state != nil && state.Properties != nil {
+			peer.LedgerHeight = state.Properties.LedgerHeight
+			for _, cc := range state.Properties.Chaincodes {
+				peer.Chaincodes = append(peer.Chaincodes, Chaincode{Name: cc.Name, Version: cc.Version})
+			}
+		}
+	}
+
+	return peer, nil
+}
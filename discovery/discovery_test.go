@@ -0,0 +1,241 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/Asutorufa/fabricsdk/internal/testutil"
+	"github.com/golang/protobuf/proto"
+	dp "github.com/hyperledger/fabric-protos-go/discovery"
+	"github.com/hyperledger/fabric-protos-go/gossip"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/msp"
+	"google.golang.org/grpc"
+)
+
+func gossipEnvelope(t *testing.T, msg *gossip.GossipMessage) *gossip.Envelope {
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &gossip.Envelope{Payload: payload}
+}
+
+func discoveredPeer(t *testing.T, mspID, endpoint string, height uint64) *dp.Peer {
+	identity, err := proto.Marshal(&mb.SerializedIdentity{Mspid: mspID, IdBytes: []byte(endpoint)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &dp.Peer{
+		Identity: identity,
+		MembershipInfo: gossipEnvelope(t, &gossip.GossipMessage{Content: &gossip.GossipMessage_AliveMsg{
+			AliveMsg: &gossip.AliveMessage{Membership: &gossip.Member{Endpoint: endpoint}},
+		}}),
+		StateInfo: gossipEnvelope(t, &gossip.GossipMessage{Content: &gossip.GossipMessage_StateInfo{
+			StateInfo: &gossip.StateInfo{Properties: &gossip.Properties{
+				LedgerHeight: height,
+				Chaincodes:   []*gossip.Chaincode{{Name: "basic", Version: "1.0"}},
+			}},
+		}}),
+	}
+}
+
+type fakeDiscovery struct {
+	t      *testing.T
+	signer msp.SigningIdentity
+	// block requests are not answered until the client gives up
+	block bool
+}
+
+func (f *fakeDiscovery) Discover(ctx context.Context, sr *dp.SignedRequest) (*dp.Response, error) {
+	if f.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	if err := f.signer.Verify(sr.Payload, sr.Signature); err != nil {
+		return nil, fmt.Errorf("verify signature failed: %v", err)
+	}
+
+	req := &dp.Request{}
+	if err := proto.Unmarshal(sr.Payload, req); err != nil {
+		return nil, err
+	}
+
+	creator, _ := f.signer.Serialize()
+	if !bytes.Equal(req.Authentication.ClientIdentity, creator) {
+		return nil, fmt.Errorf("unexpected client identity")
+	}
+
+	org1 := discoveredPeer(f.t, "Org1MSP", "peer0.org1.example.com:7051", 10)
+	org2 := discoveredPeer(f.t, "Org2MSP", "peer0.org2.example.com:9051", 9)
+
+	resp := &dp.Response{}
+	for _, q := range req.Queries {
+		var result *dp.QueryResult
+		switch query := q.Query.(type) {
+		case *dp.Query_PeerQuery:
+			result = &dp.QueryResult{Result: &dp.QueryResult_Members{Members: &dp.PeerMembershipResult{
+				PeersByOrg: map[string]*dp.Peers{"Org1MSP": {Peers: []*dp.Peer{org1}}, "Org2MSP": {Peers: []*dp.Peer{org2}}},
+			}}}
+		case *dp.Query_ConfigQuery:
+			result = &dp.QueryResult{Result: &dp.QueryResult_ConfigResult{ConfigResult: &dp.ConfigResult{
+				Msps: map[string]*mb.FabricMSPConfig{"Org1MSP": {Name: "Org1MSP", TlsRootCerts: [][]byte{[]byte("tlsca")}}},
+				Orderers: map[string]*dp.Endpoints{
+					"OrdererMSP": {Endpoint: []*dp.Endpoint{{Host: "orderer.example.com", Port: 7050}}},
+				},
+			}}}
+		case *dp.Query_CcQuery:
+			calls := query.CcQuery.Interests[0].Chaincodes
+			if calls[0].Name != "basic" {
+				result = &dp.QueryResult{Result: &dp.QueryResult_Error{Error: &dp.Error{Content: "chaincode not found"}}}
+				break
+			}
+
+			layouts := []*dp.Layout{{QuantitiesByGroup: map[string]uint32{"G0": 1, "G1": 1}}}
+			// the collection is only for org1
+			if len(calls[0].CollectionNames) != 0 {
+				layouts = []*dp.Layout{{QuantitiesByGroup: map[string]uint32{"G0": 1}}}
+			}
+
+			result = &dp.QueryResult{Result: &dp.QueryResult_CcQueryRes{CcQueryRes: &dp.ChaincodeQueryResult{
+				Content: []*dp.EndorsementDescriptor{{
+					Chaincode:         calls[0].Name,
+					EndorsersByGroups: map[string]*dp.Peers{"G0": {Peers: []*dp.Peer{org1}}, "G1": {Peers: []*dp.Peer{org2}}},
+					Layouts:           layouts,
+				}},
+			}}}
+		default:
+			result = &dp.QueryResult{Result: &dp.QueryResult_Error{Error: &dp.Error{Content: "access denied"}}}
+		}
+		resp.Results = append(resp.Results, result)
+	}
+
+	return resp, nil
+}
+
+func newTestClient(t *testing.T) *Client {
+	signer := testutil.NewSigner(t, "Org1MSP", "User1")
+
+	s := grpc.NewServer()
+	dp.RegisterDiscoveryServer(s, &fakeDiscovery{t: t, signer: signer})
+	return NewClient(testutil.NewPeerClient(t, testutil.Serve(t, s)), signer)
+}
+
+func TestPeers(t *testing.T) {
+	c := newTestClient(t)
+
+	peers, err := c.Peers("mychannel")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Slice(peers, func(i, j int) bool { return peers[i].MSPID < peers[j].MSPID })
+	if len(peers) != 2 || peers[0].MSPID != "Org1MSP" || peers[0].Endpoint != "peer0.org1.example.com:7051" ||
+		peers[0].LedgerHeight != 10 || len(peers[0].Chaincodes) != 1 || peers[0].Chaincodes[0].Name != "basic" {
+		t.Errorf("peers: %+v", peers)
+	}
+
+	if _, err = c.LocalPeers(); err == nil {
+		t.Error("local peers of non admin")
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	signer := testutil.NewSigner(t, "Org1MSP", "User1")
+
+	s := grpc.NewServer()
+	dp.RegisterDiscoveryServer(s, &fakeDiscovery{t: t, signer: signer, block: true})
+	c := NewClient(testutil.NewPeerClient(t, testutil.Serve(t, s)), signer, WithTimeout(100*time.Millisecond))
+
+	start := time.Now()
+	if _, err := c.Peers("mychannel"); err == nil || !strings.Contains(err.Error(), "DeadlineExceeded") {
+		t.Errorf("peers of a blocked peer: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("timeout is not used, returned after %v", elapsed)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	c := newTestClient(t)
+
+	config, err := c.Config("mychannel")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if o := config.Orderers["OrdererMSP"]; len(o) != 1 || o[0] != "orderer.example.com:7050" {
+		t.Errorf("orderers: %v", config.Orderers)
+	}
+
+	if string(config.TLSRootCerts("Org1MSP")) != "tlsca" {
+		t.Errorf("tls root certs: %s", config.TLSRootCerts("Org1MSP"))
+	}
+}
+
+func TestEndorsers(t *testing.T) {
+	c := newTestClient(t)
+
+	desc, err := c.Endorsers("mychannel", &peer.ChaincodeCall{Name: "basic"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(desc.Layouts) != 1 || desc.Layouts[0]["G0"] != 1 || desc.Layouts[0]["G1"] != 1 {
+		t.Errorf("layouts: %v", desc.Layouts)
+	}
+	if len(desc.EndorsersByGroups["G1"]) != 1 || desc.EndorsersByGroups["G1"][0].MSPID != "Org2MSP" {
+		t.Errorf("endorsers: %v", desc.EndorsersByGroups)
+	}
+
+	desc, err = c.Endorsers("mychannel", &peer.ChaincodeCall{Name: "basic", CollectionNames: []string{"org1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(desc.Layouts) != 1 || len(desc.Layouts[0]) != 1 {
+		t.Errorf("collection layouts: %v", desc.Layouts)
+	}
+
+	if _, err = c.Endorsers("mychannel", &peer.ChaincodeCall{Name: "unknown"}); err == nil {
+		t.Error("endorsers of unknown chaincode")
+	}
+}
+
+func TestEndorsementDescriptorPlan(t *testing.T) {
+	c := newTestClient(t)
+
+	desc, err := c.Endorsers("mychannel", &peer.ChaincodeCall{Name: "basic"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := desc.Plan(func(p *Peer) (*client.PeerClient, error) {
+		if p.MSPID != "Org1MSP" {
+			return nil, fmt.Errorf("unreachable")
+		}
+		return c.peer, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan.Groups["G0"]) != 1 || len(plan.Groups["G1"]) != 0 {
+		t.Errorf("groups: %v", plan.Groups)
+	}
+	if len(plan.Layouts) != 1 || plan.Layouts[0]["G1"] != 1 {
+		t.Errorf("layouts: %v", plan.Layouts)
+	}
+
+	if _, err = desc.Plan(func(*Peer) (*client.PeerClient, error) { return nil, fmt.Errorf("unreachable") }); err == nil {
+		t.Error("plan without endorsers")
+	}
+}
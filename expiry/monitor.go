@@ -0,0 +1,276 @@
+package expiry
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Asutorufa/fabricsdk/chaincode"
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/golang/protobuf/proto"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric/common/metrics"
+	"github.com/hyperledger/fabric/common/metrics/disabled"
+	"github.com/hyperledger/fabric/msp"
+)
+
+//Kind certificate kind
+type Kind string
+
+//certificate kinds
+const (
+	KindSigner    Kind = "signer"
+	KindCA        Kind = "ca"
+	KindTLSClient Kind = "tls_client"
+	KindTLSCA     Kind = "tls_ca"
+)
+
+var daysToExpiryGaugeOpts = metrics.GaugeOpts{
+	Namespace:    "fabricsdk",
+	Subsystem:    "certificate",
+	Name:         "days_to_expiry",
+	Help:         "Days until the soonest expiring certificate of the name and kind expires, negative if expired.",
+	LabelNames:   []string{"name", "kind"},
+	StatsdFormat: "%{#fqname}.%{name}.%{kind}",
+}
+
+//Status expiry status of a certificate
+type Status struct {
+	// Name eg: msp id of signer, peer address of tls certificate
+	Name         string
+	Kind         Kind
+	Subject      string
+	NotAfter     time.Time
+	DaysToExpiry int
+	Expired      bool
+}
+
+type cert struct {
+	name string
+	kind Kind
+	cert *x509.Certificate
+}
+
+//Monitor certificate expiry monitor
+type Monitor struct {
+	threshold time.Duration
+	callbacks []func(Status)
+	gauge     metrics.Gauge
+
+	mutex sync.Mutex
+	certs map[string]cert
+}
+
+//WithCallback called for every certificate which expires within the threshold, or is expired
+func WithCallback(f func(Status)) func(*Monitor) {
+	return func(m *Monitor) {
+		m.callbacks = append(m.callbacks, f)
+	}
+}
+
+//WithMetricsProvider report days to expiry of every certificate as gauge
+func WithMetricsProvider(p metrics.Provider) func(*Monitor) {
+	return func(m *Monitor) {
+		m.gauge = p.NewGauge(daysToExpiryGaugeOpts)
+	}
+}
+
+//NewMonitor new monitor
+// threshold callbacks are called for certificates which expire within threshold
+func NewMonitor(threshold time.Duration, Opt ...func(*Monitor)) *Monitor {
+	m := &Monitor{
+		threshold: threshold,
+		gauge:     (&disabled.Provider{}).NewGauge(daysToExpiryGaugeOpts),
+		certs:     map[string]cert{},
+	}
+
+	for oi := range Opt {
+		Opt[oi](m)
+	}
+
+	return m
+}
+
+//AddPEM add pem certificates
+func (m *Monitor) AddPEM(name string, kind Kind, data []byte) error {
+	var found bool
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("parse certificate of [%s] failed: %v", name, err)
+		}
+
+		m.mutex.Lock()
+		m.certs[fmt.Sprintf("%s/%s/%x", kind, name, c.SerialNumber)] = cert{name: name, kind: kind, cert: c}
+		m.mutex.Unlock()
+		found = true
+	}
+
+	if !found {
+		return fmt.Errorf("no certificate found for [%s]", name)
+	}
+	return nil
+}
+
+//AddSigner add certificate of a x509 signer, eg: from chaincode.GetSigner
+func (m *Monitor) AddSigner(signer msp.SigningIdentity) error {
+	data, err := signer.Serialize()
+	if err != nil {
+		return fmt.Errorf("serialize signer failed: %v", err)
+	}
+
+	sid := &mb.SerializedIdentity{}
+	if err = proto.Unmarshal(data, sid); err != nil {
+		return fmt.Errorf("unmarshal serialized identity failed: %v", err)
+	}
+
+	return m.AddPEM(sid.Mspid, KindSigner, sid.IdBytes)
+}
+
+//AddTLSOpt add tls client certificate and tls ca certificate of a endpoint
+func (m *Monitor) AddTLSOpt(address string, opt chaincode.GrpcTLSOpt) error {
+	if len(opt.ClientCrt) != 0 {
+		if err := m.AddPEM(address, KindTLSClient, opt.ClientCrt); err != nil {
+			return err
+		}
+	}
+
+	if len(opt.Ca) != 0 {
+		if err := m.AddPEM(address, KindTLSCA, opt.Ca); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//AddMSPDir add ca and intermediate certificates of a msp directory as KindCA,
+// tls ca and tls intermediate certificates as KindTLSCA
+func (m *Monitor) AddMSPDir(mspID, mspPath string) error {
+	mspConfig, err := msp.GetVerifyingMspConfig(mspPath, mspID, msp.ProviderTypeToString(msp.FABRIC))
+	if err != nil {
+		return fmt.Errorf("get msp config of [%s] failed: %v", mspID, err)
+	}
+
+	conf := &mb.FabricMSPConfig{}
+	if err = proto.Unmarshal(mspConfig.Config, conf); err != nil {
+		return fmt.Errorf("unmarshal msp config of [%s] failed: %v", mspID, err)
+	}
+
+	for _, certs := range []struct {
+		kind Kind
+		pems [][]byte
+	}{
+		{KindCA, conf.RootCerts},
+		{KindCA, conf.IntermediateCerts},
+		{KindTLSCA, conf.TlsRootCerts},
+		{KindTLSCA, conf.TlsIntermediateCerts},
+	} {
+		for _, data := range certs.pems {
+			if err = m.AddPEM(mspID, certs.kind, data); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//AddGroup add tls certificates of all endpoints and all signers of the group,
+// ca certificates of signers loaded from msp directories, see AddMSPDir
+func (m *Monitor) AddGroup(g *client.Group) error {
+	for _, e := range g.GetEndpoints() {
+		if err := m.AddTLSOpt(e.Address, chaincode.GrpcTLSOpt(e.GrpcTLSOpt)); err != nil {
+			return err
+		}
+	}
+
+	for _, s := range g.GetSigners() {
+		if err := m.AddSigner(s); err != nil {
+			return err
+		}
+	}
+
+	for mspID, mspPath := range g.GetMSPPaths() {
+		if err := m.AddMSPDir(mspID, mspPath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//Check check all certificates, sorted by expiry time
+// update metrics and call callbacks for certificates which expire within threshold,
+// the gauge of a name and kind is of its soonest expiring certificate
+func (m *Monitor) Check(now time.Time) []Status {
+	m.mutex.Lock()
+	var status []Status
+	for _, c := range m.certs {
+		left := c.cert.NotAfter.Sub(now)
+		status = append(status, Status{
+			Name:         c.name,
+			Kind:         c.kind,
+			Subject:      c.cert.Subject.String(),
+			NotAfter:     c.cert.NotAfter,
+			DaysToExpiry: int(left / (24 * time.Hour)),
+			Expired:      left < 0,
+		})
+	}
+	m.mutex.Unlock()
+
+	sort.Slice(status, func(i, j int) bool { return status[i].NotAfter.Before(status[j].NotAfter) })
+
+	reported := map[string]bool{}
+	for _, s := range status {
+		// status are sorted, the first one of a name and kind expires soonest
+		if key := string(s.Kind) + "/" + s.Name; !reported[key] {
+			reported[key] = true
+			m.gauge.With("name", s.Name, "kind", string(s.Kind)).Set(float64(s.DaysToExpiry))
+		}
+
+		if s.NotAfter.Sub(now) > m.threshold {
+			continue
+		}
+
+		for _, f := range m.callbacks {
+			f(s)
+		}
+	}
+
+	return status
+}
+
+//Start check every interval until ctx is done
+func (m *Monitor) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for _, s := range m.Check(time.Now()) {
+			if s.Expired {
+				log.Printf("certificate [%s] of [%s] expired at %v\n", s.Kind, s.Name, s.NotAfter)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
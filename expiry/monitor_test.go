@@ -0,0 +1,137 @@
+package expiry
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Asutorufa/fabricsdk/chaincode"
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/Asutorufa/fabricsdk/internal/testutil"
+	"github.com/hyperledger/fabric/common/metrics"
+)
+
+type fakeProvider struct {
+	metrics.Provider
+	values map[string]float64
+}
+
+func (f *fakeProvider) NewGauge(metrics.GaugeOpts) metrics.Gauge {
+	return &fakeGauge{values: f.values}
+}
+
+type fakeGauge struct {
+	labels []string
+	values map[string]float64
+}
+
+func (f *fakeGauge) With(labelValues ...string) metrics.Gauge {
+	return &fakeGauge{labels: labelValues, values: f.values}
+}
+
+func (f *fakeGauge) Add(delta float64) {
+	f.values[strings.Join(f.labels, ",")] += delta
+}
+
+func (f *fakeGauge) Set(value float64) {
+	f.values[strings.Join(f.labels, ",")] = value
+}
+
+func TestMonitor(t *testing.T) {
+	now := time.Now()
+
+	past := now.Add(-365 * 24 * time.Hour)
+
+	// the msp of a signer requires its ca
+	mspCA, mspCAKey := testutil.NewCA(t, "ca", past, now.Add(365*24*time.Hour))
+	signerCert, signerKey := testutil.IssueCert(t, "User1@org1.example.com", past, now.Add(10*24*time.Hour+time.Hour), mspCA, mspCAKey)
+	signer, err := chaincode.GetSignerFromPEM("Org1MSP", testutil.CertPEM(signerCert), testutil.KeyPEM(t, signerKey), testutil.CertPEM(mspCA))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tlsCert, _ := testutil.NewCA(t, "client", past, now.Add(-time.Hour))
+	caCert, _ := testutil.NewCA(t, "tlsca", past, now.Add(365*24*time.Hour+time.Hour))
+
+	var warned []Status
+	provider := &fakeProvider{values: map[string]float64{}}
+	m := NewMonitor(30*24*time.Hour, WithCallback(func(s Status) { warned = append(warned, s) }), WithMetricsProvider(provider))
+
+	if err = m.AddSigner(signer); err != nil {
+		t.Fatal(err)
+	}
+	err = m.AddTLSOpt("127.0.0.1:7051", chaincode.GrpcTLSOpt{ClientCrt: testutil.CertPEM(tlsCert), Ca: testutil.CertPEM(caCert)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status := m.Check(now)
+	if len(status) != 3 {
+		t.Fatalf("status: %v", status)
+	}
+
+	if status[0].Kind != KindTLSClient || !status[0].Expired {
+		t.Errorf("first status: %v", status[0])
+	}
+	if status[1].Kind != KindSigner || status[1].Name != "Org1MSP" || status[1].DaysToExpiry != 10 {
+		t.Errorf("second status: %v", status[1])
+	}
+
+	if len(warned) != 2 {
+		t.Errorf("warned: %v", warned)
+	}
+
+	if provider.values["name,127.0.0.1:7051,kind,tls_ca"] != 365 {
+		t.Errorf("metrics: %v", provider.values)
+	}
+}
+
+func TestMonitorChainMetrics(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-365 * 24 * time.Hour)
+
+	// a tls ca bundle, the intermediate expires before the root
+	root, rootKey := testutil.NewCA(t, "tlsca", past, now.Add(300*24*time.Hour+time.Hour))
+	intermediate, _ := testutil.IssueCert(t, "tlsica", past, now.Add(20*24*time.Hour+time.Hour), root, rootKey)
+
+	provider := &fakeProvider{values: map[string]float64{}}
+	m := NewMonitor(30*24*time.Hour, WithMetricsProvider(provider))
+	bundle := append(testutil.CertPEM(intermediate), testutil.CertPEM(root)...)
+	if err := m.AddPEM("127.0.0.1:7051", KindTLSCA, bundle); err != nil {
+		t.Fatal(err)
+	}
+
+	if status := m.Check(now); len(status) != 2 {
+		t.Fatalf("status: %v", status)
+	}
+	if provider.values["name,127.0.0.1:7051,kind,tls_ca"] != 20 {
+		t.Errorf("metrics: %v, want the soonest expiring certificate", provider.values)
+	}
+}
+
+func TestMonitorGroupCA(t *testing.T) {
+	dir := t.TempDir()
+	id := testutil.NewIdentity(t, "User1@org1.example.com")
+	testutil.WriteMSP(t, dir, id, nil)
+
+	g := client.NewGroup()
+	if err := g.AddSigner("Org1MSP", dir); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewMonitor(30 * 24 * time.Hour)
+	if err := m.AddGroup(g); err != nil {
+		t.Fatal(err)
+	}
+
+	kinds := map[Kind]int{}
+	for _, s := range m.Check(time.Now()) {
+		if s.Name != "Org1MSP" {
+			t.Errorf("status: %v", s)
+		}
+		kinds[s.Kind]++
+	}
+	if kinds[KindSigner] != 1 || kinds[KindCA] != 1 {
+		t.Errorf("kinds: %v", kinds)
+	}
+}
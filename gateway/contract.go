@@ -0,0 +1,118 @@
+package gateway
+
+import (
+	"fmt"
+
+	"github.com/Asutorufa/fabricsdk/chaincode"
+)
+
+//Contract a chaincode on the network
+type Contract struct {
+	network   *Network
+	chaincode chaincode.ChainOpt
+}
+
+//Name chaincode name
+func (c *Contract) Name() string {
+	return c.chaincode.Name
+}
+
+//Submit submit a transaction to the ledger, return the chaincode response payload
+// the transaction is endorsed by all peers of the gateway, sent to the orderers,
+// and returns after it is committed
+func (c *Contract) Submit(fn string, args ...string) ([]byte, error) {
+	return c.NewTransaction(fn).Submit(args...)
+}
+
+//Evaluate evaluate a transaction function, return the chaincode response payload,
+// the result is not sent to the orderers
+func (c *Contract) Evaluate(fn string, args ...string) ([]byte, error) {
+	return c.NewTransaction(fn).Evaluate(args...)
+}
+
+//Transaction a chaincode function invocation
+type Transaction struct {
+	contract  *Contract
+	fn        string
+	transient map[string][]byte
+}
+
+//WithTransient transient data, eg: private data
+func WithTransient(transient map[string][]byte) func(*Transaction) {
+	return func(t *Transaction) {
+		t.transient = transient
+	}
+}
+
+//NewTransaction new transaction of a chaincode function
+func (c *Contract) NewTransaction(fn string, Opt ...func(*Transaction)) *Transaction {
+	t := &Transaction{contract: c, fn: fn}
+
+	for oi := range Opt {
+		Opt[oi](t)
+	}
+
+	return t
+}
+
+func (t *Transaction) args(args []string) [][]byte {
+	data := make([][]byte, 0, len(args)+1)
+	data = append(data, []byte(t.fn))
+	for i := range args {
+		data = append(data, []byte(args[i]))
+	}
+	return data
+}
+
+//Submit submit the transaction, see Contract.Submit
+func (t *Transaction) Submit(args ...string) ([]byte, error) {
+	n := t.contract.network
+	g := n.gateway
+
+	if g.gatewayPeer != nil {
+		return t.submitGatewayPeer(args)
+	}
+
+	peers := g.group.GetPeerClients()
+	if len(peers) == 0 {
+		return nil, fmt.Errorf("no peer in gateway")
+	}
+
+	orderers := g.group.GetOrderersClients()
+	if len(orderers) == 0 {
+		return nil, fmt.Errorf("no orderer in gateway")
+	}
+
+	resp, err := chaincode.InternalInvoke(t.contract.chaincode, g.mspOpt(), t.args(args), t.transient, n.channelID, peers, orderers)
+	if err != nil {
+		return nil, fmt.Errorf("submit transaction [%s] failed: %w", t.fn, err)
+	}
+
+	if resp == nil {
+		return nil, fmt.Errorf("submit transaction [%s] failed: no proposal response", t.fn)
+	}
+
+	return resp.Response.Payload, nil
+}
+
+//Evaluate evaluate the transaction, see Contract.Evaluate
+func (t *Transaction) Evaluate(args ...string) ([]byte, error) {
+	n := t.contract.network
+	g := n.gateway
+
+	if g.gatewayPeer != nil {
+		return t.evaluateGatewayPeer(args)
+	}
+
+	peers := g.group.GetPeerClients()
+	if len(peers) == 0 {
+		return nil, fmt.Errorf("no peer in gateway")
+	}
+
+	resp, err := chaincode.InternalQuery(t.contract.chaincode, g.mspOpt(), t.args(args), t.transient, n.channelID, peers)
+	if err != nil {
+		return nil, fmt.Errorf("evaluate transaction [%s] failed: %w", t.fn, err)
+	}
+
+	return resp.Response.Payload, nil
+}
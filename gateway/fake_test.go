@@ -0,0 +1,354 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/Asutorufa/fabricsdk/internal/testutil"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/common"
+	gp "github.com/hyperledger/fabric-protos-go/gateway"
+	"github.com/hyperledger/fabric-protos-go/orderer"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/msp"
+	"github.com/hyperledger/fabric/protoutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// invocation a chaincode invocation received by the fake peer
+type invocation struct {
+	channelID string
+	txID      string
+	chaincode string
+	args      [][]byte
+	transient map[string][]byte
+}
+
+// fakeNetwork a peer and a orderer in process, without tls
+// every envelope broadcast to the orderer is committed in its own block immediately
+type fakeNetwork struct {
+	endorser msp.SigningIdentity
+	// handler chaincode, return the chaincode response
+	handler func(inv *invocation) *peer.Response
+	// validationCode validation code of committed transactions
+	validationCode peer.TxValidationCode
+	// emptyResults the gateway service responds without results
+	emptyResults bool
+
+	peerAddress    string
+	ordererAddress string
+
+	mutex  sync.Mutex
+	blocks []*peer.FilteredBlock
+	notify chan struct{}
+}
+
+func newFakeNetwork(t *testing.T, handler func(inv *invocation) *peer.Response) *fakeNetwork {
+	f := &fakeNetwork{
+		endorser: testutil.NewSigner(t, "Org1MSP", "peer0.org1.example.com"),
+		handler:  handler,
+		notify:   make(chan struct{}),
+	}
+
+	peerServer := grpc.NewServer()
+	peer.RegisterEndorserServer(peerServer, &fakeEndorser{f})
+	peer.RegisterDeliverServer(peerServer, &fakeDeliver{f})
+	gp.RegisterGatewayServer(peerServer, &fakeGateway{f})
+	f.peerAddress = testutil.Serve(t, peerServer)
+
+	ordererServer := grpc.NewServer()
+	orderer.RegisterAtomicBroadcastServer(ordererServer, &fakeOrderer{f})
+	f.ordererAddress = testutil.Serve(t, ordererServer)
+
+	return f
+}
+
+// group connected group of the fake network
+func (f *fakeNetwork) group(t *testing.T) *client.Group {
+	g := client.NewGroup()
+	if err := g.AddPeerClient(client.Endpoint{Address: f.peerAddress}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddOrdererClient(client.Endpoint{Address: f.ordererAddress}); err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+// setValidationCode validation code of transactions committed after
+func (f *fakeNetwork) setValidationCode(code peer.TxValidationCode) {
+	f.mutex.Lock()
+	f.validationCode = code
+	f.mutex.Unlock()
+}
+
+// setEmptyResults the gateway service responds without results after
+func (f *fakeNetwork) setEmptyResults(empty bool) {
+	f.mutex.Lock()
+	f.emptyResults = empty
+	f.mutex.Unlock()
+}
+
+func (f *fakeNetwork) empty() bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.emptyResults
+}
+
+func (f *fakeNetwork) commit(channelID, txID string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.blocks = append(f.blocks, &peer.FilteredBlock{
+		ChannelId: channelID,
+		Number:    uint64(len(f.blocks)),
+		FilteredTransactions: []*peer.FilteredTransaction{
+			{Txid: txID, Type: common.HeaderType_ENDORSER_TRANSACTION, TxValidationCode: f.validationCode},
+		},
+	})
+	close(f.notify)
+	f.notify = make(chan struct{})
+}
+
+// block get block by number, or a channel closed on next commit
+func (f *fakeNetwork) block(number uint64) (*peer.FilteredBlock, <-chan struct{}) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if number < uint64(len(f.blocks)) {
+		return f.blocks[number], nil
+	}
+	return nil, f.notify
+}
+
+// wait wait for the transaction committed
+func (f *fakeNetwork) wait(ctx context.Context, txID string) (*peer.FilteredTransaction, uint64, error) {
+	for number := uint64(0); ; {
+		block, notify := f.block(number)
+		if block == nil {
+			select {
+			case <-notify:
+				continue
+			case <-ctx.Done():
+				return nil, 0, ctx.Err()
+			}
+		}
+
+		for _, tx := range block.FilteredTransactions {
+			if tx.Txid == txID {
+				return tx, block.Number, nil
+			}
+		}
+		number++
+	}
+}
+
+func (f *fakeNetwork) height() uint64 {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return uint64(len(f.blocks))
+}
+
+type fakeEndorser struct{ f *fakeNetwork }
+
+func (e *fakeEndorser) ProcessProposal(_ context.Context, sp *peer.SignedProposal) (*peer.ProposalResponse, error) {
+	inv, err := testutil.UnmarshalInvocation(sp)
+	if err != nil {
+		return nil, err
+	}
+
+	response := e.f.handler(&invocation{
+		channelID: inv.ChannelHeader.ChannelId,
+		txID:      inv.ChannelHeader.TxId,
+		chaincode: inv.Spec.ChaincodeId.Name,
+		args:      inv.Spec.Input.Args,
+		transient: inv.Payload.TransientMap,
+	})
+
+	resp, err := protoutil.CreateProposalResponse(inv.Proposal.Header, inv.Proposal.Payload, response, nil, nil,
+		&peer.ChaincodeID{Name: inv.Spec.ChaincodeId.Name}, e.f.endorser)
+	if err != nil {
+		return nil, err
+	}
+	resp.Response = response
+	return resp, nil
+}
+
+type fakeDeliver struct{ f *fakeNetwork }
+
+func (d *fakeDeliver) Deliver(peer.Deliver_DeliverServer) error {
+	return nil
+}
+
+func (d *fakeDeliver) DeliverWithPrivateData(peer.Deliver_DeliverWithPrivateDataServer) error {
+	return nil
+}
+
+// DeliverFiltered only newest and specified start positions
+func (d *fakeDeliver) DeliverFiltered(stream peer.Deliver_DeliverFilteredServer) error {
+	env, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	seekInfo := &orderer.SeekInfo{}
+	if _, err = protoutil.UnmarshalEnvelopeOfType(env, common.HeaderType_DELIVER_SEEK_INFO, seekInfo); err != nil {
+		return err
+	}
+
+	var next uint64
+	switch start := seekInfo.Start.Type.(type) {
+	case *orderer.SeekPosition_Newest:
+		if height := d.f.height(); height > 0 {
+			next = height - 1
+		}
+	case *orderer.SeekPosition_Specified:
+		next = start.Specified.Number
+	}
+
+	for {
+		block, notify := d.f.block(next)
+		if block == nil {
+			select {
+			case <-notify:
+				continue
+			case <-stream.Context().Done():
+				return nil
+			}
+		}
+
+		err = stream.Send(&peer.DeliverResponse{Type: &peer.DeliverResponse_FilteredBlock{FilteredBlock: block}})
+		if err != nil {
+			return err
+		}
+		next++
+	}
+}
+
+type fakeOrderer struct{ f *fakeNetwork }
+
+func (o *fakeOrderer) Broadcast(stream orderer.AtomicBroadcast_BroadcastServer) error {
+	for {
+		env, err := stream.Recv()
+		if err != nil {
+			return nil
+		}
+
+		chdr, err := protoutil.ChannelHeader(env)
+		if err != nil {
+			return err
+		}
+
+		o.f.commit(chdr.ChannelId, chdr.TxId)
+
+		if err = stream.Send(&orderer.BroadcastResponse{Status: common.Status_SUCCESS}); err != nil {
+			return err
+		}
+	}
+}
+
+func (o *fakeOrderer) Deliver(orderer.AtomicBroadcast_DeliverServer) error {
+	return nil
+}
+
+// fakeGateway gateway service of the fake peer, endorse by the fake peer only
+type fakeGateway struct{ f *fakeNetwork }
+
+func (g *fakeGateway) endorse(sp *peer.SignedProposal) (*peer.ProposalResponse, error) {
+	resp, err := (&fakeEndorser{g.f}).ProcessProposal(context.Background(), sp)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Response.Status >= 400 {
+		st, _ := status.New(codes.Aborted, "failed to endorse transaction").WithDetails(&gp.ErrorDetail{
+			Address: g.f.peerAddress,
+			MspId:   "Org1MSP",
+			Message: fmt.Sprintf("chaincode response %d, %s", resp.Response.Status, resp.Response.Message),
+		})
+		return nil, st.Err()
+	}
+
+	return resp, nil
+}
+
+func (g *fakeGateway) Evaluate(_ context.Context, req *gp.EvaluateRequest) (*gp.EvaluateResponse, error) {
+	resp, err := g.endorse(req.ProposedTransaction)
+	if err != nil {
+		return nil, err
+	}
+	if g.f.empty() {
+		return &gp.EvaluateResponse{}, nil
+	}
+	return &gp.EvaluateResponse{Result: resp.Response}, nil
+}
+
+// unsignedSigner the gateway returns the transaction unsigned, the client signs it
+type unsignedSigner struct{ creator []byte }
+
+func (u *unsignedSigner) Sign([]byte) ([]byte, error) { return nil, nil }
+func (u *unsignedSigner) Serialize() ([]byte, error)  { return u.creator, nil }
+
+func (g *fakeGateway) Endorse(_ context.Context, req *gp.EndorseRequest) (*gp.EndorseResponse, error) {
+	resp, err := g.endorse(req.ProposedTransaction)
+	if err != nil {
+		return nil, err
+	}
+	if g.f.empty() {
+		return &gp.EndorseResponse{}, nil
+	}
+
+	prop, err := protoutil.UnmarshalProposal(req.ProposedTransaction.ProposalBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	hdr, err := protoutil.UnmarshalHeader(prop.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	shdr, err := protoutil.UnmarshalSignatureHeader(hdr.SignatureHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	env, err := protoutil.CreateSignedTx(prop, &unsignedSigner{creator: shdr.Creator}, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &gp.EndorseResponse{PreparedTransaction: env}, nil
+}
+
+func (g *fakeGateway) Submit(_ context.Context, req *gp.SubmitRequest) (*gp.SubmitResponse, error) {
+	if len(req.PreparedTransaction.Signature) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "transaction is not signed")
+	}
+
+	g.f.commit(req.ChannelId, req.TransactionId)
+	return &gp.SubmitResponse{}, nil
+}
+
+func (g *fakeGateway) CommitStatus(ctx context.Context, req *gp.SignedCommitStatusRequest) (*gp.CommitStatusResponse, error) {
+	r := &gp.CommitStatusRequest{}
+	if err := proto.Unmarshal(req.Request, r); err != nil {
+		return nil, err
+	}
+
+	tx, number, err := g.f.wait(ctx, r.TransactionId)
+	if err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+
+	return &gp.CommitStatusResponse{Result: tx.TxValidationCode, BlockNumber: number}, nil
+}
+
+func (g *fakeGateway) ChaincodeEvents(*gp.SignedChaincodeEventsRequest, gp.Gateway_ChaincodeEventsServer) error {
+	return status.Error(codes.Unimplemented, "chaincode events are not supported")
+}
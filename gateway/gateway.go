@@ -0,0 +1,79 @@
+package gateway
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Asutorufa/fabricsdk/chaincode"
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/hyperledger/fabric/msp"
+)
+
+//Gateway a identity and the peers and orderers it connects to,
+// or a fabric 2.4+ peer which gateway service is used, see ConnectGatewayPeer
+type Gateway struct {
+	signer      msp.SigningIdentity
+	group       *client.Group
+	gatewayPeer *client.PeerClient
+
+	// timeouts of the gateway service calls, see ConnectGatewayPeer
+	evaluateTimeout     time.Duration
+	endorseTimeout      time.Duration
+	submitTimeout       time.Duration
+	commitStatusTimeout time.Duration
+}
+
+//Connect new gateway
+// signer eg: chaincode.GetSignerByOpt, wallet.Wallet.Signer
+// group peers and orderers, all peers are used as endorsers
+func Connect(signer msp.SigningIdentity, group *client.Group) (*Gateway, error) {
+	if signer == nil {
+		return nil, fmt.Errorf("signer is nil")
+	}
+
+	if group == nil {
+		return nil, fmt.Errorf("group is nil")
+	}
+
+	return &Gateway{signer: signer, group: group}, nil
+}
+
+//GetNetwork get network of a channel
+func (g *Gateway) GetNetwork(channelID string) *Network {
+	return &Network{gateway: g, channelID: channelID}
+}
+
+//Signer the identity of the gateway
+func (g *Gateway) Signer() msp.SigningIdentity {
+	return g.signer
+}
+
+//Group the peers and orderers of the gateway, nil if the gateway peer is used
+func (g *Gateway) Group() *client.Group {
+	return g.group
+}
+
+func (g *Gateway) mspOpt() chaincode.MSPOpt {
+	return chaincode.MSPOpt{ID: g.signer.GetMSPIdentifier(), Signer: g.signer}
+}
+
+//Network a channel
+type Network struct {
+	gateway   *Gateway
+	channelID string
+}
+
+//Name channel id
+func (n *Network) Name() string {
+	return n.channelID
+}
+
+//GetContract get contract by chaincode name
+func (n *Network) GetContract(name string) *Contract {
+	return n.GetContractWithOpt(chaincode.NewChainOpt(name))
+}
+
+//GetContractWithOpt get contract by chaincode options, eg: IsInit, Type
+func (n *Network) GetContractWithOpt(opt chaincode.ChainOpt) *Contract {
+	return &Contract{network: n, chaincode: opt}
+}
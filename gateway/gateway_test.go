@@ -0,0 +1,179 @@
+package gateway
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Asutorufa/fabricsdk/chaincode"
+	"github.com/Asutorufa/fabricsdk/internal/testutil"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// kvChaincode set/get chaincode, state is written on endorsement
+func kvChaincode() func(inv *invocation) *peer.Response {
+	var state sync.Map
+	return func(inv *invocation) *peer.Response {
+		switch string(inv.args[0]) {
+		case "set":
+			state.Store(string(inv.args[1]), string(inv.args[2]))
+			return &peer.Response{Status: 200, Payload: inv.args[2]}
+		case "get":
+			v, ok := state.Load(string(inv.args[1]))
+			if !ok {
+				return &peer.Response{Status: 404, Message: "not found"}
+			}
+			return &peer.Response{Status: 200, Payload: []byte(v.(string))}
+		case "transient":
+			return &peer.Response{Status: 200, Payload: inv.transient["secret"]}
+		}
+		return &peer.Response{Status: 500, Message: "unknown function"}
+	}
+}
+
+func newTestContract(t *testing.T, f *fakeNetwork) *Contract {
+	gw, err := Connect(testutil.NewSigner(t, "Org1MSP", "User1@org1.example.com"), f.group(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gw.GetNetwork("mychannel").GetContract("basic")
+}
+
+func TestContract(t *testing.T) {
+	f := newFakeNetwork(t, kvChaincode())
+	contract := newTestContract(t, f)
+
+	result, err := contract.Submit("set", "a", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != "1" {
+		t.Errorf("submit result: %s", result)
+	}
+	if f.height() != 1 {
+		t.Errorf("blocks: %d", f.height())
+	}
+
+	result, err = contract.Evaluate("get", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != "1" {
+		t.Errorf("evaluate result: %s", result)
+	}
+	if f.height() != 1 {
+		t.Error("evaluate is sent to the orderer")
+	}
+
+	if _, err = contract.Evaluate("get", "b"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("evaluate missing key: %v", err)
+	}
+
+	_, err = contract.Submit("unknown")
+	var e *chaincode.EndorseError
+	if !errors.As(err, &e) || !strings.Contains(err.Error(), "unknown function") {
+		t.Errorf("submit unknown function: %v", err)
+	}
+	if f.height() != 1 {
+		t.Error("failed endorsement is sent to the orderer")
+	}
+
+	result, err = contract.NewTransaction("transient", WithTransient(map[string][]byte{"secret": []byte("s")})).Evaluate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != "s" {
+		t.Errorf("transient result: %s", result)
+	}
+}
+
+func TestContractInvalidTransaction(t *testing.T) {
+	f := newFakeNetwork(t, kvChaincode())
+	f.setValidationCode(peer.TxValidationCode_MVCC_READ_CONFLICT)
+	contract := newTestContract(t, f)
+
+	if _, err := contract.Submit("set", "a", "1"); !chaincode.IsConflict(err) {
+		t.Errorf("submit invalid transaction: %v", err)
+	}
+}
+
+func TestGatewayPeer(t *testing.T) {
+	f := newFakeNetwork(t, kvChaincode())
+
+	peerClient := testutil.NewPeerClient(t, f.peerAddress)
+
+	gw, err := ConnectGatewayPeer(testutil.NewSigner(t, "Org1MSP", "User1@org1.example.com"), peerClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contract := gw.GetNetwork("mychannel").GetContract("basic")
+
+	result, err := contract.Submit("set", "a", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != "1" {
+		t.Errorf("submit result: %s", result)
+	}
+	if f.height() != 1 {
+		t.Errorf("blocks: %d", f.height())
+	}
+
+	result, err = contract.Evaluate("get", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != "1" {
+		t.Errorf("evaluate result: %s", result)
+	}
+
+	_, err = contract.Submit("unknown")
+	if err == nil || !strings.Contains(err.Error(), "unknown function") || !strings.Contains(err.Error(), f.peerAddress) {
+		t.Errorf("submit unknown function: %v", err)
+	}
+
+	f.setValidationCode(peer.TxValidationCode_MVCC_READ_CONFLICT)
+	if _, err = contract.Submit("set", "a", "2"); err == nil || !strings.Contains(err.Error(), "MVCC_READ_CONFLICT") {
+		t.Errorf("submit invalid transaction: %v", err)
+	}
+
+	// responses without results are errors, not panics
+	f.setEmptyResults(true)
+	if _, err = contract.Evaluate("get", "a"); err == nil || !strings.Contains(err.Error(), "no result") {
+		t.Errorf("evaluate without result: %v", err)
+	}
+	if _, err = contract.Submit("set", "a", "3"); err == nil || !strings.Contains(err.Error(), "no prepared transaction") {
+		t.Errorf("submit without prepared transaction: %v", err)
+	}
+}
+
+func TestGatewayPeerTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	f := newFakeNetwork(t, func(inv *invocation) *peer.Response {
+		<-release
+		return &peer.Response{Status: 200}
+	})
+
+	peerClient := testutil.NewPeerClient(t, f.peerAddress)
+
+	gw, err := ConnectGatewayPeer(testutil.NewSigner(t, "Org1MSP", "User1@org1.example.com"), peerClient,
+		WithEvaluateTimeout(100*time.Millisecond), WithEndorseTimeout(200*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	contract := gw.GetNetwork("mychannel").GetContract("basic")
+
+	start := time.Now()
+	if _, err = contract.Evaluate("get", "a"); err == nil || !strings.Contains(err.Error(), "deadline") {
+		t.Errorf("evaluate: %v", err)
+	}
+	if _, err = contract.Submit("set", "a", "1"); err == nil || !strings.Contains(err.Error(), "deadline") {
+		t.Errorf("submit: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("timeouts are not used, returned after %v", elapsed)
+	}
+}
@@ -0,0 +1,232 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Asutorufa/fabricsdk/chaincode"
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/golang/protobuf/proto"
+	gp "github.com/hyperledger/fabric-protos-go/gateway"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/msp"
+	"github.com/hyperledger/fabric/protoutil"
+	"google.golang.org/grpc/status"
+)
+
+//DefaultGatewayTimeout default timeout of every gateway service call
+const DefaultGatewayTimeout = time.Minute
+
+//WithEvaluateTimeout timeout of the Evaluate call to the gateway peer
+func WithEvaluateTimeout(timeout time.Duration) func(*Gateway) {
+	return func(g *Gateway) {
+		g.evaluateTimeout = timeout
+	}
+}
+
+//WithEndorseTimeout timeout of the Endorse call to the gateway peer
+func WithEndorseTimeout(timeout time.Duration) func(*Gateway) {
+	return func(g *Gateway) {
+		g.endorseTimeout = timeout
+	}
+}
+
+//WithSubmitTimeout timeout of the Submit call to the gateway peer
+func WithSubmitTimeout(timeout time.Duration) func(*Gateway) {
+	return func(g *Gateway) {
+		g.submitTimeout = timeout
+	}
+}
+
+//WithCommitStatusTimeout timeout of waiting for the commit status from the gateway peer
+func WithCommitStatusTimeout(timeout time.Duration) func(*Gateway) {
+	return func(g *Gateway) {
+		g.commitStatusTimeout = timeout
+	}
+}
+
+//ConnectGatewayPeer new gateway which uses the gateway service of a fabric 2.4+ peer,
+// the peer selects endorsers and submits the transaction to orderers,
+// so only the address of one peer is needed
+// Opt timeouts of the gateway service calls, DefaultGatewayTimeout if not set
+func ConnectGatewayPeer(signer msp.SigningIdentity, peerClient *client.PeerClient, Opt ...func(*Gateway)) (*Gateway, error) {
+	if signer == nil {
+		return nil, fmt.Errorf("signer is nil")
+	}
+
+	if peerClient == nil {
+		return nil, fmt.Errorf("peer client is nil")
+	}
+
+	g := &Gateway{
+		signer:              signer,
+		gatewayPeer:         peerClient,
+		evaluateTimeout:     DefaultGatewayTimeout,
+		endorseTimeout:      DefaultGatewayTimeout,
+		submitTimeout:       DefaultGatewayTimeout,
+		commitStatusTimeout: DefaultGatewayTimeout,
+	}
+	for oi := range Opt {
+		Opt[oi](g)
+	}
+
+	return g, nil
+}
+
+func (t *Transaction) proposal(args []string) (*peer.SignedProposal, string, error) {
+	_, sp, txid, err := chaincode.NewSignedProposal(t.contract.chaincode, t.contract.network.gateway.signer,
+		t.args(args), t.transient, t.contract.network.channelID)
+	return sp, txid, err
+}
+
+func (t *Transaction) evaluateGatewayPeer(args []string) ([]byte, error) {
+	g := t.contract.network.gateway
+
+	gc, err := g.gatewayPeer.Gateway()
+	if err != nil {
+		return nil, fmt.Errorf("get gateway client failed: %v", err)
+	}
+
+	sp, txid, err := t.proposal(args)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), g.evaluateTimeout)
+	defer cancel()
+
+	resp, err := gc.Evaluate(ctx, &gp.EvaluateRequest{
+		TransactionId:       txid,
+		ChannelId:           t.contract.network.channelID,
+		ProposedTransaction: sp,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("evaluate transaction [%s] failed: %v", t.fn, gatewayError(err))
+	}
+
+	if resp.Result == nil {
+		return nil, fmt.Errorf("evaluate transaction [%s] failed: no result from gateway", t.fn)
+	}
+
+	return resp.Result.Payload, nil
+}
+
+func (t *Transaction) submitGatewayPeer(args []string) ([]byte, error) {
+	n := t.contract.network
+	g := n.gateway
+
+	gc, err := g.gatewayPeer.Gateway()
+	if err != nil {
+		return nil, fmt.Errorf("get gateway client failed: %v", err)
+	}
+
+	sp, txid, err := t.proposal(args)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), g.endorseTimeout)
+	endorsed, err := gc.Endorse(ctx, &gp.EndorseRequest{
+		TransactionId:       txid,
+		ChannelId:           n.channelID,
+		ProposedTransaction: sp,
+	})
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("endorse transaction [%s] failed: %v", t.fn, gatewayError(err))
+	}
+
+	if endorsed.PreparedTransaction == nil {
+		return nil, fmt.Errorf("endorse transaction [%s] failed: no prepared transaction from gateway", t.fn)
+	}
+
+	// the prepared transaction is unsigned, sign it by the client
+	env := endorsed.PreparedTransaction
+	env.Signature, err = g.signer.Sign(env.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("sign transaction failed: %v", err)
+	}
+
+	action, err := protoutil.GetActionFromEnvelopeMsg(env)
+	if err != nil {
+		return nil, fmt.Errorf("get chaincode action from transaction failed: %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), g.submitTimeout)
+	_, err = gc.Submit(ctx, &gp.SubmitRequest{
+		TransactionId:       txid,
+		ChannelId:           n.channelID,
+		PreparedTransaction: env,
+	})
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("submit transaction [%s] failed: %v", t.fn, gatewayError(err))
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), g.commitStatusTimeout)
+	defer cancel()
+
+	code, err := commitStatus(ctx, gc, g.signer, n.channelID, txid)
+	if err != nil {
+		return nil, err
+	}
+
+	if code != peer.TxValidationCode_VALID {
+		return nil, fmt.Errorf("transaction [%s] invalidated with status (%s)", txid, code)
+	}
+
+	return action.Response.Payload, nil
+}
+
+// commitStatus wait for the transaction committed, return the validation code
+func commitStatus(ctx context.Context, gc gp.GatewayClient, signer msp.SigningIdentity,
+	channelID, txid string) (peer.TxValidationCode, error) {
+	creator, err := signer.Serialize()
+	if err != nil {
+		return 0, fmt.Errorf("serialize signer failed: %v", err)
+	}
+
+	req, err := proto.Marshal(&gp.CommitStatusRequest{
+		TransactionId: txid,
+		ChannelId:     channelID,
+		Identity:      creator,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("marshal commit status request failed: %v", err)
+	}
+
+	sig, err := signer.Sign(req)
+	if err != nil {
+		return 0, fmt.Errorf("sign commit status request failed: %v", err)
+	}
+
+	resp, err := gc.CommitStatus(ctx, &gp.SignedCommitStatusRequest{Request: req, Signature: sig})
+	if err != nil {
+		return 0, fmt.Errorf("get commit status of [%s] failed: %v", txid, gatewayError(err))
+	}
+
+	return resp.Result, nil
+}
+
+// gatewayError append the endorsing peers' errors of the gateway to the error message
+func gatewayError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	var details []string
+	for _, d := range st.Details() {
+		if e, ok := d.(*gp.ErrorDetail); ok {
+			details = append(details, fmt.Sprintf("%s(%s): %s", e.Address, e.MspId, e.Message))
+		}
+	}
+
+	if len(details) == 0 {
+		return fmt.Errorf("%s", st.Message())
+	}
+
+	return fmt.Errorf("%s [%s]", st.Message(), strings.Join(details, "; "))
+}
@@ -0,0 +1,210 @@
+//Package testutil fixtures shared by tests of the sdk packages: certificates, msp signers and fake grpc servers
+package testutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Asutorufa/fabricsdk/client"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/msp"
+	"github.com/hyperledger/fabric/protoutil"
+	"google.golang.org/grpc"
+)
+
+// serial serial numbers of issued certificates, unique in the test binary
+var serial int64
+
+//NewCA self-signed ca certificate of cn, valid from notBefore to notAfter
+func NewCA(t testing.TB, cn string, notBefore, notAfter time.Time) (*x509.Certificate, *ecdsa.PrivateKey) {
+	return IssueCert(t, cn, notBefore, notAfter, nil, nil)
+}
+
+//IssueCert certificate of cn issued by ca, valid from notBefore to notAfter,
+// a self-signed ca certificate if ca is nil
+func IssueCert(t testing.TB, cn string, notBefore, notAfter time.Time,
+	ca *x509.Certificate, caKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(atomic.AddInt64(&serial, 1)),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	if ca == nil {
+		template.KeyUsage |= x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+		template.BasicConstraintsValid = true
+		template.IsCA = true
+		ca, caKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+//CertPEM pem of cert
+func CertPEM(cert *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+}
+
+//KeyPEM pkcs#8 pem of key
+func KeyPEM(t testing.TB, key *ecdsa.PrivateKey) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+//Identity a signer certificate and its key, issued by a ca of its own
+type Identity struct {
+	Cert *x509.Certificate
+	Key  *ecdsa.PrivateKey
+	CA   *x509.Certificate
+}
+
+//NewIdentity identity of cn valid for an hour around now, the msp of a signer requires its ca
+func NewIdentity(t testing.TB, cn string) *Identity {
+	notBefore, notAfter := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	ca, caKey := NewCA(t, "ca", notBefore, notAfter)
+	cert, key := IssueCert(t, cn, notBefore, notAfter, ca, caKey)
+	return &Identity{Cert: cert, Key: key, CA: ca}
+}
+
+//WriteMSP write id as a msp directory, the signer is the admin too,
+// the key is a legacy encrypted pem if password is not nil
+func WriteMSP(t testing.TB, dir string, id *Identity, password []byte) {
+	keyDER, err := x509.MarshalECPrivateKey(id.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyBlock := &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}
+	if password != nil {
+		if keyBlock, err = x509.EncryptPEMBlock(rand.Reader, "EC PRIVATE KEY", keyDER, password, x509.PEMCipherAES256); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files := map[string][]byte{
+		"cacerts/ca.pem":       CertPEM(id.CA),
+		"admincerts/admin.pem": CertPEM(id.Cert),
+		"signcerts/cert.pem":   CertPEM(id.Cert),
+		"keystore/key_sk":      pem.EncodeToMemory(keyBlock),
+	}
+	for name, data := range files {
+		if err = os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+//NewSigner signing identity of a new identity of cn, loaded from a msp directory of mspID
+func NewSigner(t testing.TB, mspID, cn string) msp.SigningIdentity {
+	dir := t.TempDir()
+	WriteMSP(t, dir, NewIdentity(t, cn), nil)
+
+	signer, err := client.GetMSPSigner(dir, mspID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+//Serve serve s on a random local port until the test ends, return its address
+func Serve(t testing.TB, s *grpc.Server) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+//Invocation a chaincode invocation received by a fake peer
+type Invocation struct {
+	Proposal      *peer.Proposal
+	ChannelHeader *common.ChannelHeader
+	Payload       *peer.ChaincodeProposalPayload
+	Spec          *peer.ChaincodeSpec
+}
+
+//UnmarshalInvocation decode the signed proposal received by a fake peer
+func UnmarshalInvocation(sp *peer.SignedProposal) (*Invocation, error) {
+	prop, err := protoutil.UnmarshalProposal(sp.ProposalBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	hdr, err := protoutil.UnmarshalHeader(prop.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	chdr, err := protoutil.UnmarshalChannelHeader(hdr.ChannelHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	cpp, err := protoutil.UnmarshalChaincodeProposalPayload(prop.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	cis := &peer.ChaincodeInvocationSpec{}
+	if err = proto.Unmarshal(cpp.Input, cis); err != nil {
+		return nil, err
+	}
+
+	return &Invocation{Proposal: prop, ChannelHeader: chdr, Payload: cpp, Spec: cis.ChaincodeSpec}, nil
+}
+
+//NewPeerClient client of the fake peer at address without tls, closed when the test ends
+func NewPeerClient(t testing.TB, address string) *client.PeerClient {
+	pc, err := client.NewPeerClientSelf(address, "", client.WithTimeout(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pc.Close() })
+	return pc
+}
+
+//NewOrdererClient client of the fake orderer at address without tls, closed when the test ends
+func NewOrdererClient(t testing.TB, address string) *client.OrdererClient {
+	oc, err := client.NewOrdererClientSelf(address, "", client.WithTimeout(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { oc.Close() })
+	return oc
+}
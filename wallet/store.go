@@ -0,0 +1,158 @@
+package wallet
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// idSuffix identity file suffix of the node sdk file system wallet
+const idSuffix = ".id"
+
+//FileSystemStore store identities in a directory, one <label>.id file per identity
+type FileSystemStore struct {
+	path string
+}
+
+//NewFileSystemStore new file system store, create the directory if not exist
+func NewFileSystemStore(path string) (*FileSystemStore, error) {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, fmt.Errorf("create wallet directory failed: %v", err)
+	}
+
+	return &FileSystemStore{path: path}, nil
+}
+
+//NewFileSystemWallet new wallet with file system store
+func NewFileSystemWallet(path string) (*Wallet, error) {
+	store, err := NewFileSystemStore(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(store), nil
+}
+
+// file path of the label's identity file, labels are file names, they can't leave the wallet directory
+func (f *FileSystemStore) file(label string) (string, error) {
+	if label == "" || strings.Contains(label, "..") || strings.ContainsAny(label, `/\`) {
+		return "", fmt.Errorf("invalid label [%s]: path separators and \"..\" are not allowed", label)
+	}
+
+	return filepath.Join(f.path, label+idSuffix), nil
+}
+
+//Put write to a temporary file then rename, so a identity file is never half written
+func (f *FileSystemStore) Put(label string, data []byte) error {
+	path, err := f.file(label)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(f.path, "."+label+"-*")
+	if err != nil {
+		return fmt.Errorf("create temporary file failed: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("write temporary file failed: %v", err)
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+//Get read the identity file
+func (f *FileSystemStore) Get(label string) ([]byte, error) {
+	path, err := f.file(label)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+//List list labels of all identity files
+func (f *FileSystemStore) List() ([]string, error) {
+	files, err := ioutil.ReadDir(f.path)
+	if err != nil {
+		return nil, fmt.Errorf("read wallet directory failed: %v", err)
+	}
+
+	var labels []string
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), idSuffix) || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		labels = append(labels, strings.TrimSuffix(file.Name(), idSuffix))
+	}
+	return labels, nil
+}
+
+//Remove remove the identity file
+func (f *FileSystemStore) Remove(label string) error {
+	path, err := f.file(label)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+//InMemoryStore store identities in memory
+type InMemoryStore struct {
+	ids sync.Map
+}
+
+//NewInMemoryWallet new wallet with in-memory store
+func NewInMemoryWallet() *Wallet {
+	return New(&InMemoryStore{})
+}
+
+//Put store a copy of data
+func (m *InMemoryStore) Put(label string, data []byte) error {
+	m.ids.Store(label, append([]byte(nil), data...))
+	return nil
+}
+
+//Get get data by label
+func (m *InMemoryStore) Get(label string) ([]byte, error) {
+	v, ok := m.ids.Load(label)
+	if !ok {
+		return nil, nil
+	}
+	return v.([]byte), nil
+}
+
+//List list all labels, sorted
+func (m *InMemoryStore) List() ([]string, error) {
+	var labels []string
+	m.ids.Range(func(key, value interface{}) bool {
+		labels = append(labels, key.(string))
+		return true
+	})
+	sort.Strings(labels)
+	return labels, nil
+}
+
+//Remove remove by label
+func (m *InMemoryStore) Remove(label string) error {
+	m.ids.Delete(label)
+	return nil
+}
@@ -0,0 +1,159 @@
+package wallet
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Asutorufa/fabricsdk/chaincode"
+	"github.com/hyperledger/fabric/msp"
+)
+
+// x509Type identity type of the node and java sdk
+const x509Type = "X.509"
+
+//Identity a labelled x509 identity in wallet
+type Identity struct {
+	MSPID string
+	// Certificate pem certificate
+	Certificate []byte
+	// PrivateKey pem private key
+	PrivateKey []byte
+	// CACerts pem root and intermediate certificates of the msp, needed by Signer,
+	// the node and java sdk wallets don't store them
+	CACerts []byte
+}
+
+// identityJSON identity format of the node and java sdk wallet
+type identityJSON struct {
+	Credentials struct {
+		Certificate string `json:"certificate"`
+		PrivateKey  string `json:"privateKey"`
+	} `json:"credentials"`
+	MSPID   string `json:"mspId"`
+	Type    string `json:"type"`
+	Version int    `json:"version"`
+	// CACertificates not in the node and java sdk format, they ignore it
+	CACertificates string `json:"caCertificates,omitempty"`
+}
+
+//Signer get msp signer of the identity, see chaincode.GetSignerFromPEM,
+// caCerts are used with CACerts, eg: for identities imported from the node and java sdk wallets
+func (i *Identity) Signer(caCerts ...[]byte) (msp.SigningIdentity, error) {
+	caCerts = append([][]byte{i.CACerts}, caCerts...)
+	roots, _, err := chaincode.SplitCAChain(caCerts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("no root certificate of msp [%s] for the identity, put them in CACerts", i.MSPID)
+	}
+
+	return chaincode.GetSignerFromPEM(i.MSPID, i.Certificate, i.PrivateKey, caCerts...)
+}
+
+//MarshalJSON to the node and java sdk format
+func (i *Identity) MarshalJSON() ([]byte, error) {
+	x := &identityJSON{MSPID: i.MSPID, Type: x509Type, Version: 1}
+	x.Credentials.Certificate = string(i.Certificate)
+	x.Credentials.PrivateKey = string(i.PrivateKey)
+	x.CACertificates = string(i.CACerts)
+	return json.Marshal(x)
+}
+
+//UnmarshalJSON from the node and java sdk format
+func (i *Identity) UnmarshalJSON(data []byte) error {
+	x := &identityJSON{}
+	if err := json.Unmarshal(data, x); err != nil {
+		return err
+	}
+
+	if x.Type != x509Type {
+		return fmt.Errorf("unsupported identity type: %s", x.Type)
+	}
+
+	i.MSPID = x.MSPID
+	i.Certificate = []byte(x.Credentials.Certificate)
+	i.PrivateKey = []byte(x.Credentials.PrivateKey)
+	if x.CACertificates != "" {
+		i.CACerts = []byte(x.CACertificates)
+	}
+	return nil
+}
+
+//Store wallet store, data is the serialized identity
+type Store interface {
+	Put(label string, data []byte) error
+	// Get return nil data, nil error if the label is not exist
+	Get(label string) ([]byte, error)
+	List() ([]string, error)
+	Remove(label string) error
+}
+
+//Wallet identity wallet
+type Wallet struct {
+	store Store
+}
+
+//New new wallet with store
+func New(store Store) *Wallet {
+	return &Wallet{store: store}
+}
+
+//Put put a identity with label, replace if exist
+func (w *Wallet) Put(label string, id *Identity) error {
+	data, err := json.Marshal(id)
+	if err != nil {
+		return fmt.Errorf("marshal identity failed: %v", err)
+	}
+
+	return w.store.Put(label, data)
+}
+
+//Get get a identity by label
+func (w *Wallet) Get(label string) (*Identity, error) {
+	data, err := w.store.Get(label)
+	if err != nil {
+		return nil, fmt.Errorf("get identity [%s] failed: %v", label, err)
+	}
+
+	if data == nil {
+		return nil, fmt.Errorf("identity [%s] is not exist", label)
+	}
+
+	id := &Identity{}
+	if err = json.Unmarshal(data, id); err != nil {
+		return nil, fmt.Errorf("unmarshal identity [%s] failed: %v", label, err)
+	}
+
+	return id, nil
+}
+
+//List list all labels
+func (w *Wallet) List() ([]string, error) {
+	return w.store.List()
+}
+
+//Remove remove a identity by label
+func (w *Wallet) Remove(label string) error {
+	return w.store.Remove(label)
+}
+
+//Signer get signer of the identity by label, for client.Group.AddSignerIdentity, see Identity.Signer
+func (w *Wallet) Signer(label string, caCerts ...[]byte) (msp.SigningIdentity, error) {
+	id, err := w.Get(label)
+	if err != nil {
+		return nil, err
+	}
+
+	return id.Signer(caCerts...)
+}
+
+//MSPOpt get msp options of the identity by label, for all functions which need chaincode.MSPOpt
+func (w *Wallet) MSPOpt(label string, caCerts ...[]byte) (chaincode.MSPOpt, error) {
+	signer, err := w.Signer(label, caCerts...)
+	if err != nil {
+		return chaincode.MSPOpt{}, err
+	}
+
+	return chaincode.MSPOpt{ID: signer.GetMSPIdentifier(), Signer: signer}, nil
+}
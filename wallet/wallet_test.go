@@ -0,0 +1,132 @@
+package wallet
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/Asutorufa/fabricsdk/chaincode"
+	"github.com/Asutorufa/fabricsdk/internal/testutil"
+)
+
+func newIdentity(t *testing.T) *Identity {
+	id := testutil.NewIdentity(t, "User1@org1.example.com")
+	return &Identity{
+		MSPID:       "Org1MSP",
+		Certificate: testutil.CertPEM(id.Cert),
+		PrivateKey:  testutil.KeyPEM(t, id.Key),
+		CACerts:     testutil.CertPEM(id.CA),
+	}
+}
+
+func testWallet(t *testing.T, w *Wallet) {
+	id := newIdentity(t)
+
+	if err := w.Put("user1", id); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Put("user2", newIdentity(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	labels, err := w.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(labels) != 2 || labels[0] != "user1" || labels[1] != "user2" {
+		t.Errorf("labels: %v", labels)
+	}
+
+	got, err := w.Get("user1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.MSPID != id.MSPID || string(got.Certificate) != string(id.Certificate) || string(got.PrivateKey) != string(id.PrivateKey) ||
+		string(got.CACerts) != string(id.CACerts) {
+		t.Errorf("get identity: %v", got)
+	}
+
+	mspOpt, err := w.MSPOpt("user1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := chaincode.GetSignerByOpt(mspOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sig, err := signer.Sign([]byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = signer.Verify([]byte("hello"), sig); err != nil {
+		t.Error(err)
+	}
+	if signer.GetMSPIdentifier() != "Org1MSP" {
+		t.Errorf("msp id: %s", signer.GetMSPIdentifier())
+	}
+
+	if err = w.Remove("user1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Get("user1"); err == nil {
+		t.Error("get removed identity")
+	}
+}
+
+func TestInMemoryWallet(t *testing.T) {
+	testWallet(t, NewInMemoryWallet())
+}
+
+func TestFileSystemWallet(t *testing.T) {
+	w, err := NewFileSystemWallet(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testWallet(t, w)
+
+	// labels are file names in the wallet directory
+	for _, label := range []string{"", "..", "../user1", "a/b", `a\b`, "a..b"} {
+		if err = w.Put(label, newIdentity(t)); err == nil {
+			t.Errorf("put identity with label [%s]", label)
+		}
+		if _, err = w.Get(label); err == nil {
+			t.Errorf("get identity with label [%s]", label)
+		}
+		if err = w.Remove(label); err == nil {
+			t.Errorf("remove identity with label [%s]", label)
+		}
+	}
+}
+
+func TestNodeSDKFormat(t *testing.T) {
+	dir := t.TempDir()
+	id := newIdentity(t)
+
+	// written by the node sdk: wallet/appUser.id
+	data := `{"credentials":{"certificate":` + quote(id.Certificate) + `,"privateKey":` + quote(id.PrivateKey) +
+		`},"mspId":"Org1MSP","type":"X.509","version":1}`
+	if err := ioutil.WriteFile(filepath.Join(dir, "appUser.id"), []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewFileSystemWallet(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the node sdk wallet has no ca certificates
+	if _, err = w.Signer("appUser"); err == nil {
+		t.Error("get signer without ca certificates")
+	}
+	if _, err = w.Signer("appUser", id.CACerts); err != nil {
+		t.Error(err)
+	}
+}
+
+func quote(b []byte) string {
+	data, _ := json.Marshal(string(b))
+	return string(data)
+}